@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// linkedBOMs keeps track of the SBOMs fetched via external references of type bom.
+// It is safe for concurrent use.
+type linkedBOMs struct {
+	max int
+
+	mux     sync.Mutex
+	visited map[string]bool
+	fetched int
+}
+
+func newLinkedBOMs(max int) *linkedBOMs {
+	return &linkedBOMs{
+		max:     max,
+		visited: make(map[string]bool),
+	}
+}
+
+// Visit records that the SBOM at url is about to be fetched. It returns false if
+// the SBOM was already visited, or if the maximum number of fetched SBOMs was reached.
+func (l *linkedBOMs) Visit(url string) (ok bool, exhausted bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.visited[url] {
+		return false, false
+	}
+	if l.max > 0 && l.fetched >= l.max {
+		return false, true
+	}
+	l.visited[url] = true
+	l.fetched++
+	return true, false
+}
+
+// MarkVisited records url as visited without counting it, e.g. for SBOMs
+// downloaded from Maven Central, so that references back to them are not followed.
+func (l *linkedBOMs) MarkVisited(url string) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.visited[url] = true
+}
+
+// bomReferenceURLs returns the http(s) URLs of all external references of type bom
+// in bom, including those of its root component and (nested) components.
+func bomReferenceURLs(bom *cyclonedx.BOM) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	collect := func(refs *[]cyclonedx.ExternalReference) {
+		if refs == nil {
+			return
+		}
+		for _, ref := range *refs {
+			if ref.Type != cyclonedx.ERTypeBOM || seen[ref.URL] {
+				continue
+			}
+			if u, err := url.Parse(ref.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				continue
+			}
+			seen[ref.URL] = true
+			urls = append(urls, ref.URL)
+		}
+	}
+
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			collect(component.ExternalReferences)
+			visit(component.Components)
+		}
+	}
+
+	collect(bom.ExternalReferences)
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		collect(bom.Metadata.Component.ExternalReferences)
+	}
+	visit(bom.Components)
+
+	return urls
+}
+
+// linkedGAV determines the coordinates under which a linked SBOM is stored.
+// The root component of the SBOM is used if it has a name and version.
+// Otherwise, the coordinates of parent are used, with a hash of the SBOM's URL
+// appended to the version.
+func linkedGAV(bom *cyclonedx.BOM, parent GAV, bomURL string) GAV {
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		component := bom.Metadata.Component
+		if component.Name != "" && component.Version != "" {
+			group := component.Group
+			if group == "" {
+				group = parent.GroupID
+			}
+			return GAV{GroupID: group, ArtifactID: component.Name, Version: component.Version}
+		}
+	}
+
+	digest := sha256.Sum256([]byte(bomURL))
+	return GAV{
+		GroupID:    parent.GroupID,
+		ArtifactID: parent.ArtifactID,
+		Version:    fmt.Sprintf("%s-bom-%s", parent.Version, hex.EncodeToString(digest[:4])),
+	}
+}
+
+// fetchLinkedSBOM downloads the SBOM at bomURL.
+func fetchLinkedSBOM(ctx context.Context, repo Repository, bomURL string) ([]byte, cyclonedx.BOMFileFormat, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	format := suffixFormat(strings.ToLower(bomURL))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bomURL, nil)
+	if err != nil {
+		return nil, format, err
+	}
+
+	res, err := repo.Client.Do(req)
+	if err != nil {
+		return nil, format, err
+	}
+	defer res.Body.Close()
+
+	debugf("GET %s: %s", bomURL, res.Status)
+	if res.StatusCode != http.StatusOK {
+		return nil, format, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, format, fmt.Errorf("failed to read sbom: %w", err)
+	}
+	if isThrottlePage(res.Header, data, format) {
+		return nil, format, fmt.Errorf("received throttle page: %w", errRateLimited)
+	}
+
+	return data, format, nil
+}
+
+// followBOMRefs fetches the SBOMs at urls, which are referenced by parent, processes
+// them like SBOMs downloaded from Maven Central, and sends the results. SBOMs referenced
+// by those are followed as well, until depth exceeds Options.FollowBOMRefs.
+// The caller must collect urls before sending the result of parent, as the BOM may be
+// modified once it has been sent. It returns false if sending was aborted.
+func (c *Crawler) followBOMRefs(ctx context.Context, parent GAV, urls []string, depth int, send func(Result) bool) bool {
+	if depth > c.opts.FollowBOMRefs {
+		return true
+	}
+
+	for _, bomURL := range urls {
+		if ctx.Err() != nil {
+			return false
+		}
+		ok, exhausted := c.linkedBOMs.Visit(bomURL)
+		if exhausted {
+			debugf("not following %s because -max-linked-boms was reached", bomURL)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		log.Printf("following bom reference of %s to %s", parent, bomURL)
+		var result *Result
+		fetchedAt := time.Now()
+		err := safely(parent, c.summary, func() error {
+			data, format, err := fetchLinkedSBOM(ctx, c.repo, bomURL)
+			if err != nil {
+				return err
+			}
+
+			// The coordinates are only known once the SBOM has been decoded.
+			sbom, err := decodeForFilters(ctx, data, format, c.opts.Filters)
+			if err != nil {
+				return err
+			}
+			gav := linkedGAV(&sbom.bom, parent, bomURL)
+			if result = checkSBOMSize(gav, len(data), c.opts.Filters, c.summary); result != nil {
+				return nil
+			}
+			result, err = filterSBOM(gav, sbom, c.opts.Filters, c.summary)
+			return err
+		})
+		if err != nil {
+			if !send(Result{GAV: parent, Err: fmt.Errorf("failed to follow bom reference to %s: %w", bomURL, err)}) {
+				return false
+			}
+			continue
+		}
+		if result.Discard != nil {
+			if !c.sendDiscarded(send, result) {
+				return false
+			}
+			continue
+		}
+
+		result.URL = bomURL
+		result.FetchedAt = fetchedAt
+		result.LinkedFrom = parent.String()
+		linked := bomReferenceURLs(result.BOM)
+		if !send(*result) {
+			return false
+		}
+		if !c.followBOMRefs(ctx, result.GAV, linked, depth+1, send) {
+			return false
+		}
+	}
+
+	return true
+}
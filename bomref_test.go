@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestBOMReferenceURLs(t *testing.T) {
+	bom := &cyclonedx.BOM{
+		ExternalReferences: &[]cyclonedx.ExternalReference{
+			{Type: cyclonedx.ERTypeBOM, URL: "https://example.com/a.json"},
+			{Type: cyclonedx.ERTypeWebsite, URL: "https://example.com"},
+			{Type: cyclonedx.ERTypeBOM, URL: "urn:cdx:f08a6ccd-4dce-4759-bd84-c626675d60a7/1"},
+		},
+		Metadata: &cyclonedx.Metadata{
+			Component: &cyclonedx.Component{
+				ExternalReferences: &[]cyclonedx.ExternalReference{
+					{Type: cyclonedx.ERTypeBOM, URL: "https://example.com/a.json"},
+				},
+			},
+		},
+		Components: &[]cyclonedx.Component{
+			{
+				Components: &[]cyclonedx.Component{
+					{
+						ExternalReferences: &[]cyclonedx.ExternalReference{
+							{Type: cyclonedx.ERTypeBOM, URL: "http://example.com/b.xml"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	urls := bomReferenceURLs(bom)
+	if fmt.Sprint(urls) != "[https://example.com/a.json http://example.com/b.xml]" {
+		t.Fatalf("unexpected urls: %v", urls)
+	}
+}
+
+func TestLinkedGAV(t *testing.T) {
+	parent := GAV{GroupID: "org.example", ArtifactID: "parent", Version: "1.0.0"}
+
+	testCases := []struct {
+		name      string
+		component *cyclonedx.Component
+		want      string
+	}{
+		{"RootComponent", &cyclonedx.Component{Group: "com.example", Name: "child", Version: "2.0.0"}, "com.example:child:2.0.0"},
+		{"NoGroup", &cyclonedx.Component{Name: "child", Version: "2.0.0"}, "org.example:child:2.0.0"},
+		{"NoVersion", &cyclonedx.Component{Name: "child"}, "org.example:parent:1.0.0-bom-8f847de9"},
+		{"NoRootComponent", nil, "org.example:parent:1.0.0-bom-8f847de9"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bom := &cyclonedx.BOM{Metadata: &cyclonedx.Metadata{Component: tc.component}}
+			gav := linkedGAV(bom, parent, "https://example.com/a.json")
+			if gav.String() != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, gav)
+			}
+		})
+	}
+}
+
+func TestCrawlerFollowBOMRefs(t *testing.T) {
+	files := make(map[string]fixture)
+	repo := newFixtureRepository(t, files, nil)
+
+	linkedSBOM := func(name, ref string) fixture {
+		return fixture{body: fmt.Sprintf(`{
+			"bomFormat": "CycloneDX",
+			"specVersion": "1.4",
+			"metadata": {"component": {"type": "library", "group": "org.example", "name": %q, "version": "1.0.0"}},
+			"externalReferences": [{"type": "bom", "url": %q}]
+		}`, name, repo.BaseURL+"/"+ref)}
+	}
+	files["a.json"] = linkedSBOM("a", "b.json")
+	files["b.json"] = linkedSBOM("b", "c.json")
+	files["c.json"] = linkedSBOM("c", "a.json")
+
+	parent := GAV{GroupID: "org.example", ArtifactID: "parent", Version: "1.0.0"}
+
+	testCases := []struct {
+		name  string
+		depth int
+		max   int
+		want  string
+	}{
+		{"Depth1", 1, 0, "[org.example:a:1.0.0]"},
+		{"Cycle", 5, 0, "[org.example:a:1.0.0 org.example:b:1.0.0 org.example:c:1.0.0]"},
+		{"MaxLinkedBOMs", 5, 2, "[org.example:a:1.0.0 org.example:b:1.0.0]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{FollowBOMRefs: tc.depth, MaxLinkedBOMs: tc.max, Filters: Filters{SampleRate: 1}}
+			crawler := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0))
+
+			var linked []string
+			crawler.followBOMRefs(context.Background(), parent, []string{repo.BaseURL + "/a.json"}, 1, func(result Result) bool {
+				if result.Err != nil {
+					t.Fatalf("unexpected error: %v", result.Err)
+				}
+				// Sent results are written concurrently, which may modify their BOM.
+				result.BOM.ExternalReferences = nil
+				if result.LinkedFrom == "" {
+					t.Errorf("expected %s to be linked from another sbom", result.GAV)
+				}
+				linked = append(linked, result.GAV.String())
+				return true
+			})
+
+			if fmt.Sprint(linked) != tc.want {
+				t.Fatalf("expected %s, got %v", tc.want, linked)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// diskBudget keeps track of the number of bytes written to disk and,
+// with -max-sboms, of the number of SBOMs collected and their groups.
+// It is safe for concurrent use.
+type diskBudget struct {
+	max  int64
+	used atomic.Int64
+
+	maxSBOMs  int
+	minGroups int
+	mux       sync.Mutex
+	sboms     int
+	reserved  int
+	groups    map[string]int  // number of SBOMs taken or reserved per group
+	taken     map[string]bool // coordinates of the SBOMs taken
+
+	// checkpoint persists the SBOMs taken, see openCheckpoint.
+	checkpoint *os.File
+}
+
+func newDiskBudget(max int64) *diskBudget {
+	return &diskBudget{max: max, groups: make(map[string]int), taken: make(map[string]bool)}
+}
+
+// withSBOMLimit configures the limits of -max-sboms and -min-unique-groups.
+func (b *diskBudget) withSBOMLimit(maxSBOMs, minGroups int) *diskBudget {
+	b.maxSBOMs = maxSBOMs
+	b.minGroups = minGroups
+	return b
+}
+
+// Add records n written bytes.
+func (b *diskBudget) Add(n int) {
+	b.used.Add(int64(n))
+}
+
+// Used returns the number of bytes written so far.
+func (b *diskBudget) Used() int64 {
+	return b.used.Load()
+}
+
+// Exhausted determines whether the budget has been used up.
+// It always returns false when no budget is configured.
+func (b *diskBudget) Exhausted() bool {
+	return b.max > 0 && b.used.Load() >= b.max
+}
+
+// ReserveSBOM reserves the budget for the SBOM of gav, unless -max-sboms was reached.
+// Once it was reached, SBOMs are only reserved if they add a new group,
+// until -min-unique-groups distinct groups are represented. SBOMs that were
+// already taken, e.g. by a previous run with the same -checkpoint, are always
+// reserved, as they don't count again.
+// The reservation must be followed by TakeSBOM once the SBOM was written,
+// or by ReleaseSBOM if it wasn't.
+func (b *diskBudget) ReserveSBOM(gav GAV) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if !b.taken[gav.String()] && !b.acceptsLocked(gav.GroupID) {
+		return false
+	}
+	b.reserved++
+	b.groups[gav.GroupID]++
+	return true
+}
+
+// TakeSBOM records the SBOM of gav reserved with ReserveSBOM as collected.
+func (b *diskBudget) TakeSBOM(gav GAV) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.reserved--
+	if b.taken[gav.String()] {
+		b.releaseGroupLocked(gav.GroupID)
+		return
+	}
+	b.sboms++
+	b.taken[gav.String()] = true
+	if err := b.recordCheckpoint(gav); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// ReleaseSBOM gives back the budget reserved with ReserveSBOM for the SBOM of gav that wasn't written.
+func (b *diskBudget) ReleaseSBOM(gav GAV) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.reserved--
+	b.releaseGroupLocked(gav.GroupID)
+}
+
+func (b *diskBudget) releaseGroupLocked(group string) {
+	if b.groups[group]--; b.groups[group] <= 0 {
+		delete(b.groups, group)
+	}
+}
+
+// AcceptsGroup determines whether SBOMs of group could still be taken.
+func (b *diskBudget) AcceptsGroup(group string) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.acceptsLocked(group)
+}
+
+func (b *diskBudget) acceptsLocked(group string) bool {
+	if b.maxSBOMs <= 0 || b.sboms+b.reserved < b.maxSBOMs {
+		return true
+	}
+	return b.groups[group] == 0 && len(b.groups) < b.minGroups
+}
+
+// SBOMLimitReached determines whether no more SBOMs can be taken.
+// It always returns false when -max-sboms isn't set.
+func (b *diskBudget) SBOMLimitReached() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.maxSBOMs > 0 && b.sboms >= b.maxSBOMs && len(b.groups) >= b.minGroups
+}
+
+// Groups returns the number of distinct groups of the SBOMs taken so far,
+// including those that are reserved.
+func (b *diskBudget) Groups() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return len(b.groups)
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestDiskBudget(t *testing.T) {
+	budget := newDiskBudget(10)
+	budget.Add(6)
+	if budget.Exhausted() {
+		t.Fatal("budget must not be exhausted after 6 of 10 bytes")
+	}
+	budget.Add(4)
+	if !budget.Exhausted() {
+		t.Fatal("budget must be exhausted after 10 of 10 bytes")
+	}
+	if budget.Used() != 10 {
+		t.Fatalf("expected 10 used bytes, got %d", budget.Used())
+	}
+}
+
+func TestDiskBudgetUnlimited(t *testing.T) {
+	budget := newDiskBudget(0)
+	budget.Add(1 << 30)
+	if budget.Exhausted() {
+		t.Fatal("budget without maximum must never be exhausted")
+	}
+}
+
+func TestDiskBudgetSBOMLimit(t *testing.T) {
+	budget := newDiskBudget(0).withSBOMLimit(2, 3)
+
+	for _, gav := range []string{"org.a:a:1.0.0", "org.a:a:2.0.0"} {
+		if !takeSBOM(t, budget, gav) {
+			t.Fatalf("expected sbom of %s to be taken before -max-sboms was reached", gav)
+		}
+	}
+	if takeSBOM(t, budget, "org.a:a:3.0.0") {
+		t.Fatal("expected sbom of a represented group not to be taken after -max-sboms was reached")
+	}
+	if !takeSBOM(t, budget, "org.a:a:1.0.0") || budget.SBOMs() != 2 {
+		t.Fatal("expected an sbom that was already taken to be taken again without counting it")
+	}
+	if !takeSBOM(t, budget, "org.b:b:1.0.0") || budget.SBOMLimitReached() {
+		t.Fatal("expected sbom of a new group to be taken until -min-unique-groups was reached")
+	}
+	if !takeSBOM(t, budget, "org.c:c:1.0.0") || !budget.SBOMLimitReached() {
+		t.Fatal("expected limit to be reached after -min-unique-groups was reached")
+	}
+	if budget.AcceptsGroup("org.d") {
+		t.Fatal("expected no more groups to be accepted once the limit was reached")
+	}
+	if budget.Groups() != 3 {
+		t.Fatalf("expected 3 unique groups, got %d", budget.Groups())
+	}
+}
+
+func TestDiskBudgetSBOMReservation(t *testing.T) {
+	budget := newDiskBudget(0).withSBOMLimit(1, 2)
+	a := GAV{GroupID: "org.a", ArtifactID: "a", Version: "1.0.0"}
+	b := GAV{GroupID: "org.b", ArtifactID: "b", Version: "1.0.0"}
+	c := GAV{GroupID: "org.c", ArtifactID: "c", Version: "1.0.0"}
+
+	if !budget.ReserveSBOM(a) {
+		t.Fatal("expected the first sbom to be reserved")
+	}
+	if !budget.ReserveSBOM(b) || budget.ReserveSBOM(c) {
+		t.Fatal("expected reservations to count towards -max-sboms and -min-unique-groups")
+	}
+
+	// The sbom of a failed to be written, so its group is free again.
+	budget.ReleaseSBOM(a)
+	budget.TakeSBOM(b)
+	if budget.SBOMs() != 1 || budget.Groups() != 1 {
+		t.Fatalf("expected 1 sbom of 1 group, got %d of %d", budget.SBOMs(), budget.Groups())
+	}
+	if !budget.ReserveSBOM(c) {
+		t.Fatal("expected an sbom of a new group to be reserved after a release")
+	}
+}
+
+// takeSBOM reserves and takes the SBOM of gav, as if it was written.
+func takeSBOM(t *testing.T, budget *diskBudget, gav string) bool {
+	t.Helper()
+
+	parsed, err := ParseGAV(gav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !budget.ReserveSBOM(parsed) {
+		return false
+	}
+	budget.TakeSBOM(parsed)
+	return true
+}
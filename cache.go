@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheTransport stores successful responses to GET requests in a directory,
+// keyed by their URL, and serves subsequent requests for the same URL from
+// there for as long as the stored response is younger than ttl (-http-cache).
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+	ttl  time.Duration
+}
+
+func newCacheTransport(base http.RoundTripper, dir string, ttl time.Duration) (*cacheTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &cacheTransport{base: base, dir: dir, ttl: ttl}, nil
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	res, err := t.lookup(path, req)
+	if err != nil {
+		debugf("ignoring cached response for %s: %v", req.URL, err)
+	} else if res != nil {
+		debugf("serving %s from -http-cache", req.URL)
+		return res, nil
+	}
+
+	res, err = t.base.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	if isHTMLResponse(res.Header, body) {
+		// Likely a throttle page, which must not be served again.
+		return res, nil
+	}
+	if err = t.store(path, res, body); err != nil {
+		debugf("failed to store response for %s in -http-cache: %v", req.URL, err)
+	}
+	return res, nil
+}
+
+// path returns the location of the cached response to req.
+// Files are spread across subdirectories to keep directories small.
+func (t *cacheTransport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(t.dir, key[:2], key)
+}
+
+// lookup reads the cached response at path. It returns nil if there
+// is no cached response, or if it expired.
+func (t *cacheTransport) lookup(path string, req *http.Request) (*http.Response, error) {
+	fi, err := os.Stat(path)
+	if err != nil || (t.ttl > 0 && time.Since(fi.ModTime()) > t.ttl) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
+
+func (t *cacheTransport) store(path string, res *http.Response, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	stored := *res
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+	return writeFileAtomic(path, false, stored.Write)
+}
+
+// isHTMLResponse determines whether a response is an HTML page,
+// based on its Content-Type header or, if absent, its body.
+func isHTMLResponse(header http.Header, body []byte) bool {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/html"
+}
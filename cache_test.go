@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/sbom.json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"bomFormat":"CycloneDX"}`))
+		case "/throttled.json":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache, err := newCacheTransport(http.DefaultTransport, dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: cache}
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, string(body)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		status   int
+		body     string
+		requests int32
+	}{
+		{name: "Miss", path: "/sbom.json", status: http.StatusOK, body: `{"bomFormat":"CycloneDX"}`, requests: 1},
+		{name: "Hit", path: "/sbom.json", status: http.StatusOK, body: `{"bomFormat":"CycloneDX"}`, requests: 1},
+		{name: "NotFound", path: "/missing.json", status: http.StatusNotFound, requests: 2},
+		{name: "NotFoundNotCached", path: "/missing.json", status: http.StatusNotFound, requests: 3},
+		{name: "ThrottlePage", path: "/throttled.json", status: http.StatusOK, body: `<html></html>`, requests: 4},
+		{name: "ThrottlePageNotCached", path: "/throttled.json", status: http.StatusOK, body: `<html></html>`, requests: 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := get(tc.path)
+			if status != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, status)
+			}
+			if tc.body != "" && body != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, body)
+			}
+			if n := requests.Load(); n != tc.requests {
+				t.Errorf("expected %d requests to the server, got %d", tc.requests, n)
+			}
+		})
+	}
+
+	t.Run("Expired", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/sbom.json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-2 * time.Hour)
+		if err = os.Chtimes(cache.path(req), old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		before := requests.Load()
+		if _, body := get("/sbom.json"); body != `{"bomFormat":"CycloneDX"}` {
+			t.Errorf("unexpected body %q", body)
+		}
+		if n := requests.Load(); n != before+1 {
+			t.Errorf("expected an expired response to be fetched again")
+		}
+	})
+
+	entries, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected 1 cached response, got %v", entries)
+	}
+}
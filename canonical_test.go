@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestCanonicalizeJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Whitespace",
+			input:    "{ \"a\" : [ 1 , true , null ] }\n",
+			expected: `{"a":[1,true,null]}`,
+		},
+		{
+			name:     "KeyOrder",
+			input:    `{"b": 1, "a": {"d": 2, "c": 3}}`,
+			expected: `{"a":{"c":3,"d":2},"b":1}`,
+		},
+		{
+			// U+FB33 sorts after U+1D11E when comparing code points, but before it
+			// when comparing UTF-16 code units, as the latter is a surrogate pair.
+			name:     "KeyOrderUTF16",
+			input:    `{"דּ": 1, "𝄞": 2}`,
+			expected: "{\"\U0001D11E\":2,\"דּ\":1}",
+		},
+		{
+			name:     "Numbers",
+			input:    `[1.0, -0, 1e2, 0.000001, 1e-7, 1e21, 123456789012345678901, 4.50]`,
+			expected: `[1,0,100,0.000001,1e-7,1e+21,123456789012345680000,4.5]`,
+		},
+		{
+			name:     "Strings",
+			input:    `["é\/", "\u001f\t", "<&>"]`,
+			expected: "[\"é/\",\"\\u001f\\t\",\"<&>\"]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			canonical, err := canonicalizeJSON([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(canonical) != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, canonical)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeJSONInvalid(t *testing.T) {
+	for _, input := range []string{`{"a": `, `{} {}`, `[1e400]`} {
+		if _, err := canonicalizeJSON([]byte(input)); err == nil {
+			t.Errorf("expected %s to be rejected", input)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// Catalog is a CycloneDX BOM listing all collected SBOMs.
+// Each SBOM is represented by a component, with an external reference
+// of type "bom" pointing to the location it was downloaded from.
+// It is safe for concurrent use.
+type Catalog struct {
+	mux        sync.Mutex
+	components []catalogComponent
+}
+
+type catalogComponent struct {
+	gav       GAV
+	component cyclonedx.Component
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{
+		components: make([]catalogComponent, 0),
+	}
+}
+
+func (c *Catalog) Add(result Result) {
+	hash := result.SHA256
+	if hash == "" {
+		hash = sha256Hex(result.Raw)
+	}
+	gav := result.GAV
+
+	component := cyclonedx.Component{
+		BOMRef:     gav.String(),
+		Type:       cyclonedx.ComponentTypeLibrary,
+		Group:      gav.GroupID,
+		Name:       gav.ArtifactID,
+		Version:    gav.Version,
+		PackageURL: fmt.Sprintf("pkg:maven/%s/%s@%s", gav.GroupID, gav.ArtifactID, gav.Version),
+		ExternalReferences: &[]cyclonedx.ExternalReference{
+			{
+				URL:  result.URL,
+				Type: cyclonedx.ERTypeBOM,
+				Hashes: &[]cyclonedx.Hash{
+					{
+						Algorithm: cyclonedx.HashAlgoSHA256,
+						Value:     hash,
+					},
+				},
+			},
+		},
+	}
+	if result.BOM != nil {
+		properties := []cyclonedx.Property{
+			{
+				Name:  "cdx-central:sbom:specVersion",
+				Value: result.BOM.SpecVersion.String(),
+			},
+			{
+				Name:  "cdx-central:sbom:components",
+				Value: fmt.Sprintf("%d", result.ComponentCounts.TopLevel),
+			},
+		}
+		if result.BOM.SerialNumber != "" {
+			properties = append(properties, cyclonedx.Property{
+				Name:  "cdx-central:sbom:serialNumber",
+				Value: result.BOM.SerialNumber,
+			})
+		}
+		component.Properties = &properties
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.components = append(c.components, catalogComponent{gav: gav, component: component})
+}
+
+// WriteFile writes the catalog to path, with components sorted by their GAV.
+func (c *Catalog) WriteFile(path string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	sort.Slice(c.components, func(i, j int) bool {
+		return c.components[i].gav.Less(c.components[j].gav)
+	})
+	components := make([]cyclonedx.Component, len(c.components))
+	for i := range c.components {
+		components[i] = c.components[i].component
+	}
+
+	bom := cyclonedx.NewBOM()
+	bom.Metadata = &cyclonedx.Metadata{
+		Component: &cyclonedx.Component{
+			Type: cyclonedx.ComponentTypeApplication,
+			Name: "cdx-central catalog",
+		},
+	}
+	bom.Components = &components
+
+	return writeFileAtomic(path, false, func(w io.Writer) error {
+		return cyclonedx.NewBOMEncoder(w, cyclonedx.BOMFileFormatJSON).SetPretty(true).Encode(bom)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.Add(Result{
+		GAV: GAV{GroupID: "org.example", ArtifactID: "b", Version: "1.0.0"},
+		Raw: []byte("{}"),
+		URL: "https://repo1.maven.org/maven2/org/example/b/1.0.0/b-1.0.0-cyclonedx.json",
+	})
+	catalog.Add(Result{
+		GAV: GAV{GroupID: "org.example", ArtifactID: "a", Version: "1.0.0"},
+		Raw: []byte("{}"),
+		URL: "https://repo1.maven.org/maven2/org/example/a/1.0.0/a-1.0.0-cyclonedx.json",
+	})
+
+	path := filepath.Join(t.TempDir(), "catalog.cdx.json")
+	if err := catalog.WriteFile(path); err != nil {
+		t.Fatalf("failed to write catalog: %v", err)
+	}
+
+	bom, err := decodeBOMFile(path)
+	if err != nil {
+		t.Fatalf("failed to decode catalog: %v", err)
+	}
+	if bom.Components == nil || len(*bom.Components) != 2 {
+		t.Fatalf("expected 2 components, got %v", bom.Components)
+	}
+
+	component := (*bom.Components)[0]
+	if component.Name != "a" {
+		t.Errorf("expected components to be sorted by GAV, but first is %q", component.Name)
+	}
+	if component.ExternalReferences == nil || len(*component.ExternalReferences) != 1 {
+		t.Fatalf("expected 1 external reference, got %v", component.ExternalReferences)
+	}
+
+	ref := (*component.ExternalReferences)[0]
+	if ref.Type != cyclonedx.ERTypeBOM {
+		t.Errorf("expected external reference of type %q, got %q", cyclonedx.ERTypeBOM, ref.Type)
+	}
+	if ref.URL != "https://repo1.maven.org/maven2/org/example/a/1.0.0/a-1.0.0-cyclonedx.json" {
+		t.Errorf("unexpected url %q", ref.URL)
+	}
+	// sha256("{}")
+	if ref.Hashes == nil || (*ref.Hashes)[0].Value != "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a" {
+		t.Errorf("unexpected hashes %v", ref.Hashes)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// openCheckpoint restores the SBOMs taken by previous runs from the -checkpoint
+// file in path, and records SBOMs taken from now on in it. The file holds
+// one line per taken SBOM with its coordinates, so that the number of SBOMs
+// and the distinct groups can be restored, and SBOMs that are collected again
+// aren't counted twice. Lines of older checkpoints only hold the group.
+func (b *diskBudget) openCheckpoint(path string) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	// A line may be incomplete if the previous run was killed while writing it.
+	if i := strings.LastIndexByte(string(data), '\n'); i >= 0 {
+		data = data[:i+1]
+	} else {
+		data = nil
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		group := line
+		if gav, err := ParseGAV(line); err == nil {
+			if b.taken[gav.String()] {
+				continue
+			}
+			b.taken[gav.String()] = true
+			group = gav.GroupID
+		}
+		b.sboms++
+		b.groups[group]++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Rewrite the complete lines, so that an incomplete one is dropped.
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return err
+	}
+	b.checkpoint, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	return err
+}
+
+// recordCheckpoint appends gav to the -checkpoint file, if any.
+// The caller must hold b.mux.
+func (b *diskBudget) recordCheckpoint(gav GAV) error {
+	if b.checkpoint == nil {
+		return nil
+	}
+	if _, err := b.checkpoint.WriteString(gav.String() + "\n"); err != nil {
+		return fmt.Errorf("failed to write -checkpoint: %w", err)
+	}
+	return nil
+}
+
+// SBOMs returns the number of SBOMs taken so far, including those restored from -checkpoint.
+func (b *diskBudget) SBOMs() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.sboms
+}
+
+// Close closes the -checkpoint file, if any.
+func (b *diskBudget) Close() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.checkpoint == nil {
+		return nil
+	}
+	return b.checkpoint.Close()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskBudgetCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	// The first run is interrupted after taking 2 of 3 SBOMs.
+	budget := newDiskBudget(0).withSBOMLimit(3, 0)
+	if err := budget.openCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, gav := range []string{"org.a:a:1.0.0", "org.b:b:1.0.0"} {
+		if !takeSBOM(t, budget, gav) {
+			t.Fatalf("expected sbom of %s to be taken", gav)
+		}
+	}
+	if err := budget.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a line that was only partially written when the run was killed.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("org.")
+	f.Close()
+
+	resumed := newDiskBudget(0).withSBOMLimit(3, 0)
+	if err := resumed.openCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if resumed.SBOMs() != 2 || resumed.Groups() != 2 {
+		t.Fatalf("expected 2 sboms of 2 groups to be restored, got %d of %d", resumed.SBOMs(), resumed.Groups())
+	}
+	if !takeSBOM(t, resumed, "org.a:a:1.0.0") || resumed.SBOMs() != 2 {
+		t.Fatal("expected an sbom recorded in the checkpoint not to be counted again")
+	}
+	if !takeSBOM(t, resumed, "org.c:c:1.0.0") {
+		t.Fatal("expected the third sbom to be taken after resuming")
+	}
+	if takeSBOM(t, resumed, "org.d:d:1.0.0") || !resumed.SBOMLimitReached() {
+		t.Fatal("expected -max-sboms to be reached after resuming")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "org.a:a:1.0.0\norg.b:b:1.0.0\norg.c:c:1.0.0\n" {
+		t.Fatalf("unexpected checkpoint: %q", data)
+	}
+}
+
+func TestDiskBudgetCheckpointGroups(t *testing.T) {
+	// Checkpoints of older versions only record the group of each SBOM.
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	if err := os.WriteFile(path, []byte("org.a\norg.a\norg.b:b:1.0.0\norg.b:b:1.0.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	budget := newDiskBudget(0).withSBOMLimit(10, 0)
+	if err := budget.openCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	defer budget.Close()
+	if budget.SBOMs() != 3 || budget.Groups() != 2 {
+		t.Fatalf("expected 3 sboms of 2 groups to be restored, got %d of %d", budget.SBOMs(), budget.Groups())
+	}
+}
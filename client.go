@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Repository is a Maven repository along with its search API.
+type Repository struct {
+	Client *http.Client
+
+	// SearchURL is the URL of the Solr search endpoint.
+	SearchURL string
+
+	// BaseURL is the URL under which artifact files are located.
+	BaseURL string
+
+	// FallbackBaseURLs are tried in order when an artifact file is not found under BaseURL,
+	// e.g. for artifacts that were published to other hosts and aren't mirrored identically.
+	FallbackBaseURLs []string
+}
+
+// mavenCentral returns the Repository for Maven Central.
+func mavenCentral(client *http.Client) Repository {
+	return Repository{
+		Client:    client,
+		SearchURL: "https://search.maven.org/solrsearch/select",
+		BaseURL:   "https://repo1.maven.org/maven2",
+	}
+}
+
+// hosts returns the hosts of the repository's URLs.
+func (r Repository) hosts() []string {
+	var hosts []string
+	for _, rawURL := range append([]string{r.SearchURL, r.BaseURL}, r.FallbackBaseURLs...) {
+		if u, err := url.Parse(rawURL); err == nil {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
+
+// hostOf returns the host of rawURL, or rawURL itself if it can't be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
+
+// maxRedirects mirrors the limit of http.Client's default redirect policy.
+const maxRedirects = 10
+
+// allowedHostsRedirectPolicy returns a redirect policy for http.Client.CheckRedirect
+// that rejects redirects to hosts other than the given ones.
+func allowedHostsRedirectPolicy(hosts []string) func(req *http.Request, via []*http.Request) error {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		allowed[strings.ToLower(host)] = true
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		host := strings.ToLower(req.URL.Hostname())
+		if !allowed[host] {
+			log.Printf("rejecting redirect from %s to %s because %s is not in -allowed-hosts", via[len(via)-1].URL, req.URL, host)
+			return fmt.Errorf("redirect to %s is not allowed by -allowed-hosts", host)
+		}
+
+		return nil
+	}
+}
+
+// Timeouts for individual requests, including reading the response body.
+// Downloads get more time than searches, as SBOMs can be several megabytes large.
+var (
+	searchTimeout   = 30 * time.Second
+	downloadTimeout = 5 * time.Minute
+)
+
+// newHTTPClient creates a client with a dedicated transport.
+//
+// HTTP/2 is attempted by default, which allows multiple requests to the same
+// host to be multiplexed over a single connection. Some proxies don't handle
+// HTTP/2 correctly though, in which case forceHTTP1 can be used to disable it.
+//
+// headers are added to every request.
+func newHTTPClient(forceHTTP1 bool, headers http.Header) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	if forceHTTP1 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	var rt http.RoundTripper = &progressTransport{base: transport}
+	if len(headers) > 0 {
+		rt = &headerTransport{base: rt, headers: headers}
+	}
+
+	return &http.Client{
+		Transport: rt,
+	}
+}
+
+// headerTransport adds headers to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the original request.
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.base.RoundTrip(req)
+}
+
+// parseHeader parses a header in the form "Name: Value".
+func parseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q: expected \"Name: Value\"", s)
+	}
+	return textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// parseHeaders parses headers in the form "Name: Value".
+// Multiple values for the same name are retained.
+func parseHeaders(headers []string) (http.Header, error) {
+	parsed := make(http.Header)
+	for _, header := range headers {
+		name, value, err := parseHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Add(name, value)
+	}
+	return parsed, nil
+}
+
+// redactHeader returns value, unless the header name suggests that it holds a secret.
+func redactHeader(name, value string) string {
+	lower := strings.ToLower(name)
+	for _, hint := range []string{"auth", "token", "key", "secret", "password", "cookie", "session"} {
+		if strings.Contains(lower, hint) {
+			return "<redacted>"
+		}
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	name, value, err := parseHeader("x-api-key:  secret ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "X-Api-Key" || value != "secret" {
+		t.Fatalf("unexpected header %q: %q", name, value)
+	}
+
+	for _, invalid := range []string{"X-Api-Key", ": value", "X Api Key: value"} {
+		if _, _, err := parseHeader(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestHTTPClientHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+	}))
+	defer server.Close()
+
+	headers, err := parseHeaders([]string{"X-Tenant: a", "X-Tenant: b", "Authorization: Bearer token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := newHTTPClient(false, headers).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	header := <-received
+	if tenants := header.Values("X-Tenant"); len(tenants) != 2 || tenants[0] != "a" || tenants[1] != "b" {
+		t.Errorf("unexpected X-Tenant values %v", tenants)
+	}
+	if auth := header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("unexpected Authorization value %q", auth)
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	if value := redactHeader("Authorization", "Bearer token"); value != "<redacted>" {
+		t.Errorf("expected Authorization to be redacted, got %q", value)
+	}
+	if value := redactHeader("X-Tenant", "example"); value != "example" {
+		t.Errorf("expected X-Tenant not to be redacted, got %q", value)
+	}
+}
+
+func TestAllowedHostsRedirectPolicy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Redirect to localhost, so that the host differs from the one of server.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+targetURL.Port(), http.StatusFound)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name    string
+		allowed []string
+		errMsg  string
+	}{
+		{
+			name:    "Allowed",
+			allowed: []string{"127.0.0.1", "LOCALHOST"},
+		},
+		{
+			name:    "NotAllowed",
+			allowed: []string{"127.0.0.1"},
+			errMsg:  "redirect to localhost is not allowed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newHTTPClient(false, nil)
+			client.CheckRedirect = allowedHostsRedirectPolicy(tc.allowed)
+
+			res, err := client.Get(server.URL)
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// componentSetHash returns the SHA-256 hash of the sorted identities of all
+// (nested) components in bom, or an empty string if bom has no components.
+// Components are identified by their purl, or group:name:version if they have none.
+// The root component is not considered, so that SBOMs with identical dependencies
+// yield the same hash regardless of their metadata.
+func componentSetHash(bom *cyclonedx.BOM) string {
+	var identities []string
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			identity := component.PackageURL
+			if identity == "" {
+				identity = component.Group + ":" + component.Name + ":" + component.Version
+			}
+			identities = append(identities, identity)
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+
+	if len(identities) == 0 {
+		return ""
+	}
+
+	sort.Strings(identities)
+	digest := sha256.Sum256([]byte(strings.Join(identities, "\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+// componentSets keeps track of the component sets of collected SBOMs.
+// It is safe for concurrent use.
+type componentSets struct {
+	mux       sync.Mutex
+	first     map[string]GAV
+	collapsed map[string]int
+}
+
+func newComponentSets() *componentSets {
+	return &componentSets{
+		first:     make(map[string]GAV),
+		collapsed: make(map[string]int),
+	}
+}
+
+// Add records the component set hash of the SBOM of gav. If the hash was already
+// recorded, it returns false along with the coordinates of the SBOM that had it first.
+// Empty hashes are never recorded, and thus always new.
+func (s *componentSets) Add(hash string, gav GAV) (GAV, bool) {
+	if hash == "" {
+		return GAV{}, true
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if first, ok := s.first[hash]; ok {
+		s.collapsed[hash]++
+		return first, false
+	}
+	s.first[hash] = gav
+	return GAV{}, true
+}
+
+// Collapsed returns how many SBOMs were duplicates, and of how many distinct component sets.
+func (s *componentSets) Collapsed() (sboms, sets int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, n := range s.collapsed {
+		sboms += n
+	}
+	return sboms, len(s.collapsed)
+}
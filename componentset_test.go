@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestComponentSetHash(t *testing.T) {
+	bom := func(root string, components ...cyclonedx.Component) *cyclonedx.BOM {
+		return &cyclonedx.BOM{
+			SerialNumber: "urn:uuid:" + root,
+			Metadata:     &cyclonedx.Metadata{Component: &cyclonedx.Component{Name: root}},
+			Components:   &components,
+		}
+	}
+	a := cyclonedx.Component{Name: "a", PackageURL: "pkg:maven/org.example/a@1.0.0"}
+	b := cyclonedx.Component{Group: "org.example", Name: "b", Version: "1.0.0"}
+	c := cyclonedx.Component{Name: "c", PackageURL: "pkg:maven/org.example/c@1.0.0"}
+	nested := a
+	nested.Components = &[]cyclonedx.Component{c}
+
+	hash := componentSetHash(bom("x", a, b, c))
+	if hash == "" {
+		t.Fatal("expected a hash")
+	}
+	if other := componentSetHash(bom("y", c, b, a)); other != hash {
+		t.Fatal("expected order and metadata not to affect the hash")
+	}
+	if other := componentSetHash(bom("x", b, nested)); other != hash {
+		t.Fatal("expected nested components to be considered")
+	}
+	if other := componentSetHash(bom("x", a, b)); other == hash {
+		t.Fatal("expected different components to yield a different hash")
+	}
+	if empty := componentSetHash(bom("x")); empty != "" {
+		t.Fatalf("expected no hash without components, got %s", empty)
+	}
+}
+
+func TestComponentSets(t *testing.T) {
+	sets := newComponentSets()
+	first := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+
+	for _, step := range []struct {
+		hash  string
+		gav   GAV
+		isNew bool
+	}{
+		{"aaaa", first, true},
+		{"aaaa", GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.1"}, false},
+		{"aaaa", GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.2"}, false},
+		{"bbbb", GAV{GroupID: "org.example", ArtifactID: "lib", Version: "2.0.0"}, true},
+		{"", GAV{GroupID: "org.example", ArtifactID: "lib", Version: "3.0.0"}, true},
+		{"", GAV{GroupID: "org.example", ArtifactID: "lib", Version: "3.0.1"}, true},
+	} {
+		aliasOf, isNew := sets.Add(step.hash, step.gav)
+		if isNew != step.isNew {
+			t.Fatalf("expected Add(%q, %s) to return %t", step.hash, step.gav, step.isNew)
+		}
+		if !isNew && aliasOf != first {
+			t.Fatalf("expected %s to be an alias of %s, got %s", step.gav, first, aliasOf)
+		}
+	}
+
+	if sboms, sets := sets.Collapsed(); sboms != 2 || sets != 1 {
+		t.Fatalf("expected 2 sboms collapsed into 1 set, got %d into %d", sboms, sets)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+var validComponentTypes = []cyclonedx.ComponentType{
+	cyclonedx.ComponentTypeApplication,
+	cyclonedx.ComponentTypeContainer,
+	cyclonedx.ComponentTypeDevice,
+	cyclonedx.ComponentTypeFile,
+	cyclonedx.ComponentTypeFirmware,
+	cyclonedx.ComponentTypeFramework,
+	cyclonedx.ComponentTypeLibrary,
+	cyclonedx.ComponentTypeOS,
+}
+
+func parseComponentType(s string) (cyclonedx.ComponentType, error) {
+	for _, typ := range validComponentTypes {
+		if strings.EqualFold(s, string(typ)) {
+			return typ, nil
+		}
+	}
+
+	names := make([]string, 0, len(validComponentTypes))
+	for _, typ := range validComponentTypes {
+		names = append(names, string(typ))
+	}
+	return "", fmt.Errorf("invalid component type %q: expected one of %s", s, strings.Join(names, ", "))
+}
+
+// filterComponentTypes removes all components from bom whose type is not one of the given ones.
+// It returns the number of removed components.
+func filterComponentTypes(bom *cyclonedx.BOM, types []string) int {
+	allowed := make(map[cyclonedx.ComponentType]bool, len(types))
+	for _, t := range types {
+		if typ, err := parseComponentType(t); err == nil {
+			allowed[typ] = true
+		}
+	}
+
+	return filterComponents(bom, func(component cyclonedx.Component) bool {
+		return allowed[component.Type]
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestFilterComponentTypes(t *testing.T) {
+	bom := cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{
+				BOMRef: "a",
+				Type:   cyclonedx.ComponentTypeLibrary,
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "a-file", Type: cyclonedx.ComponentTypeFile},
+					{BOMRef: "a-nested", Type: cyclonedx.ComponentTypeLibrary},
+				},
+			},
+			{BOMRef: "b", Type: cyclonedx.ComponentTypeFramework},
+			{
+				BOMRef: "c",
+				Type:   cyclonedx.ComponentTypeContainer,
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "c-os", Type: cyclonedx.ComponentTypeOS},
+				},
+			},
+		},
+		Dependencies: &[]cyclonedx.Dependency{
+			{Ref: "a", Dependencies: &[]string{"a-file", "a-nested", "b", "c"}},
+			{Ref: "c", Dependencies: &[]string{"c-os"}},
+		},
+	}
+
+	if removed := filterComponentTypes(&bom, []string{"Library", "framework"}); removed != 3 {
+		t.Fatalf("expected 3 removed components, got %d", removed)
+	}
+
+	expected := []cyclonedx.Dependency{
+		{Ref: "a", Dependencies: &[]string{"a-nested", "b"}},
+	}
+	if !reflect.DeepEqual(*bom.Dependencies, expected) {
+		t.Fatalf("expected dependencies %v, got %v", expected, *bom.Dependencies)
+	}
+	if counts := countAllComponents(&bom); counts.TopLevel != 2 || counts.Get(componentCountRecursive) != 3 {
+		t.Fatalf("unexpected components %v", *bom.Components)
+	}
+}
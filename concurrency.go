@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Auto-tuning of concurrency: every concurrencyTuneInterval, the concurrency is
+// increased by one if at most maxErrorRate of the downloads in the interval
+// failed or were rate limited, and halved otherwise.
+var concurrencyTuneInterval = 30 * time.Second
+
+const maxErrorRate = 0.05
+
+// concurrencyLimiter limits how many of a fixed set of workers may be busy.
+// Workers are numbered, and worker i may only proceed while i is below the limit.
+// It is safe for concurrent use.
+type concurrencyLimiter struct {
+	mux    sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	closed bool
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	l := &concurrencyLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mux)
+	return l
+}
+
+// Wait blocks until worker may proceed, or the limiter was closed.
+// It returns false if ctx was cancelled in the meantime.
+func (l *concurrencyLimiter) Wait(ctx context.Context, worker int) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for worker >= l.limit && !l.closed && ctx.Err() == nil {
+		l.cond.Wait()
+	}
+	return ctx.Err() == nil
+}
+
+func (l *concurrencyLimiter) Limit() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.limit
+}
+
+func (l *concurrencyLimiter) SetLimit(limit int) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+// Close lets all workers proceed, e.g. so they notice that there's no more work.
+func (l *concurrencyLimiter) Close() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.closed = true
+	l.cond.Broadcast()
+}
+
+// wakeOnDone wakes up all waiting workers once ctx is done, so they can return.
+func (l *concurrencyLimiter) wakeOnDone(ctx context.Context) {
+	<-ctx.Done()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.cond.Broadcast()
+}
+
+// tuneConcurrency adjusts the limit of l based on the error and rate limit
+// rates recorded in summary, until ctx is done.
+func tuneConcurrency(ctx context.Context, l *concurrencyLimiter, max int, summary *Summary) {
+	go l.wakeOnDone(ctx)
+
+	ticker := time.NewTicker(concurrencyTuneInterval)
+	defer ticker.Stop()
+
+	summary.AddConcurrency(l.Limit())
+	downloaded, failed, rateLimited := summary.requestCounts()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		d, f, r := summary.requestCounts()
+		limit := nextConcurrency(l.Limit(), max, d-downloaded, (f-failed)+(r-rateLimited))
+		downloaded, failed, rateLimited = d, f, r
+
+		if limit != l.Limit() {
+			log.Printf("changing concurrency from %d to %d", l.Limit(), limit)
+			l.SetLimit(limit)
+			summary.AddConcurrency(limit)
+		}
+	}
+}
+
+// nextConcurrency determines the concurrency for the next interval,
+// given the number of successful and unsuccessful downloads in the last one.
+func nextConcurrency(current, max, succeeded, errors int) int {
+	total := succeeded + errors
+	if total == 0 {
+		return current
+	}
+
+	if float64(errors)/float64(total) > maxErrorRate {
+		if current > 1 {
+			return current / 2
+		}
+		return 1
+	}
+	if current < max {
+		return current + 1
+	}
+	return max
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNextConcurrency(t *testing.T) {
+	testCases := []struct {
+		name      string
+		current   int
+		succeeded int
+		errors    int
+		expected  int
+	}{
+		{name: "NoDownloads", current: 4, expected: 4},
+		{name: "Increase", current: 4, succeeded: 100, errors: 5, expected: 5},
+		{name: "AtMax", current: 10, succeeded: 100, expected: 10},
+		{name: "Decrease", current: 4, succeeded: 90, errors: 10, expected: 2},
+		{name: "AtMin", current: 1, errors: 1, expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if next := nextConcurrency(tc.current, 10, tc.succeeded, tc.errors); next != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, next)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := newConcurrencyLimiter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go limiter.wakeOnDone(ctx)
+
+	if !limiter.Wait(ctx, 0) {
+		t.Fatal("expected worker 0 to proceed")
+	}
+
+	proceeded := make(chan bool)
+	go func() { proceeded <- limiter.Wait(ctx, 1) }()
+	select {
+	case <-proceeded:
+		t.Fatal("expected worker 1 to wait")
+	case <-time.After(10 * time.Millisecond):
+	}
+	limiter.SetLimit(2)
+	if !<-proceeded {
+		t.Fatal("expected worker 1 to proceed after raising the limit")
+	}
+
+	go func() { proceeded <- limiter.Wait(ctx, 2) }()
+	cancel()
+	if <-proceeded {
+		t.Fatal("expected worker 2 not to proceed after cancellation")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import "github.com/CycloneDX/cyclonedx-go"
+
+// Ways of counting components for -min-components, for -component-count-source.
+const (
+	componentCountTopLevel   = "top-level"
+	componentCountRecursive  = "recursive"
+	componentCountPurlUnique = "purl-unique"
+)
+
+// ComponentCounts holds the number of components of an SBOM, counted in all supported ways.
+// The root component is never counted.
+type ComponentCounts struct {
+	// TopLevel is the number of entries in the top-level components array.
+	TopLevel int `json:"topLevel"`
+
+	// Recursive includes nested components, at any depth.
+	// It's nil if only the metadata of the SBOM was decoded.
+	Recursive *int `json:"recursive,omitempty"`
+
+	// PurlUnique is the number of distinct purls among all (nested) components.
+	// Components without purl are not counted. It's nil if only the metadata of the SBOM was decoded.
+	PurlUnique *int `json:"purlUnique,omitempty"`
+}
+
+func countAllComponents(bom *cyclonedx.BOM) ComponentCounts {
+	purls := make(map[string]bool)
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			if component.PackageURL != "" {
+				purls[component.PackageURL] = true
+			}
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+
+	recursive, purlUnique := countComponents(bom.Components, true), len(purls)
+	return ComponentCounts{
+		TopLevel:   countComponents(bom.Components, false),
+		Recursive:  &recursive,
+		PurlUnique: &purlUnique,
+	}
+}
+
+// componentRatio returns the fraction of components of bom, including nested
+// components but excluding the root component, for which match returns true.
+// SBOMs without components have a ratio of zero.
+func componentRatio(bom *cyclonedx.BOM, match func(cyclonedx.Component) bool) float64 {
+	total, matched := 0, 0
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			total++
+			if match(component) {
+				matched++
+			}
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+	if total == 0 {
+		return 0
+	}
+
+	return float64(matched) / float64(total)
+}
+
+// Get returns the count according to source, or 0 if it isn't known.
+func (c ComponentCounts) Get(source string) int {
+	var count *int
+	switch source {
+	case componentCountRecursive:
+		count = c.Recursive
+	case componentCountPurlUnique:
+		count = c.PurlUnique
+	default:
+		return c.TopLevel
+	}
+	if count == nil {
+		return 0
+	}
+	return *count
+}
+
+// componentCountSource returns how components are counted for -min-components.
+// -count-nested is a shorthand for -component-count-source recursive.
+func (f Filters) componentCountSource() string {
+	if f.CountNested && (f.ComponentCountSource == "" || f.ComponentCountSource == componentCountTopLevel) {
+		return componentCountRecursive
+	}
+	if f.ComponentCountSource == "" {
+		return componentCountTopLevel
+	}
+	return f.ComponentCountSource
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestCountAllComponents(t *testing.T) {
+	bom := &cyclonedx.BOM{
+		Metadata: &cyclonedx.Metadata{
+			Component: &cyclonedx.Component{Name: "root", PackageURL: "pkg:maven/org.example/root@1.0.0"},
+		},
+		Components: &[]cyclonedx.Component{
+			{Name: "a", PackageURL: "pkg:maven/org.example/a@1.0.0"},
+			{
+				Name:       "b",
+				PackageURL: "pkg:maven/org.example/b@1.0.0",
+				Components: &[]cyclonedx.Component{
+					{Name: "a", PackageURL: "pkg:maven/org.example/a@1.0.0"},
+					{Name: "c"},
+				},
+			},
+		},
+	}
+
+	counts := countAllComponents(bom)
+	for source, want := range map[string]int{componentCountTopLevel: 2, componentCountRecursive: 4, componentCountPurlUnique: 2} {
+		if count := counts.Get(source); count != want {
+			t.Errorf("expected %d components counted %s, got %d", want, source, count)
+		}
+	}
+	if unknown := (ComponentCounts{TopLevel: 2}); unknown.Get(componentCountRecursive) != 0 {
+		t.Errorf("expected unknown recursive count to be 0, got %d", unknown.Get(componentCountRecursive))
+	}
+}
+
+func TestComponentRatio(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ratio    func(*cyclonedx.BOM) float64
+		bom      cyclonedx.BOM
+		expected float64
+	}{
+		{
+			name:  "Versioned",
+			ratio: versionedRatio,
+			bom: cyclonedx.BOM{
+				Metadata: &cyclonedx.Metadata{Component: &cyclonedx.Component{Version: "[1.0,)"}},
+				Components: &[]cyclonedx.Component{
+					{Version: "1.0.0", Components: &[]cyclonedx.Component{{Version: ""}}},
+					{Version: "^2.0.0"},
+					{Version: "3.1.4"},
+				},
+			},
+			expected: 0.5,
+		},
+		{
+			name:  "CPE",
+			ratio: cpeRatio,
+			bom: cyclonedx.BOM{
+				Metadata: &cyclonedx.Metadata{Component: &cyclonedx.Component{CPE: "cpe:2.3:a:example:root:1.0.0:*:*:*:*:*:*:*"}},
+				Components: &[]cyclonedx.Component{
+					{CPE: "cpe:2.3:a:example:a:1.0.0:*:*:*:*:*:*:*", Components: &[]cyclonedx.Component{{CPE: " "}}},
+					{},
+					{CPE: "cpe:/a:example:c:3.1.4"},
+				},
+			},
+			expected: 0.5,
+		},
+		{"VersionedWithoutComponents", versionedRatio, cyclonedx.BOM{}, 0},
+		{"CPEWithoutComponents", cpeRatio, cyclonedx.BOM{}, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if ratio := tc.ratio(&tc.bom); ratio != tc.expected {
+				t.Errorf("expected a ratio of %g, got %g", tc.expected, ratio)
+			}
+		})
+	}
+}
+
+func TestComponentCountSource(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filters Filters
+		want    string
+	}{
+		{"Default", Filters{}, componentCountTopLevel},
+		{"CountNested", Filters{CountNested: true, ComponentCountSource: componentCountTopLevel}, componentCountRecursive},
+		{"PurlUnique", Filters{ComponentCountSource: componentCountPurlUnique}, componentCountPurlUnique},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if source := tc.filters.componentCountSource(); source != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, source)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// CoverageEntry is a line of the -registry-index report.
+type CoverageEntry struct {
+	GroupID    string `json:"group"`
+	ArtifactID string `json:"artifact"`
+
+	// Versions is the number of published versions, according to VersionSource.
+	Versions         int    `json:"versions"`
+	VersionsWithSBOM int    `json:"versionsWithSbom"`
+	VersionSource    string `json:"versionSource"`
+
+	// Coverage is VersionsWithSBOM / Versions, or 0 if there are no versions.
+	Coverage float64 `json:"coverage"`
+}
+
+// IndexCoverage determines the SBOM coverage of all artifacts the crawler would process,
+// and writes one CoverageEntry per artifact to w as NDJSON. Nothing is downloaded.
+func (c *Crawler) IndexCoverage(ctx context.Context, w io.Writer) error {
+	var (
+		writeMux sync.Mutex
+		encoder  = json.NewEncoder(w)
+		errs     []error
+	)
+	artifactsChan := make(chan Artifact, 1)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for artifact := range artifactsChan {
+				entry, err := c.artifactCoverage(ctx, artifact)
+				writeMux.Lock()
+				if err == nil {
+					err = encoder.Encode(entry)
+				}
+				if err != nil {
+					log.Printf("%v", err)
+					errs = append(errs, err)
+				}
+				writeMux.Unlock()
+				if err == nil {
+					c.summary.AddArtifact()
+				}
+			}
+		}()
+	}
+
+	err := discoverArtifacts(ctx, c.repo, c.opts.GroupPrefixes, c.opts.SearchConcurrency, c.acceptArtifact, artifactsChan)
+	close(artifactsChan)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to determine the coverage of %d artifacts", len(errs))
+	}
+
+	return nil
+}
+
+// artifactCoverage counts the versions of artifact, and how many of them have an SBOM
+// matching -sbom-suffixes. The latter is always determined with Solr, as only its
+// search results tell which files were published. With -version-source metadata,
+// both numbers are taken from the versions in maven-metadata.xml, if it exists.
+func (c *Crawler) artifactCoverage(ctx context.Context, artifact Artifact) (CoverageEntry, error) {
+	entry := CoverageEntry{
+		GroupID:       artifact.GroupID,
+		ArtifactID:    artifact.ArtifactID,
+		VersionSource: versionSourceSolr,
+	}
+
+	docs, err := searchVersionDocs(ctx, c.repo, artifact)
+	if err != nil {
+		return entry, fmt.Errorf("failed to search for versions of %s: %w", artifact, err)
+	}
+	docs = uniqueVersionDocs(docs)
+	withSBOM := make(map[string]bool)
+	suffixes := c.opts.sbomSuffixes()
+	for _, doc := range docs {
+		if containsAny(doc.EC, suffixes) {
+			withSBOM[doc.Version] = true
+		}
+	}
+	entry.Versions, entry.VersionsWithSBOM = len(docs), len(withSBOM)
+
+	if c.opts.VersionSource == versionSourceMetadata {
+		versions, err := collectMetadataVersions(ctx, c.repo, artifact, "", c.opts.versionComparator())
+		switch {
+		case err == nil:
+			// Versions that aren't listed in the metadata are left out of both numbers,
+			// so that the coverage can't exceed 1.
+			entry.Versions, entry.VersionsWithSBOM = len(versions), 0
+			for _, version := range versions {
+				if withSBOM[version.GAV.Version] {
+					entry.VersionsWithSBOM++
+				}
+			}
+			entry.VersionSource = versionSourceMetadata
+		case errors.Is(err, errMetadataNotFound):
+			log.Printf("%v, counting versions with solr", err)
+		default:
+			return entry, err
+		}
+	}
+
+	if entry.Versions > 0 {
+		entry.Coverage = float64(entry.VersionsWithSBOM) / float64(entry.Versions)
+	}
+	return entry, nil
+}
+
+// runRegistryIndex runs IndexCoverage and writes the report to -registry-index.
+// It returns false if the report is incomplete.
+func runRegistryIndex(ctx context.Context, repo Repository, opts Options, summary *Summary) bool {
+	f, err := os.Create(opts.RegistryIndex)
+	if err != nil {
+		log.Fatalf("failed to create -registry-index: %v", err)
+	}
+	w := bufio.NewWriter(f)
+
+	complete := true
+	err = NewCrawler(repo, opts, summary, newDiskBudget(0)).IndexCoverage(ctx, w)
+	if err != nil {
+		log.Printf("coverage report is incomplete: %v", err)
+		complete = false
+	}
+	if err := w.Flush(); err != nil {
+		log.Printf("failed to write -registry-index: %v", err)
+		complete = false
+	}
+	if err := f.Close(); err != nil {
+		log.Printf("failed to close -registry-index: %v", err)
+		complete = false
+	}
+	summary.Log()
+	return complete
+}
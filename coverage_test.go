@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCrawlerIndexCoverage(t *testing.T) {
+	files := map[string]fixture{
+		"org/example/lib/maven-metadata.xml": {body: `<metadata><versioning><versions>
+			<version>1.0.0</version><version>2.0.0</version><version>3.0.0</version><version>4.0.0</version>
+		</versions></versioning></metadata>`},
+	}
+	repo := newFixtureRepository(t, files, fixtureSearches(t, fixtureArtifact{name: "lib", versions: []fixtureVersion{
+		{version: "5.0.0", ec: []string{".jar", "-cyclonedx.json"}},
+		{version: "3.0.0", ec: []string{".jar", "-cyclonedx.json"}},
+		{version: "2.0.0", ec: []string{".jar", "-cyclonedx.xml"}},
+		{version: "1.0.0", ec: []string{".jar"}},
+	}}))
+
+	testCases := []struct {
+		name          string
+		versionSource string
+		want          CoverageEntry
+	}{
+		{"Solr", versionSourceSolr, CoverageEntry{GroupID: "org.example", ArtifactID: "lib", Versions: 4, VersionsWithSBOM: 3, VersionSource: versionSourceSolr, Coverage: 0.75}},
+		{"Metadata", versionSourceMetadata, CoverageEntry{GroupID: "org.example", ArtifactID: "lib", Versions: 4, VersionsWithSBOM: 2, VersionSource: versionSourceMetadata, Coverage: 0.5}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{
+				Concurrency:   1,
+				SBOMSuffixes:  []string{"-cyclonedx.json"},
+				RetryAsXML:    true,
+				VersionSource: tc.versionSource,
+			}
+			var buf bytes.Buffer
+			err := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0)).IndexCoverage(context.Background(), &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var entry CoverageEntry
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid report %q: %v", buf.String(), err)
+			}
+			if entry != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// cpeRatio returns the fraction of components of bom that have a CPE (see componentRatio).
+func cpeRatio(bom *cyclonedx.BOM) float64 {
+	return componentRatio(bom, func(component cyclonedx.Component) bool {
+		return strings.TrimSpace(component.CPE) != ""
+	})
+}
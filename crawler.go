@@ -0,0 +1,625 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Result is the outcome of processing a single GAV.
+//
+// If Err is set, the other fields may be empty; an error that
+// isn't specific to a single GAV (e.g. a failed search) has no GAV.
+type Result struct {
+	GAV     GAV
+	BOM     *cyclonedx.BOM
+	Raw     []byte
+	Format  cyclonedx.BOMFileFormat
+	URL     string
+	Headers ResponseHeaders
+	Err     error
+
+	// Discard is set when the SBOM was discarded by a filter.
+	Discard *Discard
+
+	// Timestamp is the metadata.timestamp of the SBOM. Unlike BOM, it's also set
+	// for SBOMs that were discarded by a filter after they were decoded.
+	Timestamp string
+
+	// SHA256 is the SHA-256 hash of Raw.
+	SHA256 string
+
+	// Modified is set when BOM was changed after decoding,
+	// in which case Raw no longer reflects its content.
+	Modified bool
+
+	// NonStrict is set when BOM could only be decoded partially because of -lenient.
+	NonStrict bool
+
+	// Signed is set when a JSON SBOM has a top-level signature, which BOM can't hold.
+	Signed bool
+
+	// QualityScore rates the metadata completeness of BOM from 0 to 100.
+	QualityScore float64
+
+	// VersionedRatio is the fraction of components of BOM with a concrete version,
+	// and CPERatio the fraction with a CPE. Both are nil if only the metadata of BOM was decoded.
+	VersionedRatio *float64
+	CPERatio       *float64
+
+	// SpecInconsistencies lists fields of the SBOM that require a newer spec
+	// version than the declared one (-check-spec-consistency).
+	SpecInconsistencies []string
+
+	// ComponentCounts holds the number of components of BOM, counted in all supported ways.
+	// OriginalComponentCounts holds them before components were removed by -component-types.
+	ComponentCounts         ComponentCounts
+	OriginalComponentCounts *ComponentCounts
+
+	// CollapsedDuplicates is the number of duplicate components removed by -dedup-components.
+	CollapsedDuplicates int
+
+	// Tools lists the tools that generated BOM, as listed in its metadata.
+	// It's only set by main, if -tool-version or the index needs them.
+	Tools []SBOMTool
+
+	// UnresolvablePurls lists the package URLs of components of BOM
+	// that reference artifacts that don't exist (-verify-purl-resolvable).
+	UnresolvablePurls []string
+
+	// Classifiers lists all SBOM classifiers published for GAV.
+	// It's not populated for probes.
+	Classifiers []string
+
+	// FetchedAt is when the SBOM was downloaded.
+	FetchedAt time.Time
+
+	// LinkedFrom holds the coordinates of the SBOM that referenced this one,
+	// if it was fetched via -follow-bom-refs.
+	LinkedFrom string
+}
+
+// Discard describes why an SBOM was discarded.
+type Discard struct {
+	Reason string
+
+	// Metrics holds the values that caused the SBOM to be discarded, if any.
+	Metrics map[string]any
+}
+
+// Crawler discovers and downloads SBOMs from Maven Central.
+type Crawler struct {
+	repo       Repository
+	opts       Options
+	summary    *Summary
+	groupQuota *groupQuota
+	diskBudget *diskBudget
+	linkedBOMs *linkedBOMs
+	memory     *memoryThrottle
+	watchdog   *watchdog
+
+	// downloadSBOM is called for every version. It's a field so tests can replace it.
+	downloadSBOM func(ctx context.Context, repo Repository, gav GAV, opts Options, summary *Summary) (*Result, error)
+}
+
+// NewCrawler creates a crawler. Once diskBudget is exhausted,
+// the crawler stops initiating new downloads.
+func NewCrawler(repo Repository, opts Options, summary *Summary, diskBudget *diskBudget) *Crawler {
+	opts.Filters.decodeSlots = newDecodeSlots(opts.Filters.MaxDecodes)
+	return &Crawler{
+		repo:       repo,
+		opts:       opts,
+		summary:    summary,
+		groupQuota: newGroupQuota(opts.MaxPerGroup),
+		diskBudget: diskBudget,
+		linkedBOMs: newLinkedBOMs(opts.MaxLinkedBOMs),
+		memory:     newMemoryThrottle(opts.MemLimit),
+		watchdog:   newWatchdog(opts.StuckTimeout, opts.CancelStuck, summary),
+
+		downloadSBOM: downloadSBOM,
+	}
+}
+
+// Stream crawls Maven Central and yields a Result for every SBOM that passed
+// all filters, and for every error encountered along the way. With
+// Options.IndexDiscarded or Options.DiscardsFile, SBOMs that were discarded are yielded as well. SBOMs are yielded
+// as soon as they're downloaded, so callers apply backpressure simply by
+// consuming the channel at their own pace.
+//
+// The channel is closed once the crawl completed, or ctx was cancelled.
+func (c *Crawler) Stream(ctx context.Context) <-chan Result {
+	results := make(chan Result, c.opts.Concurrency)
+	artifactsChan := make(chan Artifact, 1)
+
+	var queue *artifactQueue
+	if c.opts.QueueFile != "" {
+		var err error
+		queue, err = openArtifactQueue(c.opts.QueueFile)
+		if err != nil {
+			results <- Result{Err: fmt.Errorf("failed to open -queue-file: %w", err)}
+			close(results)
+			return results
+		}
+		if pending := queue.Pending(); pending > 0 {
+			log.Printf("resuming crawl with %d pending artifacts from -queue-file", pending)
+		}
+	}
+
+	// Stopping the crawl early must not affect the caller's context,
+	// as it's not an interruption.
+	ctx, stop := context.WithCancel(ctx)
+	var stopOnce sync.Once
+	stopEarly := func(flag string) {
+		stopOnce.Do(func() {
+			log.Printf("stopping crawl because %s was reached", flag)
+			c.summary.SetStoppedEarly(flag)
+			stop()
+		})
+	}
+
+	// The watchdog is stopped once all workers returned, before results are closed,
+	// so that it doesn't outlive the crawl.
+	watchdogCtx, stopWatchdog := context.WithCancel(ctx)
+	watchdogDone := make(chan struct{})
+	go func() {
+		defer close(watchdogDone)
+		c.watchdog.Run(watchdogCtx)
+	}()
+
+	send := func(result Result) bool {
+		select {
+		case results <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// With -auto-concurrency, MaxConcurrency workers are started, but only
+	// as many as the limiter allows are processing artifacts at a time.
+	workers := c.opts.Concurrency
+	var limiter *concurrencyLimiter
+	if c.opts.AutoConcurrency {
+		workers = c.opts.MaxConcurrency
+		limiter = newConcurrencyLimiter(1)
+		go tuneConcurrency(ctx, limiter, c.opts.MaxConcurrency, c.summary)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for {
+				if limiter != nil && !limiter.Wait(ctx, worker) {
+					return
+				}
+				artifact, ok := <-artifactsChan
+				if !ok {
+					return
+				}
+				complete, ok := c.processArtifact(ctx, artifact, send, stopEarly)
+				if queue != nil && complete {
+					if err := queue.Done(artifact); err != nil {
+						log.Printf("failed to record %s as done: %v", artifact, err)
+					}
+				}
+				if !ok {
+					return
+				}
+			}
+		}(i)
+	}
+
+	go func() {
+		// Artifacts are fed to the workers page by page as they are discovered,
+		// so processing overlaps with the (potentially long) search phase.
+		var err error
+		if queue != nil {
+			err = c.feedQueue(ctx, queue, artifactsChan)
+		} else if c.opts.RandomOrder {
+			err = c.feedShuffled(ctx, artifactsChan)
+		} else {
+			err = discoverArtifacts(ctx, c.repo, c.opts.GroupPrefixes, c.opts.SearchConcurrency, c.acceptArtifact, artifactsChan)
+		}
+		close(artifactsChan)
+		if limiter != nil {
+			limiter.Close()
+		}
+		if err != nil {
+			send(Result{Err: err})
+		}
+
+		wg.Wait()
+		stopWatchdog()
+		<-watchdogDone
+		if queue != nil {
+			if err := queue.Close(); err != nil {
+				log.Printf("failed to close -queue-file: %v", err)
+			}
+		}
+		close(results)
+		stop()
+	}()
+
+	return results
+}
+
+// feedQueue enqueues the artifacts found by the search, continuing where a previous
+// crawl left off, and concurrently feeds the queued artifacts to artifactsChan.
+// It returns once all queued artifacts were fed, or ctx was cancelled.
+func (c *Crawler) feedQueue(ctx context.Context, queue *artifactQueue, artifactsChan chan<- Artifact) error {
+	go queue.wakeOnDone(ctx)
+
+	searchErr := make(chan error, 1)
+	if pos, done := queue.SearchPosition(); !done {
+		go func() {
+			err := searchArtifactPages(ctx, c.repo, artifactSearchQuery(c.opts.GroupPrefixes), pos, func(page []Artifact, next searchPosition) error {
+				accepted := make([]Artifact, 0, len(page))
+				for _, artifact := range page {
+					if c.acceptArtifact(artifact) {
+						accepted = append(accepted, artifact)
+					}
+				}
+				return queue.Push(accepted, next)
+			})
+			if endErr := queue.EndSearch(err == nil); err == nil {
+				err = endErr
+			}
+			searchErr <- err
+		}()
+	} else {
+		searchErr <- nil
+	}
+
+	for {
+		artifact, ok, err := queue.Pop(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		select {
+		case artifactsChan <- artifact:
+		case <-ctx.Done():
+		}
+	}
+
+	return <-searchErr
+}
+
+// feedShuffled discovers all artifacts before feeding them to artifactsChan in random order
+// (-random-order). Artifacts found before a search failed are fed nonetheless.
+//
+// Discovery order varies between runs, so artifacts are sorted before being shuffled,
+// which makes the order reproducible with the same seed and the same artifacts.
+func (c *Crawler) feedShuffled(ctx context.Context, artifactsChan chan<- Artifact) error {
+	discovered := make(chan Artifact, 1)
+	var artifacts []Artifact
+	collected := make(chan struct{})
+	go func() {
+		for artifact := range discovered {
+			artifacts = append(artifacts, artifact)
+		}
+		close(collected)
+	}()
+	err := discoverArtifacts(ctx, c.repo, c.opts.GroupPrefixes, c.opts.SearchConcurrency, c.acceptArtifact, discovered)
+	close(discovered)
+	<-collected
+
+	seed := c.opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("processing %d artifacts in random order (seed %d)", len(artifacts), seed)
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].String() < artifacts[j].String()
+	})
+	rand.New(rand.NewSource(seed)).Shuffle(len(artifacts), func(i, j int) {
+		artifacts[i], artifacts[j] = artifacts[j], artifacts[i]
+	})
+
+	for _, artifact := range artifacts {
+		select {
+		case artifactsChan <- artifact:
+		case <-ctx.Done():
+			return err
+		}
+	}
+	return err
+}
+
+// processArtifact downloads the SBOMs of all versions of artifact and sends the results.
+// It returns whether all versions were processed, i.e. none of them is to be retried
+// when the crawl is resumed, and false for ok if the crawl is to be stopped.
+func (c *Crawler) processArtifact(ctx context.Context, artifact Artifact, send func(Result) bool, stopEarly func(flag string)) (complete, ok bool) {
+	ctx, span := tracer.Start(ctx, "artifact", trace.WithAttributes(
+		attribute.String("maven.group", artifact.GroupID),
+		attribute.String("maven.artifact", artifact.ArtifactID),
+	))
+	defer span.End()
+
+	artifactLogs.Begin(artifact)
+	defer artifactLogs.End(artifact)
+
+	artifactCtx := ctx
+	if c.opts.ArtifactDeadline > 0 {
+		var cancel context.CancelFunc
+		artifactCtx, cancel = context.WithTimeout(ctx, c.opts.ArtifactDeadline)
+		defer cancel()
+	}
+
+	searchCtx, searchSpan := tracer.Start(artifactCtx, "search")
+	searchCtx, searchDone := c.watchdog.Step(searchCtx, "searching for versions of "+artifact.String())
+	versions, fromMetadata, err := c.collectVersions(searchCtx, artifact)
+	if err != nil && errors.Is(context.Cause(searchCtx), errStuck) {
+		err = fmt.Errorf("search for versions of %s %w of %s: %v", artifact, errStuck, c.opts.StuckTimeout, err)
+	}
+	searchDone()
+	endSpan(searchSpan, err)
+	if err != nil && exceededArtifactDeadline(ctx, artifactCtx) {
+		log.Printf("giving up on %s because -artifact-deadline of %s was exceeded while searching for its versions", artifact, c.opts.ArtifactDeadline)
+		span.SetAttributes(attribute.Bool("deadline.exceeded", true))
+		c.summary.AddArtifactDeadlineExceeded(artifact.String())
+		return false, true
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return false, send(Result{Err: err})
+	}
+	span.SetAttributes(attribute.Int("versions", len(versions)))
+
+	// Timestamps are recorded for every version whose SBOM could be read, regardless of
+	// whether it's kept, and checked however processing the artifact ends.
+	var timestamps []versionTimestamp
+	recordTimestamp := func(gav GAV, timestamp string) {
+		if c.opts.ValidateTimestamps && timestamp != "" {
+			timestamps = append(timestamps, versionTimestamp{version: gav.Version, timestamp: timestamp})
+		}
+	}
+	defer func() {
+		c.reportTimestampAnomalies(artifact, timestamps)
+	}()
+
+	complete = true
+	for i, version := range versions {
+		if ctx.Err() != nil {
+			return false, false
+		}
+		if exceededArtifactDeadline(ctx, artifactCtx) {
+			c.abandonArtifact(span, artifact, len(versions)-i, len(versions))
+			return false, true
+		}
+		if c.diskBudget.Exhausted() {
+			stopEarly("-max-disk-bytes")
+			return false, false
+		}
+		if c.diskBudget.SBOMLimitReached() {
+			stopEarly("-max-sboms")
+			return false, false
+		}
+		if !c.diskBudget.AcceptsGroup(version.GAV.GroupID) {
+			debugf("skipping %s because -max-sboms was reached and its group is already represented", artifact)
+			return true, true
+		}
+		if c.groupQuota.Exhausted(version.GAV.GroupID) {
+			debugf("skipping %s because its group reached -max-per-group", version.GAV)
+			c.summary.AddDiscarded(discardGroupQuota)
+			if !c.sendDiscarded(send, discardedResult(version.GAV, discardGroupQuota, map[string]any{"maxPerGroup": c.opts.MaxPerGroup})) {
+				return false, false
+			}
+			continue
+		}
+
+		if c.opts.SkipList.SkipsVersion(version.GAV) {
+			debugf("skipping %s because it's in -skip-list-file", version.GAV)
+			c.summary.AddSkipListed()
+			continue
+		}
+		if c.opts.NoClobber && c.opts.FileNameTemplate == "" && c.opts.PartitionBy == "" && sbomExists(c.opts.OutputDir, version.GAV) {
+			debugf("skipping %s because its sbom was already written", version.GAV)
+			if c.opts.ValidateTimestamps {
+				recordTimestamp(version.GAV, writtenSBOMTimestamp(c.opts.OutputDir, version.GAV))
+			}
+			continue
+		}
+
+		result, err := c.download(artifactCtx, version.GAV)
+		if err == nil {
+			recordTimestamp(version.GAV, result.Timestamp)
+		}
+		if err != nil && exceededArtifactDeadline(ctx, artifactCtx) {
+			c.abandonArtifact(span, artifact, len(versions)-i, len(versions))
+			return false, true
+		}
+		if err != nil && fromMetadata && errors.Is(err, errSBOMNotFound) {
+			// Versions listed in maven-metadata.xml aren't known to have an SBOM.
+			debugf("no sbom found for %s", version.GAV)
+			continue
+		}
+		if err != nil {
+			// Versions without an SBOM won't have one when the crawl is resumed either.
+			if !errors.Is(err, errSBOMNotFound) {
+				complete = false
+			}
+			if !send(Result{GAV: version.GAV, Err: err}) {
+				return false, false
+			}
+			continue
+		}
+		if result.Discard != nil {
+			if !c.sendDiscarded(send, result) {
+				return false, false
+			}
+			continue
+		}
+
+		// Other workers may have filled the quota while this SBOM was downloaded.
+		ok, exhausted := c.groupQuota.Take(version.GAV.GroupID)
+		if exhausted {
+			c.summary.AddGroupQuotaExhausted(version.GAV.GroupID)
+		}
+		if !ok {
+			log.Printf("discarding sbom for %s because its group reached -max-per-group", version.GAV)
+			c.summary.AddDiscarded(discardGroupQuota)
+			if !c.sendDiscarded(send, discardedResult(version.GAV, discardGroupQuota, map[string]any{"maxPerGroup": c.opts.MaxPerGroup})) {
+				return false, false
+			}
+			continue
+		}
+		result.Classifiers = version.Classifiers
+
+		var linked []string
+		if c.opts.FollowBOMRefs > 0 && result.BOM != nil {
+			linked = bomReferenceURLs(result.BOM)
+		}
+		if !send(*result) {
+			return false, false
+		}
+		if c.opts.FollowBOMRefs > 0 {
+			c.linkedBOMs.MarkVisited(result.URL)
+			if !c.followBOMRefs(artifactCtx, result.GAV, linked, 1, send) {
+				return false, false
+			}
+		}
+	}
+
+	c.summary.AddArtifact()
+	return complete, true
+}
+
+// reportTimestampAnomalies logs and records the anomalies among the timestamps of the versions of artifact.
+func (c *Crawler) reportTimestampAnomalies(artifact Artifact, timestamps []versionTimestamp) {
+	anomalies := timestampAnomalies(timestamps, c.opts.versionComparator())
+	if len(anomalies) == 0 {
+		return
+	}
+	for _, anomaly := range anomalies {
+		log.Printf("sbom for %s:%s has timestamp %s, which is older than %s of version %s", artifact, anomaly.Version, anomaly.Timestamp, anomaly.PreviousTimestamp, anomaly.PreviousVersion)
+	}
+	c.summary.AddTimestampAnomalies(artifact.String(), anomalies)
+}
+
+// exceededArtifactDeadline reports whether artifactCtx expired because of -artifact-deadline,
+// rather than because the crawl was interrupted.
+func exceededArtifactDeadline(ctx, artifactCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(artifactCtx.Err(), context.DeadlineExceeded)
+}
+
+// abandonArtifact records that the remaining versions of artifact are skipped
+// because it exceeded -artifact-deadline.
+func (c *Crawler) abandonArtifact(span trace.Span, artifact Artifact, remaining, total int) {
+	log.Printf("giving up on %d of %d versions of %s because -artifact-deadline of %s was exceeded", remaining, total, artifact, c.opts.ArtifactDeadline)
+	span.SetAttributes(attribute.Bool("deadline.exceeded", true))
+	c.summary.AddArtifactDeadlineExceeded(artifact.String())
+}
+
+// collectVersions enumerates the versions of artifact according to -version-source.
+// fromMetadata is set if they were read from maven-metadata.xml rather than searched with Solr.
+// When an artifact has no maven-metadata.xml, Solr is searched instead.
+func (c *Crawler) collectVersions(ctx context.Context, artifact Artifact) (versions []VersionMatch, fromMetadata bool, err error) {
+	newerThan := c.opts.LatestVersions[artifact.String()]
+	if c.opts.VersionSource == versionSourceMetadata {
+		versions, err = collectMetadataVersions(ctx, c.repo, artifact, newerThan, c.opts.versionComparator())
+		if !errors.Is(err, errMetadataNotFound) {
+			return versions, err == nil, err
+		}
+		log.Printf("%v, falling back to solr search", err)
+	}
+
+	versions, err = collectVersions(ctx, c.repo, artifact, c.opts.sbomSuffixes(), newerThan, c.opts.versionComparator())
+	return versions, false, err
+}
+
+// download wraps downloadSBOM in a span, and recovers from panics in it.
+// With Options.GAVBudget, the download is abandoned once it took longer than that,
+// regardless of how many attempts were made.
+// With Options.MemLimit, the download waits while the heap is close to the limit.
+// With Options.StuckTimeout, the download is watched for making no progress.
+func (c *Crawler) download(ctx context.Context, gav GAV) (*Result, error) {
+	ctx, span := tracer.Start(ctx, "download", trace.WithAttributes(gavAttributes(gav)...))
+
+	if err := c.memory.Acquire(ctx); err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	defer c.memory.Release()
+
+	ctx, done := c.watchdog.Step(ctx, "downloading sbom for "+gav.String())
+	defer done()
+
+	downloadCtx := ctx
+	if c.opts.GAVBudget > 0 {
+		var cancel context.CancelFunc
+		downloadCtx, cancel = context.WithTimeout(ctx, c.opts.GAVBudget)
+		defer cancel()
+	}
+
+	var result *Result
+	err := safely(gav, c.summary, func() (err error) {
+		result, err = c.downloadSBOM(downloadCtx, c.repo, gav, c.opts, c.summary)
+		return err
+	})
+	if err != nil && ctx.Err() == nil && errors.Is(downloadCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("exceeded -gav-budget of %s: %w", c.opts.GAVBudget, err)
+	}
+	if err != nil && errors.Is(context.Cause(ctx), errStuck) {
+		err = fmt.Errorf("download %w of %s: %v", errStuck, c.opts.StuckTimeout, err)
+	}
+	if result != nil {
+		if result.Discard != nil {
+			span.SetAttributes(attribute.String("discard.reason", result.Discard.Reason))
+		} else {
+			span.SetAttributes(
+				attribute.Int("sbom.size", len(result.Raw)),
+				attribute.String("sbom.format", formatName(result.Format)),
+			)
+		}
+	}
+	endSpan(span, err)
+
+	return result, err
+}
+
+// sendDiscarded sends result if discarded SBOMs are to be yielded.
+// It returns false if sending was aborted.
+func (c *Crawler) sendDiscarded(send func(Result) bool, result *Result) bool {
+	if !c.opts.yieldDiscarded() {
+		return true
+	}
+	return send(*result)
+}
+
+// acceptArtifact applies the skip list and the group lists to a discovered artifact.
+// Exclusions take precedence over inclusions.
+func (c *Crawler) acceptArtifact(artifact Artifact) bool {
+	if c.opts.SkipList.SkipsArtifact(artifact) {
+		debugf("skipping %s because it's in -skip-list-file", artifact)
+		c.summary.AddFilteredArtifact(filterSkipList)
+		return false
+	}
+	if c.opts.ExcludeGroups != nil && c.opts.ExcludeGroups.Matches(artifact.GroupID) {
+		debugf("skipping %s because its group is excluded", artifact)
+		c.summary.AddFilteredArtifact(filterExcludeGroups)
+		return false
+	}
+	if c.opts.IncludeGroups != nil && !c.opts.IncludeGroups.Matches(artifact.GroupID) {
+		debugf("skipping %s because its group is not included", artifact)
+		c.summary.AddFilteredArtifact(filterIncludeGroups)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrawlerRecoversFromPanics(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("lib", "1.0.0", "2.0.0")))
+
+	opts := Options{
+		Concurrency:  1,
+		SBOMSuffixes: []string{"-cyclonedx.json"},
+	}
+	summary := NewSummary()
+	crawler := NewCrawler(repo, opts, summary, newDiskBudget(0))
+	crawler.downloadSBOM = func(_ context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+		if gav.Version == "1.0.0" {
+			var result *Result
+			_ = result.BOM // nil dereference
+		}
+		return &Result{GAV: gav}, nil
+	}
+
+	var results []Result
+	for result := range crawler.Stream(context.Background()) {
+		results = append(results, result)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d: %+v", len(results), results)
+	}
+	if results[0].GAV.Version != "1.0.0" || results[0].Err == nil || !strings.HasPrefix(results[0].Err.Error(), "panic:") {
+		t.Errorf("expected a panic error for 1.0.0, got %+v", results[0])
+	}
+	if results[1].GAV.Version != "2.0.0" || results[1].Err != nil {
+		t.Errorf("expected a successful result for 2.0.0, got %+v", results[1])
+	}
+	if summary.panics != 1 {
+		t.Errorf("expected 1 recorded panic, got %d", summary.panics)
+	}
+}
+
+func TestCrawlerGAVBudget(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("lib", "1.0.0")))
+
+	opts := Options{
+		Concurrency:  1,
+		SBOMSuffixes: []string{"-cyclonedx.json"},
+		GAVBudget:    10 * time.Millisecond,
+	}
+	crawler := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0))
+	crawler.downloadSBOM = func(ctx context.Context, _ Repository, _ GAV, _ Options, _ *Summary) (*Result, error) {
+		// Simulate endless retries.
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	var results []Result
+	for result := range crawler.Stream(context.Background()) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "exceeded -gav-budget") {
+		t.Fatalf("expected a -gav-budget error, got %+v", results)
+	}
+}
+
+func TestCrawlerArtifactDeadline(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("lib", "1.0.0", "2.0.0")))
+
+	opts := Options{
+		Concurrency:      1,
+		SBOMSuffixes:     []string{"-cyclonedx.json"},
+		ArtifactDeadline: 50 * time.Millisecond,
+		QueueFile:        filepath.Join(t.TempDir(), "queue.txt"),
+	}
+	summary := NewSummary()
+	crawler := NewCrawler(repo, opts, summary, newDiskBudget(0))
+	crawler.downloadSBOM = func(ctx context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+		if gav.Version == "1.0.0" {
+			return &Result{GAV: gav}, nil
+		}
+		// Simulate endless retries.
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	var results []Result
+	for result := range crawler.Stream(context.Background()) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].GAV.Version != "1.0.0" || results[0].Err != nil {
+		t.Fatalf("expected only a successful result for 1.0.0, got %+v", results)
+	}
+	if report := summary.Report(); report.Artifacts != 0 || !reflect.DeepEqual(report.DeadlineArtifacts, []string{"org.example:lib"}) {
+		t.Errorf("expected org.example:lib to be recorded as exceeding the deadline, got %+v", report)
+	}
+
+	// The abandoned artifact stays pending in -queue-file, so it's processed again when resuming the crawl.
+	crawler = NewCrawler(repo, opts, NewSummary(), newDiskBudget(0))
+	crawler.downloadSBOM = func(_ context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+		return &Result{GAV: gav}, nil
+	}
+	results = nil
+	for result := range crawler.Stream(context.Background()) {
+		results = append(results, result)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected both versions of the abandoned artifact when resuming the crawl, got %+v", results)
+	}
+}
+
+func TestCrawlerTimestampAnomalies(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("lib", "1.0.0", "2.0.0", "3.0.0")))
+
+	opts := Options{
+		Concurrency:        1,
+		SBOMSuffixes:       []string{"-cyclonedx.json"},
+		ValidateTimestamps: true,
+		ArtifactDeadline:   50 * time.Millisecond,
+	}
+	summary := NewSummary()
+	crawler := NewCrawler(repo, opts, summary, newDiskBudget(0))
+	crawler.downloadSBOM = func(ctx context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+		switch gav.Version {
+		case "1.0.0":
+			return &Result{GAV: gav, Timestamp: "2024-02-01T00:00:00Z"}, nil
+		case "2.0.0":
+			// Discarded SBOMs count as well.
+			return &Result{GAV: gav, Timestamp: "2024-01-01T00:00:00Z", Discard: &Discard{Reason: discardTooFewComponents}}, nil
+		}
+		// The artifact is abandoned at its deadline, which must not skip the check.
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	for range crawler.Stream(context.Background()) {
+	}
+
+	expected := []TimestampAnomaly{{Version: "2.0.0", Timestamp: "2024-01-01T00:00:00Z", PreviousVersion: "1.0.0", PreviousTimestamp: "2024-02-01T00:00:00Z"}}
+	if anomalies := summary.Report().TimestampAnomalies["org.example:lib"]; !reflect.DeepEqual(anomalies, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, anomalies)
+	}
+}
+
+func TestCrawlerCancelStuck(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("lib", "1.0.0")))
+
+	opts := Options{
+		Concurrency:  1,
+		SBOMSuffixes: []string{"-cyclonedx.json"},
+		StuckTimeout: 50 * time.Millisecond,
+		CancelStuck:  true,
+	}
+	summary := NewSummary()
+	crawler := NewCrawler(repo, opts, summary, newDiskBudget(0))
+	crawler.downloadSBOM = func(ctx context.Context, _ Repository, _ GAV, _ Options, _ *Summary) (*Result, error) {
+		// Simulate a hung download.
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	var results []Result
+	for result := range crawler.Stream(context.Background()) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || !errors.Is(results[0].Err, errStuck) {
+		t.Fatalf("expected the download to be cancelled as stuck, got %+v", results)
+	}
+	if report := summary.Report(); !reflect.DeepEqual(report.StuckWorkers, []string{"downloading sbom for org.example:lib:1.0.0"}) {
+		t.Errorf("expected the download to be recorded as stuck, got %v", report.StuckWorkers)
+	}
+}
+
+func TestCrawlerAutoConcurrency(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("lib", "1.0.0")))
+
+	opts := Options{
+		Concurrency:     1,
+		AutoConcurrency: true,
+		MaxConcurrency:  3,
+		SBOMSuffixes:    []string{"-cyclonedx.json"},
+	}
+	crawler := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0))
+	crawler.downloadSBOM = func(_ context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+		return &Result{GAV: gav}, nil
+	}
+
+	// Workers that are held back by the limiter must not block the crawl from completing.
+	var results []Result
+	for result := range crawler.Stream(context.Background()) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("expected a single successful result, got %+v", results)
+	}
+}
+
+func TestCrawlerQueueFile(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, sbomArtifact("a", "1.0.0"), sbomArtifact("b", "1.0.0")))
+
+	opts := Options{
+		Concurrency:  1,
+		SBOMSuffixes: []string{"-cyclonedx.json"},
+		QueueFile:    filepath.Join(t.TempDir(), "queue.txt"),
+	}
+	crawl := func(failing string) []string {
+		crawler := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0))
+		crawler.downloadSBOM = func(_ context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+			if gav.ArtifactID == failing {
+				return nil, errors.New("connection reset")
+			}
+			return &Result{GAV: gav}, nil
+		}
+
+		var gavs []string
+		for result := range crawler.Stream(context.Background()) {
+			if result.Err == nil {
+				gavs = append(gavs, result.GAV.String())
+			} else if result.GAV.ArtifactID != failing {
+				t.Fatalf("unexpected error: %v", result.Err)
+			}
+		}
+		return gavs
+	}
+
+	if gavs := crawl("b"); len(gavs) != 1 {
+		t.Fatalf("expected 1 result, got %v", gavs)
+	}
+
+	// The failed artifact is processed again when resuming the crawl.
+	if gavs := crawl(""); !reflect.DeepEqual(gavs, []string{"org.example:b:1.0.0"}) {
+		t.Fatalf("expected only org.example:b:1.0.0 when resuming the crawl, got %v", gavs)
+	}
+
+	// The crawl completed, so there's nothing left to do when resuming it.
+	if gavs := crawl(""); len(gavs) != 0 {
+		t.Fatalf("expected no results when resuming a completed crawl, got %v", gavs)
+	}
+}
+
+func TestCrawlerRandomOrder(t *testing.T) {
+	artifacts := []string{"a", "b", "c", "d", "e", "f"}
+	var fixtures []fixtureArtifact
+	for _, artifact := range artifacts {
+		fixtures = append(fixtures, sbomArtifact(artifact, "1.0.0"))
+	}
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, fixtures...))
+
+	crawl := func(seed int64) []string {
+		opts := Options{
+			Concurrency:  1,
+			SBOMSuffixes: []string{"-cyclonedx.json"},
+			RandomOrder:  true,
+			Seed:         seed,
+		}
+		crawler := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0))
+		crawler.downloadSBOM = func(_ context.Context, _ Repository, gav GAV, _ Options, _ *Summary) (*Result, error) {
+			return &Result{GAV: gav}, nil
+		}
+
+		var order []string
+		for result := range crawler.Stream(context.Background()) {
+			if result.Err != nil {
+				t.Fatalf("unexpected error: %v", result.Err)
+			}
+			order = append(order, result.GAV.ArtifactID)
+		}
+		return order
+	}
+
+	order := crawl(42)
+	if len(order) != len(artifacts) {
+		t.Fatalf("expected %d results, got %v", len(artifacts), order)
+	}
+	if reflect.DeepEqual(order, artifacts) {
+		t.Errorf("expected artifacts to be processed in random order, got search order %v", order)
+	}
+	if again := crawl(42); !reflect.DeepEqual(again, order) {
+		t.Errorf("expected the same order %v with the same seed, got %v", order, again)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+var knownComponentTypes = map[cyclonedx.ComponentType]bool{
+	cyclonedx.ComponentTypeApplication: true,
+	cyclonedx.ComponentTypeContainer:   true,
+	cyclonedx.ComponentTypeDevice:      true,
+	cyclonedx.ComponentTypeFile:        true,
+	cyclonedx.ComponentTypeFirmware:    true,
+	cyclonedx.ComponentTypeFramework:   true,
+	cyclonedx.ComponentTypeLibrary:     true,
+	cyclonedx.ComponentTypeOS:          true,
+}
+
+// decodeSBOM decodes data into bom, and returns warnings about content the
+// decoder silently accepts: unknown fields and unknown component types or scopes.
+// Unknown fields are only looked for in JSON, and only if lenient or strict is set,
+// as that requires decoding data a second time.
+//
+// Values of unexpected types are errors, unless lenient is set. The decoder
+// skips over them, so with lenient they are returned as warnings instead,
+// and nonStrict is set to signal that bom is incomplete.
+//
+// signed is set if a JSON SBOM has a top-level signature, which bom can't hold.
+// Tools in the object form of CycloneDX 1.5 are converted to the array that bom holds
+// (see decodeJSONMetadata), and spec versions newer than 1.4 are accepted (see decodeSpecVersion).
+func decodeSBOM(data []byte, format cyclonedx.BOMFileFormat, lenient, strict bool, bom *cyclonedx.BOM) (warnings []string, nonStrict, signed bool, err error) {
+	if format == cyclonedx.BOMFileFormatJSON {
+		// This is what the JSON decoder of cyclonedx-go does, plus the spec version, signature and tools.
+		envelope := jsonBOM{BOM: bom}
+		err = json.Unmarshal(data, &envelope)
+		signed = isJSONValue(envelope.Signature)
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			var specErr, metadataErr error
+			bom.SpecVersion, specErr = decodeSpecVersion(envelope.SpecVersion)
+			bom.Metadata, metadataErr = decodeJSONMetadata(envelope.Metadata)
+			if err == nil {
+				err = errors.Join(specErr, metadataErr)
+			}
+		}
+	} else {
+		err = cyclonedx.NewBOMDecoder(bytes.NewReader(data), format).Decode(bom)
+		if err == nil && bom.SpecVersion == 0 {
+			bom.SpecVersion = xmlSpecVersion(bom.XMLNS)
+		}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if err != nil {
+		if !lenient || !errors.As(err, &typeErr) {
+			return nil, false, false, err
+		}
+		warnings = append(warnings, err.Error())
+		nonStrict = true
+	}
+
+	if format == cyclonedx.BOMFileFormatJSON && (lenient || strict) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		// The spec version was already checked above.
+		fields := struct {
+			*cyclonedx.BOM
+			SpecVersion json.RawMessage `json:"specVersion"`
+		}{BOM: &cyclonedx.BOM{}}
+		if err := dec.Decode(&fields); err != nil && !errors.As(err, &typeErr) {
+			warnings = append(warnings, err.Error())
+		}
+	}
+
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			warnings = append(warnings, componentWarnings(component)...)
+			visit(component.Components)
+		}
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		warnings = append(warnings, componentWarnings(*bom.Metadata.Component)...)
+		visit(bom.Metadata.Component.Components)
+	}
+	visit(bom.Components)
+
+	return warnings, nonStrict, signed, nil
+}
+
+// jsonBOM decodes a JSON SBOM along with its top-level signature (JSON Signature Format).
+// Its spec version and metadata are left to decodeSpecVersion and decodeJSONMetadata.
+type jsonBOM struct {
+	*cyclonedx.BOM
+	SpecVersion json.RawMessage `json:"specVersion"`
+	Metadata    json.RawMessage `json:"metadata"`
+	Signature   json.RawMessage `json:"signature"`
+}
+
+// xmlSpecVersion returns the spec version of an XML SBOM with the namespace ns, if cyclonedx-go
+// doesn't know it. Like with decodeSpecVersion, newer 1.x versions are treated as 1.4.
+func xmlSpecVersion(ns string) cyclonedx.SpecVersion {
+	version, ok := strings.CutPrefix(ns, "http://cyclonedx.org/schema/bom/")
+	if minor, valid := specMinor(version); ok && valid && minor > 4 {
+		return cyclonedx.SpecVersion1_4
+	}
+	return 0
+}
+
+// decodeSpecVersion decodes the specVersion of a JSON SBOM. cyclonedx-go only knows spec
+// versions up to 1.4, and rejects all others. Newer 1.x versions only add fields though,
+// so they're decoded as 1.4 instead, which is what the SBOM can be re-encoded as.
+// A missing spec version is decoded as zero.
+func decodeSpecVersion(data json.RawMessage) (cyclonedx.SpecVersion, error) {
+	if !isJSONValue(data) {
+		return 0, nil
+	}
+
+	var version cyclonedx.SpecVersion
+	err := json.Unmarshal(data, &version)
+	if !errors.Is(err, cyclonedx.ErrInvalidSpecVersion) {
+		return version, err
+	}
+	var declared string
+	if json.Unmarshal(data, &declared) == nil {
+		if minor, ok := specMinor(declared); ok && minor > 4 {
+			return cyclonedx.SpecVersion1_4, nil
+		}
+	}
+	return 0, err
+}
+
+// decodeJSONMetadata decodes the metadata of a JSON SBOM. cyclonedx.Metadata only supports
+// the array of tools, so the components and services of the object form of CycloneDX 1.5
+// are converted to tools with the same name and version. It returns nil for null metadata.
+func decodeJSONMetadata(data json.RawMessage) (*cyclonedx.Metadata, error) {
+	if !isJSONValue(data) {
+		return nil, nil
+	}
+
+	metadata := &cyclonedx.Metadata{}
+	fields := struct {
+		*cyclonedx.Metadata
+		Tools json.RawMessage `json:"tools"`
+	}{Metadata: metadata}
+	err := json.Unmarshal(data, &fields)
+	if !isJSONValue(fields.Tools) {
+		return metadata, err
+	}
+
+	var tools []cyclonedx.Tool
+	var toolsErr error
+	if bytes.HasPrefix(bytes.TrimSpace(fields.Tools), []byte("{")) {
+		var object struct {
+			Components []cyclonedx.Tool `json:"components"`
+			Services   []cyclonedx.Tool `json:"services"`
+		}
+		toolsErr = json.Unmarshal(fields.Tools, &object)
+		tools = append(object.Components, object.Services...)
+	} else {
+		toolsErr = json.Unmarshal(fields.Tools, &tools)
+	}
+	metadata.Tools = &tools
+	if err == nil {
+		err = toolsErr
+	}
+	return metadata, err
+}
+
+// isJSONValue reports whether raw holds a value other than null.
+func isJSONValue(raw json.RawMessage) bool {
+	return len(raw) > 0 && !bytes.Equal(raw, []byte("null"))
+}
+
+func componentWarnings(component cyclonedx.Component) []string {
+	var warnings []string
+	if component.Type != "" && !knownComponentTypes[component.Type] {
+		warnings = append(warnings, fmt.Sprintf("component %q has unknown type %q", component.Name, component.Type))
+	}
+	if scope := componentScope(component); scope != cyclonedx.ScopeRequired && scope != cyclonedx.ScopeOptional && scope != cyclonedx.ScopeExcluded {
+		warnings = append(warnings, fmt.Sprintf("component %q has unknown scope %q", component.Name, component.Scope))
+	}
+	return warnings
+}
+
+// decodeSBOMMetadata decodes only bomFormat, specVersion, serialNumber, version
+// and metadata of the JSON SBOM in data into bom, without materializing any
+// components. The top-level components are only counted, and all other fields
+// are skipped. The decoded bom must not be re-encoded, as it's incomplete.
+// signed is set if the SBOM has a top-level signature.
+func decodeSBOMMetadata(data []byte, bom *cyclonedx.BOM) (components int, signed bool, err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, false, err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return 0, false, err
+		}
+		key, _ := token.(string)
+
+		switch key {
+		case "bomFormat":
+			err = dec.Decode(&bom.BOMFormat)
+		case "specVersion":
+			var specVersion json.RawMessage
+			if err = dec.Decode(&specVersion); err == nil {
+				bom.SpecVersion, err = decodeSpecVersion(specVersion)
+			}
+		case "serialNumber":
+			err = dec.Decode(&bom.SerialNumber)
+		case "version":
+			err = dec.Decode(&bom.Version)
+		case "metadata":
+			var metadata json.RawMessage
+			if err = dec.Decode(&metadata); err == nil {
+				bom.Metadata, err = decodeJSONMetadata(metadata)
+			}
+		case "components":
+			components, err = countJSONArray(dec)
+		case "signature":
+			var signature json.RawMessage
+			err = dec.Decode(&signature)
+			signed = isJSONValue(signature)
+		default:
+			err = dec.Decode(&json.RawMessage{})
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+	}
+
+	return components, signed, expectDelim(dec, '}')
+}
+
+// countJSONArray counts the elements of the array at the position of dec, skipping over them.
+// A null array has no elements.
+func countJSONArray(dec *json.Decoder) (int, error) {
+	token, err := dec.Token()
+	if err != nil || token == nil {
+		return 0, err
+	}
+	if token != json.Delim('[') {
+		return 0, fmt.Errorf("expected array, got %v", token)
+	}
+
+	count := 0
+	for dec.More() {
+		var element json.RawMessage
+		if err := dec.Decode(&element); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// decodeSlots limits how many SBOMs are decoded concurrently, for -max-decodes.
+// A nil decodeSlots doesn't limit decodes.
+type decodeSlots chan struct{}
+
+func newDecodeSlots(max int) decodeSlots {
+	if max <= 0 {
+		return nil
+	}
+	return make(decodeSlots, max)
+}
+
+// acquire waits for a free slot, unless ctx is done first.
+func (s decodeSlots) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s decodeSlots) release() {
+	if s != nil {
+		<-s
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestDecodeSBOM(t *testing.T) {
+	testCases := []struct {
+		name      string
+		data      string
+		lenient   bool
+		strict    bool
+		warnings  int
+		nonStrict bool
+		wantErr   bool
+	}{
+		{
+			name: "Clean",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "components": [{"type": "library", "name": "a"}]}`,
+		},
+		{
+			name: "UnknownField",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "foo": "bar"}`,
+		},
+		{
+			name:     "UnknownFieldStrict",
+			data:     `{"bomFormat": "CycloneDX", "specVersion": "1.4", "foo": "bar"}`,
+			strict:   true,
+			warnings: 1,
+		},
+		{
+			name:     "UnknownEnumValues",
+			data:     `{"bomFormat": "CycloneDX", "specVersion": "1.4", "components": [{"type": "librar", "name": "a", "components": [{"type": "library", "name": "b", "scope": "maybe"}]}]}`,
+			warnings: 2,
+		},
+		{
+			name:    "UnexpectedType",
+			data:    `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": "1", "components": [{"type": "library", "name": "a"}]}`,
+			wantErr: true,
+		},
+		{
+			name:      "UnexpectedTypeLenient",
+			data:      `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": "1", "components": [{"type": "library", "name": "a"}]}`,
+			lenient:   true,
+			warnings:  1,
+			nonStrict: true,
+		},
+		{
+			name: "NewerSpecVersion",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.5", "metadata": {"tools": {"components": [{"name": "a"}]}}}`,
+		},
+		{
+			name:    "UnknownSpecVersion",
+			data:    `{"bomFormat": "CycloneDX", "specVersion": "2.0"}`,
+			wantErr: true,
+		},
+		{
+			name:    "SyntaxErrorLenient",
+			data:    `{"bomFormat": "CycloneDX",`,
+			lenient: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bom cyclonedx.BOM
+			warnings, nonStrict, _, err := decodeSBOM([]byte(tc.data), cyclonedx.BOMFileFormatJSON, tc.lenient, tc.strict, &bom)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(warnings) != tc.warnings || nonStrict != tc.nonStrict {
+				t.Fatalf("expected %d warnings (non-strict: %t), got %v (non-strict: %t)", tc.warnings, tc.nonStrict, warnings, nonStrict)
+			}
+			if tc.nonStrict && (bom.Components == nil || len(*bom.Components) != 1) {
+				t.Fatal("expected components to be decoded despite the error")
+			}
+		})
+	}
+}
+
+func TestXMLSpecVersion(t *testing.T) {
+	for ns, expected := range map[string]cyclonedx.SpecVersion{
+		"http://cyclonedx.org/schema/bom/1.5": cyclonedx.SpecVersion1_4,
+		"http://cyclonedx.org/schema/bom/1.6": cyclonedx.SpecVersion1_4,
+		"http://cyclonedx.org/schema/bom/2.0": 0,
+		"http://example.com/schema/bom/1.5":   0,
+	} {
+		if version := xmlSpecVersion(ns); version != expected {
+			t.Errorf("expected spec version %d for %s, got %d", expected, ns, version)
+		}
+	}
+}
+
+func TestDecodeSBOMMetadata(t *testing.T) {
+	data := []byte(`{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.4",
+		"serialNumber": "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79",
+		"version": 1,
+		"components": [
+			{"type": "library", "name": "a", "components": [{"type": "library", "name": "a1"}]},
+			{"type": "library", "name": "b"}
+		],
+		"metadata": {"timestamp": "2024-01-01T00:00:00Z", "component": {"type": "library", "name": "lib"}},
+		"dependencies": [{"ref": "a"}],
+		"signature": {"algorithm": "ES256", "value": "c2ln"}
+	}`)
+
+	var bom cyclonedx.BOM
+	components, signed, err := decodeSBOMMetadata(data, &bom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components != 2 || !signed {
+		t.Errorf("expected 2 top-level components and a signature, got %d (signed: %t)", components, signed)
+	}
+	if bom.SpecVersion != cyclonedx.SpecVersion1_4 || bom.SerialNumber != "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79" || bom.Version != 1 {
+		t.Errorf("unexpected header fields: %+v", bom)
+	}
+	if bom.Metadata == nil || bom.Metadata.Component == nil || bom.Metadata.Component.Name != "lib" {
+		t.Errorf("expected metadata to be decoded, got %+v", bom.Metadata)
+	}
+	if bom.Components != nil || bom.Dependencies != nil {
+		t.Error("expected components and dependencies not to be decoded")
+	}
+
+	if _, _, err := decodeSBOMMetadata([]byte(`{"components": 42}`), &cyclonedx.BOM{}); err == nil {
+		t.Error("expected an error for components that aren't an array")
+	}
+}
+
+func TestDecodeSlots(t *testing.T) {
+	slots := newDecodeSlots(2)
+
+	var (
+		mux               sync.Mutex
+		active, maxActive int
+	)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := slots.acquire(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			defer slots.release()
+
+			mux.Lock()
+			active++
+			if active > maxActive {
+				maxActive = active
+			}
+			mux.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mux.Lock()
+			active--
+			mux.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxActive > 2 {
+		t.Fatalf("expected at most 2 concurrent decodes, got %d", maxActive)
+	}
+
+	// Waiting for a slot ends with the context.
+	full := newDecodeSlots(1)
+	_ = full.acquire(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := full.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Without -max-decodes, acquiring never blocks.
+	unlimited := newDecodeSlots(0)
+	for i := 0; i < 100; i++ {
+		_ = unlimited.acquire(context.Background())
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func runDiff(args []string) {
+	var asJSON bool
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s diff:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "  %s diff [flags] OLD NEW\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&asJSON, "json", false, "Print the diff as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	boms := make([]*cyclonedx.BOM, 2)
+	for i, path := range fs.Args() {
+		bom, err := decodeBOMFile(path)
+		if err != nil {
+			log.Fatalf("failed to decode %s: %v", path, err)
+		}
+		boms[i] = bom
+	}
+
+	diff := diffBOMs(boms[0], boms[1])
+
+	var err error
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(diff)
+	} else {
+		err = diff.Print(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("failed to print diff: %v", err)
+	}
+}
+
+// BOMDiff describes the differences between two BOMs.
+// Components are identified by their package URL without version,
+// or by group and name if they have no package URL. If a BOM contains
+// several versions of a component, each of them is compared (see pairComponents).
+type BOMDiff struct {
+	Added   []ComponentVersion `json:"added"`
+	Removed []ComponentVersion `json:"removed"`
+	Changed []ComponentChange  `json:"changed"`
+
+	AddedDependencies   []Edge `json:"addedDependencies"`
+	RemovedDependencies []Edge `json:"removedDependencies"`
+}
+
+// Edge is a dependency of From on To.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ComponentVersion struct {
+	Key     string `json:"key"`
+	Version string `json:"version,omitempty"`
+}
+
+type ComponentChange struct {
+	Key         string   `json:"key"`
+	OldVersion  string   `json:"oldVersion,omitempty"`
+	NewVersion  string   `json:"newVersion,omitempty"`
+	OldLicenses []string `json:"oldLicenses,omitempty"`
+	NewLicenses []string `json:"newLicenses,omitempty"`
+}
+
+// Print writes a human-readable representation of d to w.
+func (d BOMDiff) Print(w io.Writer) error {
+	var sb strings.Builder
+	for _, c := range d.Added {
+		fmt.Fprintf(&sb, "+ %s %s\n", c.Key, c.Version)
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(&sb, "- %s %s\n", c.Key, c.Version)
+	}
+	for _, c := range d.Changed {
+		if c.OldVersion != c.NewVersion {
+			fmt.Fprintf(&sb, "~ %s %s -> %s\n", c.Key, c.OldVersion, c.NewVersion)
+		}
+		if !equalStrings(c.OldLicenses, c.NewLicenses) {
+			fmt.Fprintf(&sb, "~ %s licenses: %s -> %s\n", c.Key, licenseList(c.OldLicenses), licenseList(c.NewLicenses))
+		}
+	}
+	for _, e := range d.AddedDependencies {
+		fmt.Fprintf(&sb, "+ %s -> %s\n", e.From, e.To)
+	}
+	for _, e := range d.RemovedDependencies {
+		fmt.Fprintf(&sb, "- %s -> %s\n", e.From, e.To)
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("no differences\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func licenseList(licenses []string) string {
+	if len(licenses) == 0 {
+		return "(none)"
+	}
+	return strings.Join(licenses, ", ")
+}
+
+type diffComponent struct {
+	version  string
+	licenses []string
+}
+
+// diffBOMs compares the top-level components and the dependency graphs of a and b.
+func diffBOMs(a, b *cyclonedx.BOM) BOMDiff {
+	oldComponents, oldRefs := diffComponents(a)
+	newComponents, newRefs := diffComponents(b)
+
+	diff := BOMDiff{
+		Added:               make([]ComponentVersion, 0),
+		Removed:             make([]ComponentVersion, 0),
+		Changed:             make([]ComponentChange, 0),
+		AddedDependencies:   make([]Edge, 0),
+		RemovedDependencies: make([]Edge, 0),
+	}
+	keys := make(map[string]bool)
+	for key := range oldComponents {
+		keys[key] = true
+	}
+	for key := range newComponents {
+		keys[key] = true
+	}
+	for key := range keys {
+		pairs, removed, added := pairComponents(oldComponents[key], newComponents[key])
+		for _, pair := range pairs {
+			oc, nc := pair[0], pair[1]
+			if oc.version != nc.version || !equalStrings(oc.licenses, nc.licenses) {
+				diff.Changed = append(diff.Changed, ComponentChange{
+					Key:         key,
+					OldVersion:  oc.version,
+					NewVersion:  nc.version,
+					OldLicenses: oc.licenses,
+					NewLicenses: nc.licenses,
+				})
+			}
+		}
+		for _, oc := range removed {
+			diff.Removed = append(diff.Removed, ComponentVersion{Key: key, Version: oc.version})
+		}
+		for _, nc := range added {
+			diff.Added = append(diff.Added, ComponentVersion{Key: key, Version: nc.version})
+		}
+	}
+
+	// bom-refs are not stable across BOMs, so edges are compared by component key.
+	oldEdges := diffEdges(a, oldRefs)
+	newEdges := diffEdges(b, newRefs)
+	for edge := range newEdges {
+		if !oldEdges[edge] {
+			diff.AddedDependencies = append(diff.AddedDependencies, edge)
+		}
+	}
+	for edge := range oldEdges {
+		if !newEdges[edge] {
+			diff.RemovedDependencies = append(diff.RemovedDependencies, edge)
+		}
+	}
+
+	sortComponentVersions(diff.Added)
+	sortComponentVersions(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		if diff.Changed[i].Key != diff.Changed[j].Key {
+			return diff.Changed[i].Key < diff.Changed[j].Key
+		}
+		return diff.Changed[i].OldVersion < diff.Changed[j].OldVersion
+	})
+	sortEdges(diff.AddedDependencies)
+	sortEdges(diff.RemovedDependencies)
+
+	return diff
+}
+
+// diffComponents returns the top-level components and the root component of bom by key,
+// along with a mapping of bom-refs to keys. A key has several components if bom contains
+// several versions of a package.
+func diffComponents(bom *cyclonedx.BOM) (map[string][]diffComponent, map[string]string) {
+	components := make(map[string][]diffComponent)
+	refs := make(map[string]string)
+
+	add := func(component cyclonedx.Component) {
+		key := componentKey(component)
+		if component.BOMRef != "" {
+			refs[component.BOMRef] = key
+		}
+		components[key] = append(components[key], diffComponent{
+			version:  component.Version,
+			licenses: componentLicenses(component),
+		})
+	}
+
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		add(*bom.Metadata.Component)
+	}
+	if bom.Components != nil {
+		for _, component := range *bom.Components {
+			add(component)
+		}
+	}
+
+	return components, refs
+}
+
+// pairComponents pairs the versions of a component in an old BOM with those in a new one.
+// Equal versions are paired first, and the remaining ones in version order, so that only
+// versions that actually differ are reported as changed. Versions left over were removed or added.
+func pairComponents(before, after []diffComponent) (pairs [][2]diffComponent, removed, added []diffComponent) {
+	added = append([]diffComponent(nil), after...)
+	for _, oc := range before {
+		i := 0
+		for i < len(added) && added[i].version != oc.version {
+			i++
+		}
+		if i == len(added) {
+			removed = append(removed, oc)
+			continue
+		}
+		pairs = append(pairs, [2]diffComponent{oc, added[i]})
+		added = append(added[:i], added[i+1:]...)
+	}
+
+	comparator := MavenVersionComparator{}
+	byVersion := func(components []diffComponent) {
+		sort.SliceStable(components, func(i, j int) bool {
+			return comparator.Compare(components[i].version, components[j].version) < 0
+		})
+	}
+	byVersion(removed)
+	byVersion(added)
+	for len(removed) > 0 && len(added) > 0 {
+		pairs = append(pairs, [2]diffComponent{removed[0], added[0]})
+		removed, added = removed[1:], added[1:]
+	}
+
+	return pairs, removed, added
+}
+
+func diffEdges(bom *cyclonedx.BOM, refs map[string]string) map[Edge]bool {
+	edges := make(map[Edge]bool)
+	if bom.Dependencies == nil {
+		return edges
+	}
+
+	for _, dep := range *bom.Dependencies {
+		if dep.Dependencies == nil {
+			continue
+		}
+		for _, dependsOn := range *dep.Dependencies {
+			edges[Edge{From: refKey(refs, dep.Ref), To: refKey(refs, dependsOn)}] = true
+		}
+	}
+
+	return edges
+}
+
+// refKey returns the key of the component with bom-ref ref, as mapped in refs.
+// Refs that don't belong to a component, e.g. those of services, are returned as is.
+func refKey(refs map[string]string, ref string) string {
+	if key, ok := refs[ref]; ok {
+		return key
+	}
+	return ref
+}
+
+// componentKey identifies a component independently of its version.
+func componentKey(component cyclonedx.Component) string {
+	if component.PackageURL != "" {
+		key, _, _ := strings.Cut(component.PackageURL, "?")
+		key, _, _ = strings.Cut(key, "#")
+		if i := strings.LastIndex(key, "@"); i > 0 {
+			key = key[:i]
+		}
+		return key
+	}
+	if component.Group != "" {
+		return component.Group + "/" + component.Name
+	}
+	return component.Name
+}
+
+// componentLicenses returns the sorted license IDs, names or expressions of component.
+func componentLicenses(component cyclonedx.Component) []string {
+	if component.Licenses == nil {
+		return nil
+	}
+
+	licenses := make([]string, 0, len(*component.Licenses))
+	for _, choice := range *component.Licenses {
+		switch {
+		case choice.Expression != "":
+			licenses = append(licenses, choice.Expression)
+		case choice.License != nil && choice.License.ID != "":
+			licenses = append(licenses, choice.License.ID)
+		case choice.License != nil && choice.License.Name != "":
+			licenses = append(licenses, choice.License.Name)
+		}
+	}
+	sort.Strings(licenses)
+
+	return licenses
+}
+
+func sortComponentVersions(components []ComponentVersion) {
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Key != components[j].Key {
+			return components[i].Key < components[j].Key
+		}
+		return components[i].Version < components[j].Version
+	})
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
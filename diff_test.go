@@ -0,0 +1,120 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestDiffBOMs(t *testing.T) {
+	a := &cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{BOMRef: "a1", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"},
+			{BOMRef: "b1", PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"},
+			{
+				BOMRef:     "c1",
+				PackageURL: "pkg:maven/org.example/c@1.0.0?type=jar",
+				Version:    "1.0.0",
+				Licenses:   &cyclonedx.Licenses{{License: &cyclonedx.License{ID: "MIT"}}},
+			},
+		},
+		Dependencies: &[]cyclonedx.Dependency{
+			{Ref: "a1", Dependencies: &[]string{"b1"}},
+		},
+	}
+	b := &cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{BOMRef: "a2", PackageURL: "pkg:maven/org.example/a@2.0.0", Version: "2.0.0"},
+			{
+				BOMRef:     "c2",
+				PackageURL: "pkg:maven/org.example/c@1.0.0?type=jar",
+				Version:    "1.0.0",
+				Licenses:   &cyclonedx.Licenses{{License: &cyclonedx.License{ID: "Apache-2.0"}}},
+			},
+			{BOMRef: "d2", Group: "org.example", Name: "d", Version: "1.0.0"},
+		},
+		Dependencies: &[]cyclonedx.Dependency{
+			{Ref: "a2", Dependencies: &[]string{"d2"}},
+		},
+	}
+
+	expected := BOMDiff{
+		Added:   []ComponentVersion{{Key: "org.example/d", Version: "1.0.0"}},
+		Removed: []ComponentVersion{{Key: "pkg:maven/org.example/b", Version: "1.0.0"}},
+		Changed: []ComponentChange{
+			{Key: "pkg:maven/org.example/a", OldVersion: "1.0.0", NewVersion: "2.0.0"},
+			{Key: "pkg:maven/org.example/c", OldVersion: "1.0.0", NewVersion: "1.0.0", OldLicenses: []string{"MIT"}, NewLicenses: []string{"Apache-2.0"}},
+		},
+		AddedDependencies:   []Edge{{From: "pkg:maven/org.example/a", To: "org.example/d"}},
+		RemovedDependencies: []Edge{{From: "pkg:maven/org.example/a", To: "pkg:maven/org.example/b"}},
+	}
+
+	diff := diffBOMs(a, b)
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, diff)
+	}
+
+	var sb strings.Builder
+	if err := diff.Print(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"+ org.example/d 1.0.0",
+		"- pkg:maven/org.example/b 1.0.0",
+		"~ pkg:maven/org.example/a 1.0.0 -> 2.0.0",
+		"~ pkg:maven/org.example/c licenses: MIT -> Apache-2.0",
+		"+ pkg:maven/org.example/a -> org.example/d",
+		"- pkg:maven/org.example/a -> pkg:maven/org.example/b",
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestDiffBOMsIdentical(t *testing.T) {
+	bom := &cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"},
+		},
+	}
+
+	var sb strings.Builder
+	if err := diffBOMs(bom, bom).Print(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "no differences\n" {
+		t.Errorf("unexpected output %q", sb.String())
+	}
+}
+
+func TestDiffBOMsMultipleVersions(t *testing.T) {
+	a := &cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"},
+			{PackageURL: "pkg:maven/org.example/a@2.0.0", Version: "2.0.0"},
+			{PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"},
+		},
+	}
+	b := &cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{PackageURL: "pkg:maven/org.example/a@2.0.0", Version: "2.0.0"},
+			{PackageURL: "pkg:maven/org.example/a@1.1.0", Version: "1.1.0"},
+			{PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"},
+			{PackageURL: "pkg:maven/org.example/b@2.0.0", Version: "2.0.0"},
+		},
+	}
+
+	expected := BOMDiff{
+		Added:               []ComponentVersion{{Key: "pkg:maven/org.example/b", Version: "2.0.0"}},
+		Removed:             []ComponentVersion{},
+		Changed:             []ComponentChange{{Key: "pkg:maven/org.example/a", OldVersion: "1.0.0", NewVersion: "1.1.0"}},
+		AddedDependencies:   []Edge{},
+		RemovedDependencies: []Edge{},
+	}
+	if diff := diffBOMs(a, b); !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, diff)
+	}
+}
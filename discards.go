@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// discardMetrics maps discard reasons to the metric that caused them.
+var discardMetrics = map[string]string{
+	discardTooFewComponents:     "components",
+	discardNotSampled:           "sampleRate",
+	discardGroupQuota:           "maxPerGroup",
+	discardEmptyAfterPurlFilter: "removedComponents",
+	discardLowTransitiveRatio:   "transitiveRatio",
+	discardLowVersionedRatio:    "versionedRatio",
+	discardNoCPE:                "cpeRatio",
+	discardLowCPERatio:          "cpeRatio",
+	discardAllExcluded:          "excludedComponents",
+	discardDuplicateSerial:      "serialNumber",
+	discardDecodeWarnings:       "warnings",
+	discardDuplicateComponents:  "aliasOf",
+	discardLowQualityScore:      "qualityScore",
+	discardSBOMLimit:            "maxSBOMs",
+	discardTooSmall:             "bytes",
+	discardTooLarge:             "bytes",
+	discardOldSpecVersion:       "specVersion",
+}
+
+var discardsHeader = []string{"gav", "reason", "metric", "value"}
+
+// discardsWriter appends discarded SBOMs to a CSV file, one row per SBOM.
+// It is safe for concurrent use.
+type discardsWriter struct {
+	mux  sync.Mutex
+	file *os.File
+	csv  *csv.Writer
+}
+
+// newDiscardsWriter opens the CSV file at path for appending.
+// The header is written only if the file is empty.
+func newDiscardsWriter(path string) (*discardsWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	w := &discardsWriter{file: f, csv: csv.NewWriter(f)}
+	if fi.Size() == 0 {
+		if err = w.csv.Write(discardsHeader); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Write appends a row for the SBOM of gav, which was discarded for reason.
+func (w *discardsWriter) Write(gav GAV, reason string, metrics map[string]any) error {
+	var metric, value string
+	if key, ok := discardMetrics[reason]; ok {
+		if v, ok := metrics[key]; ok {
+			metric, value = key, fmt.Sprint(v)
+		}
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if err := w.csv.Write([]string{gav.String(), reason, metric, value}); err != nil {
+		return err
+	}
+	// Flush every row, so that rows of concurrent runs appending to the same file don't interleave.
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *discardsWriter) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
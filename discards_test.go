@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscardsWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discards.csv")
+	gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+
+	w, err := newDiscardsWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(gav, discardTooFewComponents, map[string]any{"components": 3, "minComponents": 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second run appends to the file without repeating the header.
+	w, err = newDiscardsWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(gav, discardMissingRootComponent, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(gav, discardLowTransitiveRatio, map[string]any{"transitiveRatio": 0.25}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "gav,reason,metric,value\n" +
+		"org.example:lib:1.0.0,too-few-components,components,3\n" +
+		"org.example:lib:1.0.0,missing-root-component,,\n" +
+		"org.example:lib:1.0.0,low-transitive-ratio,transitiveRatio,0.25\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+}
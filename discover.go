@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ClassifierCounts tallies the SBOM classifiers found in version search results.
+// It is safe for concurrent use.
+type ClassifierCounts struct {
+	mux         sync.Mutex
+	versions    int
+	withSBOM    int
+	classifiers map[string]int
+}
+
+func NewClassifierCounts() *ClassifierCounts {
+	return &ClassifierCounts{classifiers: make(map[string]int)}
+}
+
+// Add records the classifiers of a single version.
+func (c *ClassifierCounts) Add(ec []string) {
+	classifiers := sbomClassifiers(ec, nil)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.versions++
+	if len(classifiers) > 0 {
+		c.withSBOM++
+	}
+	for _, classifier := range classifiers {
+		c.classifiers[classifier]++
+	}
+}
+
+func (c *ClassifierCounts) Log() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	log.Printf("summary: versions=%d with_sbom=%d", c.versions, c.withSBOM)
+	logHistogram("classifiers", c.classifiers, 0)
+}
+
+// DiscoverClassifiers searches for the versions of all artifacts the crawler would process,
+// and tallies which SBOM classifiers they were published with. Nothing is downloaded.
+func (c *Crawler) DiscoverClassifiers(ctx context.Context) (*ClassifierCounts, error) {
+	counts := NewClassifierCounts()
+	artifactsChan := make(chan Artifact, 1)
+
+	var (
+		errs    []error
+		errsMux sync.Mutex
+	)
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for artifact := range artifactsChan {
+				err := discoverArtifactClassifiers(ctx, c.repo, artifact, counts)
+				if err != nil {
+					log.Printf("%v", err)
+					errsMux.Lock()
+					errs = append(errs, err)
+					errsMux.Unlock()
+					continue
+				}
+				c.summary.AddArtifact()
+			}
+		}()
+	}
+
+	err := discoverArtifacts(ctx, c.repo, c.opts.GroupPrefixes, c.opts.SearchConcurrency, c.acceptArtifact, artifactsChan)
+	close(artifactsChan)
+	wg.Wait()
+	if err != nil {
+		return counts, err
+	}
+	if len(errs) > 0 {
+		return counts, fmt.Errorf("failed to search for versions of %d artifacts", len(errs))
+	}
+
+	return counts, nil
+}
+
+func discoverArtifactClassifiers(ctx context.Context, repo Repository, artifact Artifact, counts *ClassifierCounts) error {
+	docs, err := searchVersionDocs(ctx, repo, artifact)
+	if err != nil {
+		return fmt.Errorf("failed to search for versions of %s: %w", artifact, err)
+	}
+	for _, doc := range docs {
+		counts.Add(doc.EC)
+	}
+	return nil
+}
+
+// runClassifierDiscovery runs DiscoverClassifiers and logs the distribution of classifiers.
+func runClassifierDiscovery(ctx context.Context, repo Repository, opts Options, summary *Summary) {
+	counts, err := NewCrawler(repo, opts, summary, newDiskBudget(0)).DiscoverClassifiers(ctx)
+	if err != nil {
+		log.Printf("classifier discovery is incomplete: %v", err)
+	}
+	summary.Log()
+	counts.Log()
+}
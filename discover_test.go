@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverClassifiers(t *testing.T) {
+	repo := newFixtureRepository(t, nil, fixtureSearches(t, fixtureArtifact{name: "lib", versions: []fixtureVersion{
+		{version: "3.0.0", ec: []string{".jar", "-cyclonedx.json", "-cyclonedx.xml"}},
+		{version: "2.0.0", ec: []string{".jar", "-cyclonedx.xml"}},
+		{version: "1.0.0", ec: []string{".jar"}},
+	}}))
+
+	opts := Options{
+		Concurrency:  1,
+		SBOMSuffixes: []string{"-cyclonedx.json"},
+	}
+	counts, err := NewCrawler(repo, opts, NewSummary(), newDiskBudget(0)).DiscoverClassifiers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counts.versions != 3 || counts.withSBOM != 2 {
+		t.Errorf("expected 3 versions, 2 with sbom, got %d and %d", counts.versions, counts.withSBOM)
+	}
+	expected := map[string]int{"-cyclonedx.json": 1, "-cyclonedx.xml": 2}
+	if !reflect.DeepEqual(counts.classifiers, expected) {
+		t.Errorf("expected %v, got %v", expected, counts.classifiers)
+	}
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// downloadSBOM downloads the SBOM of gav and applies the filters to it.
+// If the SBOM is discarded by a filter, the returned Result only has GAV, Discard and Timestamp set.
+func downloadSBOM(ctx context.Context, repo Repository, gav GAV, opts Options, summary *Summary) (*Result, error) {
+	log.Printf("downloading sbom for %s", gav)
+	fetchedAt := time.Now()
+	resBytes, header, sbomURL, err := fetchSBOMBytes(ctx, repo, gav, opts.sbomSuffixes(), summary)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := processSBOM(ctx, gav, resBytes, suffixFormat(sbomURL), opts.Filters, summary)
+	if err != nil || result.Discard != nil {
+		return result, err
+	}
+	result.URL = sbomURL
+	result.Headers = newResponseHeaders(header)
+	result.FetchedAt = fetchedAt
+
+	if opts.VerifyPurlResolvable {
+		// A failed check says nothing about the SBOM, so it's kept either way.
+		if result.UnresolvablePurls, err = unresolvablePurls(ctx, repo, result.BOM, opts.VerifyPurlsSample); err != nil {
+			log.Printf("failed to verify purls of sbom for %s: %v", gav, err)
+		} else if len(result.UnresolvablePurls) > 0 {
+			log.Printf("sbom for %s references %d artifacts that don't exist: %s", gav, len(result.UnresolvablePurls), strings.Join(result.UnresolvablePurls, ", "))
+		}
+	}
+
+	return result, nil
+}
+
+// processSBOM decodes the SBOM of gav and applies the filters to it.
+// If the SBOM is discarded by a filter, the returned Result only has GAV, Discard and Timestamp set.
+func processSBOM(ctx context.Context, gav GAV, resBytes []byte, format cyclonedx.BOMFileFormat, filters Filters, summary *Summary) (*Result, error) {
+	// The size is checked first, as it's cheaper than decoding.
+	if discarded := checkSBOMSize(gav, len(resBytes), filters, summary); discarded != nil {
+		return discarded, nil
+	}
+
+	sbom, err := decodeForFilters(ctx, resBytes, format, filters)
+	if err != nil {
+		return nil, err
+	}
+	result, err := filterSBOM(gav, sbom, filters, summary)
+	if err == nil && sbom.bom.Metadata != nil {
+		result.Timestamp = sbom.bom.Metadata.Timestamp
+	}
+	return result, err
+}
+
+// checkSBOMSize returns a discarded Result if an SBOM of size bytes is too small or too large.
+func checkSBOMSize(gav GAV, size int, filters Filters, summary *Summary) *Result {
+	if size < filters.MinBytes {
+		log.Printf("discarding sbom for %s because it's too small (%d/%d bytes)", gav, size, filters.MinBytes)
+		summary.AddDiscarded(discardTooSmall)
+		return discardedResult(gav, discardTooSmall, map[string]any{"bytes": size, "minBytes": filters.MinBytes})
+	} else if filters.MaxBytes > 0 && size > filters.MaxBytes {
+		log.Printf("discarding sbom for %s because it's too large (%d/%d bytes)", gav, size, filters.MaxBytes)
+		summary.AddDiscarded(discardTooLarge)
+		return discardedResult(gav, discardTooLarge, map[string]any{"bytes": size, "maxBytes": filters.MaxBytes})
+	}
+	return nil
+}
+
+// decodedSBOM is an SBOM decoded by decodeForFilters.
+type decodedSBOM struct {
+	bom       cyclonedx.BOM
+	raw       []byte
+	format    cyclonedx.BOMFileFormat
+	warnings  []string
+	nonStrict bool
+
+	// signed is set if the JSON SBOM has a top-level signature.
+	signed bool
+
+	// metadataOnly is set if only the metadata of the SBOM was decoded,
+	// in which case topLevel is the number of its top-level components.
+	metadataOnly bool
+	topLevel     int
+}
+
+// decodeForFilters decodes the SBOM in resBytes as far as the filters require.
+// It fails if ctx is done while waiting for a slot of -max-decodes.
+func decodeForFilters(ctx context.Context, resBytes []byte, format cyclonedx.BOMFileFormat, filters Filters) (*decodedSBOM, error) {
+	sbom := decodedSBOM{
+		raw:          resBytes,
+		format:       format,
+		metadataOnly: filters.DecodeOnlyMetadata && format == cyclonedx.BOMFileFormatJSON,
+	}
+	if err := filters.decodeSlots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	var err error
+	if sbom.metadataOnly {
+		sbom.topLevel, sbom.signed, err = decodeSBOMMetadata(resBytes, &sbom.bom)
+	} else {
+		sbom.warnings, sbom.nonStrict, sbom.signed, err = decodeSBOM(resBytes, format, filters.Lenient, filters.StrictDecode, &sbom.bom)
+	}
+	filters.decodeSlots.release()
+	if err != nil {
+		return nil, err
+	}
+	return &sbom, nil
+}
+
+// filterSBOM applies the filters to the decoded SBOM of gav.
+// If the SBOM is discarded by a filter, the returned Result only has GAV and Discard set.
+func filterSBOM(gav GAV, decoded *decodedSBOM, filters Filters, summary *Summary) (*Result, error) {
+	var (
+		sbom         = decoded.bom
+		resBytes     = decoded.raw
+		format       = decoded.format
+		warnings     = decoded.warnings
+		metadataOnly = decoded.metadataOnly
+		counts       ComponentCounts
+		err          error
+	)
+	summary.AddDownloaded()
+	debugf("decoded sbom for %s: spec version %s, %d bytes", gav, sbom.SpecVersion, len(resBytes))
+
+	if len(warnings) > 0 {
+		log.Printf("sbom for %s decoded with %d warnings: %s", gav, len(warnings), strings.Join(warnings, "; "))
+		if filters.StrictDecode {
+			log.Printf("discarding sbom for %s because it decoded with warnings", gav)
+			summary.AddDiscarded(discardDecodeWarnings)
+			return discardedResult(gav, discardDecodeWarnings, map[string]any{"warnings": warnings}), nil
+		}
+	}
+
+	var specInconsistencies []string
+	if filters.CheckSpecConsistency {
+		if format != cyclonedx.BOMFileFormatJSON {
+			debugf("not checking spec consistency of sbom for %s because it's not json", gav)
+		} else if specInconsistencies, err = checkSpecConsistency(resBytes); err != nil {
+			log.Printf("failed to check spec consistency of sbom for %s: %v", gav, err)
+		} else if len(specInconsistencies) > 0 {
+			log.Printf("sbom for %s declares spec version %s, but uses fields of newer versions: %s", gav, sbom.SpecVersion, strings.Join(specInconsistencies, ", "))
+			summary.AddSpecInconsistencies(specInconsistencies)
+		}
+	}
+
+	hasRootComponent := sbom.Metadata != nil && sbom.Metadata.Component != nil
+	if !hasRootComponent {
+		log.Printf("sbom for %s has no root component", gav)
+		summary.AddMissingRootComponent()
+	}
+
+	modified := false
+	var originalCounts *ComponentCounts
+	if len(filters.ComponentTypes) > 0 {
+		original := countAllComponents(&sbom)
+		originalCounts = &original
+		removed := filterComponentTypes(&sbom, filters.ComponentTypes)
+		if removed > 0 {
+			log.Printf("removed %d components with types other than %s from sbom for %s", removed, strings.Join(filters.ComponentTypes, ", "), gav)
+			modified = true
+		}
+	}
+
+	if len(filters.PurlTypes) > 0 {
+		removed := filterPurlTypes(&sbom, filters.PurlTypes)
+		if removed > 0 {
+			log.Printf("removed %d components with purl types other than %s from sbom for %s", removed, strings.Join(filters.PurlTypes, ", "), gav)
+			modified = true
+		}
+		if filters.DiscardEmpty && countComponents(sbom.Components, false) == 0 {
+			log.Printf("discarding sbom for %s because no components are left after filtering purl types", gav)
+			summary.AddDiscarded(discardEmptyAfterPurlFilter)
+			return discardedResult(gav, discardEmptyAfterPurlFilter, map[string]any{"removedComponents": removed}), nil
+		}
+	}
+
+	if filters.StripPurlQualifiers {
+		stripped := stripPurlQualifiers(&sbom, filters.KeepRawPurls)
+		if stripped > 0 {
+			debugf("stripped qualifiers from the purls of %d components of sbom for %s", stripped, gav)
+			modified = true
+		}
+	}
+
+	// Duplicates are collapsed after stripping qualifiers, which may produce further duplicates.
+	var collapsed int
+	if filters.DedupComponents {
+		collapsed = collapseDuplicateComponents(&sbom)
+		if collapsed > 0 {
+			log.Printf("collapsed %d duplicate components of sbom for %s", collapsed, gav)
+			modified = true
+		}
+	}
+
+	if len(filters.Scopes) > 0 || filters.DiscardAllExcluded {
+		counts := countScopes(&sbom)
+		log.Printf("sbom for %s has components with scopes %s", gav, formatScopeCounts(counts))
+		if excluded := counts[cyclonedx.ScopeExcluded]; filters.DiscardAllExcluded && excluded > 0 && len(counts) == 1 {
+			log.Printf("discarding sbom for %s because all of its components are excluded", gav)
+			summary.AddDiscarded(discardAllExcluded)
+			return discardedResult(gav, discardAllExcluded, map[string]any{"excludedComponents": excluded}), nil
+		}
+		if len(filters.Scopes) > 0 {
+			removed := filterScopes(&sbom, filters.Scopes)
+			if removed > 0 {
+				log.Printf("removed %d components with scopes other than %s from sbom for %s", removed, strings.Join(filters.Scopes, ", "), gav)
+				modified = true
+			}
+		}
+	}
+
+	// Only the top-level components were counted if the components weren't decoded.
+	countComponents := countAllComponents
+	if metadataOnly {
+		countComponents = func(*cyclonedx.BOM) ComponentCounts { return ComponentCounts{TopLevel: decoded.topLevel} }
+	}
+	counts = countComponents(&sbom)
+	countSource := filters.componentCountSource()
+	debugf("sbom for %s has %d components counted %s (minimum: %d)", gav, counts.Get(countSource), countSource, filters.MinComponents)
+
+	metrics := make(map[string]any)
+	if keep, reason := applyFilters(builtinFilters(filters, countComponents, metrics), &sbom); !keep {
+		if len(metrics) == 0 {
+			log.Printf("discarding sbom for %s because of filter %s", gav, reason)
+			metrics = nil
+		} else {
+			log.Printf("discarding sbom for %s because of filter %s %v", gav, reason, metrics)
+		}
+		summary.AddDiscarded(reason)
+		return discardedResult(gav, reason, metrics), nil
+	}
+
+	if !hasRootComponent && filters.RequireRootComponent {
+		log.Printf("discarding sbom for %s because it has no root component", gav)
+		summary.AddDiscarded(discardMissingRootComponent)
+		return discardedResult(gav, discardMissingRootComponent, nil), nil
+	}
+
+	if matchers := propertyMatchers(filters); len(matchers) > 0 {
+		matches := countPropertyMatches(&sbom, matchers)
+		if matches == 0 {
+			log.Printf("discarding sbom for %s because no component has a matching property", gav)
+			summary.AddDiscarded(discardNoMatchingProperty)
+			return discardedResult(gav, discardNoMatchingProperty, nil), nil
+		}
+		log.Printf("sbom for %s has %d components with matching properties", gav, matches)
+	}
+
+	if len(filters.ContainsGroups) > 0 {
+		matched := componentsInGroups(&sbom, filters.ContainsGroups)
+		if len(matched) == 0 {
+			log.Printf("discarding sbom for %s because no component is of a group of -contains-group", gav)
+			summary.AddDiscarded(discardNoMatchingGroup)
+			return discardedResult(gav, discardNoMatchingGroup, nil), nil
+		}
+		log.Printf("sbom for %s has %d components of a group of -contains-group: %s", gav, len(matched), strings.Join(matched, ", "))
+	}
+
+	if filters.MinTransitiveRatio > 0 {
+		ratio := transitiveRatio(&sbom)
+		debugf("sbom for %s has a transitive ratio of %.2f (minimum: %.2f)", gav, ratio, filters.MinTransitiveRatio)
+		if ratio < filters.MinTransitiveRatio {
+			log.Printf("discarding sbom for %s because its transitive ratio is too low (%.2f/%.2f)", gav, ratio, filters.MinTransitiveRatio)
+			summary.AddDiscarded(discardLowTransitiveRatio)
+			return discardedResult(gav, discardLowTransitiveRatio, map[string]any{"transitiveRatio": ratio, "minTransitiveRatio": filters.MinTransitiveRatio}), nil
+		}
+	}
+
+	var versioned float64
+	if !metadataOnly {
+		versioned = versionedRatio(&sbom)
+		debugf("sbom for %s has a versioned ratio of %.2f (minimum: %.2f)", gav, versioned, filters.MinVersionedRatio)
+	}
+	if versioned < filters.MinVersionedRatio {
+		log.Printf("discarding sbom for %s because too few of its components have a concrete version (%.2f/%.2f)", gav, versioned, filters.MinVersionedRatio)
+		summary.AddDiscarded(discardLowVersionedRatio)
+		return discardedResult(gav, discardLowVersionedRatio, map[string]any{"versionedRatio": versioned, "minVersionedRatio": filters.MinVersionedRatio}), nil
+	}
+
+	var cpes float64
+	if !metadataOnly {
+		cpes = cpeRatio(&sbom)
+		debugf("sbom for %s has a cpe ratio of %.2f (minimum: %.2f)", gav, cpes, filters.MinCPERatio)
+	}
+	if filters.RequireCPE && cpes == 0 {
+		log.Printf("discarding sbom for %s because none of its components has a cpe", gav)
+		summary.AddDiscarded(discardNoCPE)
+		return discardedResult(gav, discardNoCPE, map[string]any{"cpeRatio": cpes}), nil
+	}
+	if cpes < filters.MinCPERatio {
+		log.Printf("discarding sbom for %s because too few of its components have a cpe (%.2f/%.2f)", gav, cpes, filters.MinCPERatio)
+		summary.AddDiscarded(discardLowCPERatio)
+		return discardedResult(gav, discardLowCPERatio, map[string]any{"cpeRatio": cpes, "minCpeRatio": filters.MinCPERatio}), nil
+	}
+
+	// The ratios aren't known if the components weren't decoded.
+	var knownVersioned, knownCPEs *float64
+	if !metadataOnly {
+		knownVersioned, knownCPEs = &versioned, &cpes
+	}
+
+	score := qualityScore(&sbom)
+	debugf("sbom for %s has a quality score of %.1f (minimum: %.1f)", gav, score, filters.MinQualityScore)
+	if score < filters.MinQualityScore {
+		log.Printf("discarding sbom for %s because its quality score is too low (%.1f/%.1f)", gav, score, filters.MinQualityScore)
+		summary.AddDiscarded(discardLowQualityScore)
+		return discardedResult(gav, discardLowQualityScore, map[string]any{"qualityScore": score, "minQualityScore": filters.MinQualityScore}), nil
+	}
+
+	// Sampling must happen after all other filters,
+	// so that it's performed over the eligible population.
+	if !sampled(gav, filters.SampleRate, filters.SampleSeed) {
+		log.Printf("discarding sbom for %s because it was not sampled", gav)
+		summary.AddDiscarded(discardNotSampled)
+		return discardedResult(gav, discardNotSampled, map[string]any{"sampleRate": filters.SampleRate, "sampleSeed": filters.SampleSeed}), nil
+	}
+	debugf("sbom for %s passed all filters", gav)
+
+	return &Result{
+		GAV:       gav,
+		BOM:       &sbom,
+		Raw:       resBytes,
+		SHA256:    sha256Hex(resBytes),
+		Format:    format,
+		Modified:  modified,
+		NonStrict: decoded.nonStrict,
+		Signed:    decoded.signed,
+
+		QualityScore:            score,
+		VersionedRatio:          knownVersioned,
+		CPERatio:                knownCPEs,
+		ComponentCounts:         counts,
+		OriginalComponentCounts: originalCounts,
+		CollapsedDuplicates:     collapsed,
+		SpecInconsistencies:     specInconsistencies,
+	}, nil
+}
+
+func discardedResult(gav GAV, reason string, metrics map[string]any) *Result {
+	return &Result{
+		GAV: gav,
+		Discard: &Discard{
+			Reason:  reason,
+			Metrics: metrics,
+		},
+	}
+}
+
+// fetchSBOMBytes fetches the SBOM of gav and reads its content.
+// It returns the URL under which the SBOM was found along with it.
+//
+// Each attempt must complete within downloadTimeout.
+// When Maven Central serves a throttle page instead of the SBOM,
+// it backs off and tries again, up to maxRateLimitAttempts times.
+// When the connection breaks while reading the SBOM, the download
+// is re-issued, up to maxReadAttempts times.
+func fetchSBOMBytes(ctx context.Context, repo Repository, gav GAV, suffixes []string, summary *Summary) ([]byte, http.Header, string, error) {
+	attempt, readAttempt := 1, 1
+	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, downloadTimeout)
+		res, suffix, sbomURL, err := fetchSBOM(attemptCtx, repo, gav, suffixes)
+		if err != nil {
+			cancel()
+			return nil, nil, "", err
+		}
+
+		resBytes, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		cancel()
+		if err != nil {
+			if !isRetryableReadError(err) || readAttempt == maxReadAttempts {
+				return nil, nil, "", fmt.Errorf("failed to read sbom: %w", err)
+			}
+			readAttempt++
+
+			log.Printf("failed to read sbom for %s, retrying in %s: %v", gav, readRetryDelay, err)
+			err = sleepContext(ctx, readRetryDelay)
+			if err != nil {
+				return nil, nil, "", err
+			}
+			continue
+		}
+
+		if !isThrottlePage(res.Header, resBytes, suffixFormat(suffix)) {
+			if suffixFormat(suffix) != suffixFormat(suffixes[0]) {
+				log.Printf("found sbom for %s with suffix %s on %s after falling back to %s", gav, suffix, hostOf(sbomURL), formatName(suffixFormat(suffix)))
+			} else {
+				log.Printf("found sbom for %s with suffix %s on %s", gav, suffix, hostOf(sbomURL))
+			}
+			return resBytes, res.Header, sbomURL, nil
+		}
+
+		summary.AddRateLimited()
+		if attempt == maxRateLimitAttempts {
+			return nil, nil, "", fmt.Errorf("received throttle page %d times: %w", attempt, errRateLimited)
+		}
+
+		delay := rateLimitBackoff(attempt)
+		debugf("backing off after attempt %d of %d for %s: sleeping %s (base delay %s, max delay %s, jitter %s)", attempt, maxRateLimitAttempts, gav, delay, retryBaseDelay, retryMaxDelay, retryJitter)
+		log.Printf("rate limited: received throttle page instead of sbom for %s, retrying in %s", gav, delay)
+		err = sleepContext(ctx, delay)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		attempt++
+	}
+}
+
+var errSBOMNotFound = errors.New("no sbom found")
+
+// fetchSBOM tries the given file name suffixes in order, and returns the
+// response for the first one that exists along with the suffix and URL.
+func fetchSBOM(ctx context.Context, repo Repository, gav GAV, suffixes []string) (*http.Response, string, string, error) {
+	for _, suffix := range suffixes {
+		res, sbomURL, err := fetchArtifactFile(ctx, repo, http.MethodGet, gav, suffix)
+		if err != nil {
+			return nil, "", "", err
+		}
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			return res, suffix, sbomURL, nil
+		case http.StatusNotFound:
+			res.Body.Close()
+			continue
+		default:
+			res.Body.Close()
+			return nil, "", "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		}
+	}
+
+	return nil, "", "", fmt.Errorf("%w with any of the suffixes %s", errSBOMNotFound, strings.Join(suffixes, ", "))
+}
+
+// fetchArtifactFile requests a file belonging to gav with method from the base URL of repo, and
+// from its fallback base URLs in order as long as it's not found. It returns the response
+// of the last request along with its URL. Responses other than 404 are not retried elsewhere.
+func fetchArtifactFile(ctx context.Context, repo Repository, method string, gav GAV, suffix string) (*http.Response, string, error) {
+	var res *http.Response
+	var fileURL string
+	for _, baseURL := range append([]string{repo.BaseURL}, repo.FallbackBaseURLs...) {
+		if res != nil {
+			res.Body.Close()
+		}
+
+		fileURL = artifactFileURL(baseURL, gav, suffix)
+		req, err := http.NewRequestWithContext(ctx, method, fileURL, nil)
+		if err != nil {
+			return nil, "", err
+		}
+
+		res, err = repo.Client.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+
+		debugf("%s %s: %s", method, fileURL, res.Status)
+		if res.StatusCode != http.StatusNotFound {
+			break
+		}
+	}
+	return res, fileURL, nil
+}
+
+// suffixFormat determines the format of an SBOM based on its file name suffix.
+func suffixFormat(suffix string) cyclonedx.BOMFileFormat {
+	if strings.HasSuffix(suffix, ".xml") {
+		return cyclonedx.BOMFileFormatXML
+	}
+	return cyclonedx.BOMFileFormatJSON
+}
+
+func formatName(format cyclonedx.BOMFileFormat) string {
+	if format == cyclonedx.BOMFileFormatXML {
+		return "xml"
+	}
+	return "json"
+}
+
+func sbomFileName(gav GAV, format cyclonedx.BOMFileFormat) string {
+	return fmt.Sprintf("%s_%s_%s.cdx.%s", gav.GroupID, gav.ArtifactID, gav.Version, formatName(format))
+}
+
+// downloadPOM downloads the POM of gav.
+// A missing POM is logged, but not treated as an error; nil is returned in that case.
+func downloadPOM(ctx context.Context, repo Repository, gav GAV) ([]byte, error) {
+	log.Printf("downloading pom for %s", gav)
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	res, pomURL, err := fetchArtifactFile(ctx, repo, http.MethodGet, gav, ".pom")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		log.Printf("no pom found for %s", gav)
+		return nil, nil
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	debugf("found pom for %s on %s", gav, hostOf(pomURL))
+
+	return io.ReadAll(res.Body)
+}
+
+func edgesFileName(gav GAV) string {
+	return fmt.Sprintf("%s_%s_%s.edges.json", gav.GroupID, gav.ArtifactID, gav.Version)
+}
+
+func pomFileName(gav GAV) string {
+	return fmt.Sprintf("%s_%s_%s.pom", gav.GroupID, gav.ArtifactID, gav.Version)
+}
+
+// artifactFileURL returns the URL of a file belonging to gav below baseURL.
+// suffix is appended to "<artifactId>-<version>", e.g. ".pom" or "-cyclonedx.json".
+func artifactFileURL(baseURL string, gav GAV, suffix string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s-%s%s", baseURL, strings.ReplaceAll(gav.GroupID, ".", "/"), gav.ArtifactID, gav.Version, gav.ArtifactID, gav.Version, suffix)
+}
+
+// countComponents counts the given components. If nested is true,
+// the nested components of each component are counted as well.
+func countComponents(components *[]cyclonedx.Component, nested bool) int {
+	if components == nil {
+		return 0
+	}
+
+	count := len(*components)
+	if nested {
+		for i := range *components {
+			count += countComponents((*components)[i].Components, true)
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+const (
+	fixtureSBOM = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "version": 1,
+  "metadata": {"component": {"type": "library", "name": "lib", "version": "1.0.0"}},
+  "components": [
+    {"type": "library", "name": "a", "version": "1.0.0"},
+    {"type": "library", "name": "b", "version": "1.0.0"}
+  ]
+}`
+	fixtureSBOMXML = `<?xml version="1.0" encoding="UTF-8"?>
+<bom xmlns="http://cyclonedx.org/schema/bom/1.4" version="1">
+  <metadata><component type="library"><name>lib</name><version>1.0.0</version></component></metadata>
+  <components>
+    <component type="library"><name>a</name><version>1.0.0</version></component>
+    <component type="library"><name>b</name><version>1.0.0</version></component>
+  </components>
+</bom>`
+)
+
+func TestDownloadSBOM(t *testing.T) {
+	gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+	const sbomPath = "org/example/lib/1.0.0/lib-1.0.0-cyclonedx.json"
+	const xmlPath = "org/example/lib/1.0.0/lib-1.0.0-cyclonedx.xml"
+
+	testCases := []struct {
+		name          string
+		files         map[string]fixture
+		minComponents int
+		minTransitive float64
+		minBytes      int
+		maxBytes      int
+		groups        []string
+		retryAsXML    bool
+		errMsg        string
+		discarded     string
+		format        cyclonedx.BOMFileFormat
+	}{
+		{
+			name:   "NotFound",
+			files:  map[string]fixture{},
+			errMsg: "no sbom found",
+		},
+		{
+			name:   "ServerError",
+			files:  map[string]fixture{sbomPath: {status: 500}},
+			errMsg: "unexpected status code: 500",
+		},
+		{
+			name:          "TooFewComponents",
+			files:         map[string]fixture{sbomPath: {body: fixtureSBOM}},
+			minComponents: 3,
+			discarded:     discardTooFewComponents,
+		},
+		{
+			name:          "NoDependencyGraph",
+			files:         map[string]fixture{sbomPath: {body: fixtureSBOM}},
+			minTransitive: 0.5,
+			discarded:     discardLowTransitiveRatio,
+		},
+		{
+			name:      "TooSmall",
+			files:     map[string]fixture{sbomPath: {body: fixtureSBOM}},
+			minBytes:  1 << 20,
+			discarded: discardTooSmall,
+		},
+		{
+			name:      "TooLarge",
+			files:     map[string]fixture{sbomPath: {body: fixtureSBOM}},
+			maxBytes:  100,
+			discarded: discardTooLarge,
+		},
+		{
+			name:      "NoMatchingGroup",
+			files:     map[string]fixture{sbomPath: {body: fixtureSBOM}},
+			groups:    []string{"com.fasterxml.jackson"},
+			discarded: discardNoMatchingGroup,
+		},
+		{
+			name:   "DecodeFailure",
+			files:  map[string]fixture{sbomPath: {body: `{"components": 42}`}},
+			errMsg: "cannot unmarshal",
+		},
+		{
+			name:       "XML",
+			files:      map[string]fixture{xmlPath: {body: fixtureSBOMXML}},
+			retryAsXML: true,
+			format:     cyclonedx.BOMFileFormatXML,
+		},
+		{
+			name:   "XMLWithoutRetry",
+			files:  map[string]fixture{xmlPath: {body: fixtureSBOMXML}},
+			errMsg: "no sbom found",
+		},
+		{
+			name:   "Success",
+			files:  map[string]fixture{sbomPath: {body: fixtureSBOM}},
+			format: cyclonedx.BOMFileFormatJSON,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFixtureRepository(t, tc.files, nil)
+			opts := Options{
+				SBOMSuffixes: []string{"-cyclonedx.json"},
+				RetryAsXML:   tc.retryAsXML,
+				Filters: Filters{
+					MinComponents:      tc.minComponents,
+					MinBytes:           tc.minBytes,
+					MaxBytes:           tc.maxBytes,
+					MinTransitiveRatio: tc.minTransitive,
+					ContainsGroups:     tc.groups,
+					SampleRate:         1,
+				},
+			}
+			summary := NewSummary()
+
+			result, err := downloadSBOM(context.Background(), repo, gav, opts, summary)
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.discarded != "" {
+				if result == nil || result.Discard == nil || result.Discard.Reason != tc.discarded {
+					t.Fatalf("expected sbom to be discarded because of %s, got %+v", tc.discarded, result)
+				}
+				if summary.discarded[tc.discarded] != 1 {
+					t.Fatalf("expected sbom to be discarded because of %s, got %v", tc.discarded, summary.discarded)
+				}
+				return
+			}
+
+			if result == nil {
+				t.Fatal("expected a result")
+			}
+			if result.Format != tc.format {
+				t.Errorf("expected format %v, got %v", tc.format, result.Format)
+			}
+			if count := countComponents(result.BOM.Components, false); count != 2 {
+				t.Errorf("expected 2 components, got %d", count)
+			}
+			if !strings.HasPrefix(result.URL, repo.BaseURL) {
+				t.Errorf("expected url below %s, got %s", repo.BaseURL, result.URL)
+			}
+			if body := tc.files[strings.TrimPrefix(result.URL, repo.BaseURL+"/")].body; result.SHA256 != sha256Hex([]byte(body)) {
+				t.Errorf("expected the hash of the downloaded sbom, got %s", result.SHA256)
+			}
+
+			dir := t.TempDir()
+			fileName := sbomFileName(result.GAV, result.Format)
+			if _, err := (dirOutput{dir: dir}).Write(fileName, result.Raw); err != nil {
+				t.Fatalf("failed to write sbom: %v", err)
+			}
+			written, err := os.ReadFile(filepath.Join(dir, fileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(written) != tc.files[strings.TrimPrefix(result.URL, repo.BaseURL+"/")].body {
+				t.Errorf("written sbom does not match the downloaded one")
+			}
+		})
+	}
+}
+
+func TestDownloadSBOMFallbackBaseURLs(t *testing.T) {
+	gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+	const sbomPath = "org/example/lib/1.0.0/lib-1.0.0-cyclonedx.json"
+
+	testCases := []struct {
+		name    string
+		files   map[string]fixture
+		wantURL string // relative to the fixture repository
+		errMsg  string
+	}{
+		{
+			name:    "Primary",
+			files:   map[string]fixture{sbomPath: {body: fixtureSBOM}, "fallback/" + sbomPath: {body: fixtureSBOM}},
+			wantURL: sbomPath,
+		},
+		{
+			name:    "Fallback",
+			files:   map[string]fixture{"fallback/" + sbomPath: {body: fixtureSBOM}},
+			wantURL: "fallback/" + sbomPath,
+		},
+		{
+			name:   "PrimaryServerError",
+			files:  map[string]fixture{sbomPath: {status: 500}, "fallback/" + sbomPath: {body: fixtureSBOM}},
+			errMsg: "unexpected status code: 500",
+		},
+		{
+			name:   "NotFound",
+			files:  map[string]fixture{},
+			errMsg: "no sbom found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newFixtureRepository(t, tc.files, nil)
+			baseURL := repo.BaseURL
+			repo.FallbackBaseURLs = []string{baseURL + "/fallback"}
+			opts := Options{SBOMSuffixes: []string{"-cyclonedx.json"}, Filters: Filters{SampleRate: 1}}
+
+			result, err := downloadSBOM(context.Background(), repo, gav, opts, NewSummary())
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := baseURL + "/" + tc.wantURL; result.URL != want {
+				t.Errorf("expected url %s, got %s", want, result.URL)
+			}
+		})
+	}
+}
+
+func TestCountComponents(t *testing.T) {
+	components := []cyclonedx.Component{
+		{Name: "a"},
+		{
+			Name: "b",
+			Components: &[]cyclonedx.Component{
+				{Name: "b1"},
+				{
+					Name: "b2",
+					Components: &[]cyclonedx.Component{
+						{Name: "b2a"},
+					},
+				},
+			},
+		},
+	}
+
+	if count := countComponents(nil, true); count != 0 {
+		t.Fatalf("expected 0 components, got %d", count)
+	}
+	if count := countComponents(&components, false); count != 2 {
+		t.Fatalf("expected 2 top-level components, got %d", count)
+	}
+	if count := countComponents(&components, true); count != 5 {
+		t.Fatalf("expected 5 components including nested ones, got %d", count)
+	}
+}
+
+func TestProcessSBOMSampling(t *testing.T) {
+	testCases := []struct {
+		name       string
+		rate       float64
+		minSampled int
+		maxSampled int
+	}{
+		{"All", 1, 100, 100},
+		{"Half", 0.5, 30, 70},
+		{"None", 0, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			summary := NewSummary()
+			filters := Filters{SampleRate: tc.rate, SampleSeed: 42}
+			kept := 0
+			for i := 0; i < 100; i++ {
+				gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: fmt.Sprintf("1.0.%d", i)}
+				result, err := processSBOM(context.Background(), gav, []byte(fixtureSBOM), cyclonedx.BOMFileFormatJSON, filters, summary)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Discard == nil {
+					summary.AddCollected(*result, false)
+					kept++
+				}
+			}
+
+			report := summary.Report()
+			if report.Sampled != kept {
+				t.Errorf("expected %d sampled sboms, got %d", kept, report.Sampled)
+			}
+			if report.Sampled < tc.minSampled || report.Sampled > tc.maxSampled {
+				t.Errorf("expected between %d and %d sampled sboms, got %d", tc.minSampled, tc.maxSampled, report.Sampled)
+			}
+			if notSampled := report.Discarded[discardNotSampled]; report.Sampled+notSampled != 100 {
+				t.Errorf("expected sampled and not sampled sboms to add up to 100, got %d and %d", report.Sampled, notSampled)
+			}
+		})
+	}
+}
+
+func TestDownloadSBOMRetriesBrokenBody(t *testing.T) {
+	defer func(delay time.Duration) { readRetryDelay = delay }(readRetryDelay)
+	readRetryDelay = 0
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "-cyclonedx.json") {
+			http.NotFound(w, r)
+			return
+		}
+		if requests.Add(1) == 1 {
+			// Announce the full SBOM, but close the connection halfway through.
+			w.Header().Set("Content-Length", strconv.Itoa(len(fixtureSBOM)))
+			_, _ = w.Write([]byte(fixtureSBOM[:len(fixtureSBOM)/2]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		_, _ = w.Write([]byte(fixtureSBOM))
+	}))
+	defer server.Close()
+
+	repo := Repository{Client: server.Client(), BaseURL: server.URL}
+	opts := Options{
+		SBOMSuffixes: []string{"-cyclonedx.json"},
+		Filters:      Filters{SampleRate: 1},
+	}
+	gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+
+	result, err := downloadSBOM(context.Background(), repo, gav, opts, NewSummary())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Discard != nil || string(result.Raw) != fixtureSBOM {
+		t.Fatalf("expected the complete sbom, got %+v", result)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestIsRetryableReadError(t *testing.T) {
+	if !isRetryableReadError(fmt.Errorf("read: %w", io.ErrUnexpectedEOF)) {
+		t.Error("expected unexpected EOF to be retryable")
+	}
+	if !isRetryableReadError(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}) {
+		t.Error("expected connection reset to be retryable")
+	}
+	if isRetryableReadError(context.DeadlineExceeded) {
+		t.Error("expected deadline exceeded not to be retryable")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// collapseDuplicateComponents collapses components of bom, including nested ones, that share
+// their package URL and version into the first of them (-dedup-components). Properties of
+// duplicates are merged into it, and dependencies on duplicates are rewired to it.
+// Nested components of a duplicate take its place. Components without package URL
+// are left as they are. It returns the number of collapsed duplicates.
+func collapseDuplicateComponents(bom *cyclonedx.BOM) int {
+	refs := make(map[string]string) // bom-refs of duplicates to those of the components they were collapsed into
+	collapsed := collapseNestedDuplicates(bom.Components, make(map[string]*cyclonedx.Component), refs)
+	if len(refs) > 0 && bom.Dependencies != nil {
+		bom.Dependencies = rewireDependencies(*bom.Dependencies, refs)
+	}
+	return collapsed
+}
+
+func collapseNestedDuplicates(components *[]cyclonedx.Component, first map[string]*cyclonedx.Component, refs map[string]string) int {
+	if components == nil {
+		return 0
+	}
+
+	collapsed := 0
+	// kept never grows beyond its capacity, so pointers to its elements stay valid.
+	kept := make([]cyclonedx.Component, 0, countComponents(components, true))
+	queue := *components
+	for len(queue) > 0 {
+		component := queue[0]
+		queue = queue[1:]
+
+		key := duplicateKey(component)
+		if original, ok := first[key]; ok && key != "" {
+			mergeDuplicate(original, component, refs)
+			collapsed++
+			if component.Components != nil {
+				queue = append(append([]cyclonedx.Component{}, *component.Components...), queue...)
+			}
+			continue
+		}
+		kept = append(kept, component)
+		if key != "" {
+			first[key] = &kept[len(kept)-1]
+		}
+	}
+	*components = kept
+
+	for i := range kept {
+		collapsed += collapseNestedDuplicates(kept[i].Components, first, refs)
+	}
+	return collapsed
+}
+
+// duplicateKey identifies the components that are considered duplicates of each other.
+func duplicateKey(component cyclonedx.Component) string {
+	if component.PackageURL == "" {
+		return ""
+	}
+	return component.PackageURL + "\x00" + component.Version
+}
+
+// mergeDuplicate merges the properties of duplicate into original.
+func mergeDuplicate(original *cyclonedx.Component, duplicate cyclonedx.Component, refs map[string]string) {
+	if duplicate.Properties != nil {
+		var properties []cyclonedx.Property
+		if original.Properties != nil {
+			properties = *original.Properties
+		}
+		for _, property := range *duplicate.Properties {
+			if !hasProperty(properties, property) {
+				properties = append(properties, property)
+			}
+		}
+		original.Properties = &properties
+	}
+
+	switch {
+	case duplicate.BOMRef == "" || duplicate.BOMRef == original.BOMRef:
+	case original.BOMRef == "":
+		original.BOMRef = duplicate.BOMRef
+	default:
+		refs[duplicate.BOMRef] = original.BOMRef
+	}
+}
+
+func hasProperty(properties []cyclonedx.Property, property cyclonedx.Property) bool {
+	for _, p := range properties {
+		if p == property {
+			return true
+		}
+	}
+	return false
+}
+
+// rewireDependencies replaces the bom-refs of collapsed duplicates in dependencies by those in refs.
+// Dependencies of a duplicate are merged into those of the component it was collapsed into.
+func rewireDependencies(dependencies []cyclonedx.Dependency, refs map[string]string) *[]cyclonedx.Dependency {
+	resolve := func(ref string) string {
+		if target, ok := refs[ref]; ok {
+			return target
+		}
+		return ref
+	}
+
+	rewired := make([]cyclonedx.Dependency, 0, len(dependencies))
+	indexes := make(map[string]int, len(dependencies))
+	for _, dep := range dependencies {
+		ref := resolve(dep.Ref)
+		i, ok := indexes[ref]
+		if !ok {
+			i = len(rewired)
+			indexes[ref] = i
+			rewired = append(rewired, cyclonedx.Dependency{Ref: ref})
+		}
+		if dep.Dependencies == nil {
+			continue
+		}
+
+		var dependsOn []string
+		if rewired[i].Dependencies != nil {
+			dependsOn = *rewired[i].Dependencies
+		}
+		for _, target := range *dep.Dependencies {
+			if target = resolve(target); target != ref && !contains(dependsOn, target) {
+				dependsOn = append(dependsOn, target)
+			}
+		}
+		rewired[i].Dependencies = &dependsOn
+	}
+	return &rewired
+}
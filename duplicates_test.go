@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestCollapseDuplicateComponents(t *testing.T) {
+	bom := cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{
+				BOMRef:     "a",
+				PackageURL: "pkg:maven/org.example/a@1.0.0",
+				Version:    "1.0.0",
+				Properties: &[]cyclonedx.Property{{Name: "origin", Value: "x"}},
+			},
+			{
+				BOMRef:     "a-dup",
+				PackageURL: "pkg:maven/org.example/a@1.0.0",
+				Version:    "1.0.0",
+				Properties: &[]cyclonedx.Property{{Name: "origin", Value: "x"}, {Name: "origin", Value: "y"}},
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "c", PackageURL: "pkg:maven/org.example/c@1.0.0", Version: "1.0.0"},
+				},
+			},
+			{
+				BOMRef:     "b",
+				PackageURL: "pkg:maven/org.example/b@1.0.0",
+				Version:    "1.0.0",
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "a-nested", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"},
+				},
+			},
+			{BOMRef: "a-2", PackageURL: "pkg:maven/org.example/a@2.0.0", Version: "2.0.0"},
+			{BOMRef: "no-purl-1", Name: "x"},
+			{BOMRef: "no-purl-2", Name: "x"},
+		},
+		Dependencies: &[]cyclonedx.Dependency{
+			{Ref: "a", Dependencies: &[]string{"b"}},
+			{Ref: "a-dup", Dependencies: &[]string{"c", "a"}},
+			{Ref: "b", Dependencies: &[]string{"a-nested", "a-dup"}},
+		},
+	}
+
+	if collapsed := collapseDuplicateComponents(&bom); collapsed != 2 {
+		t.Fatalf("expected 2 collapsed duplicates, got %d", collapsed)
+	}
+
+	var refs []string
+	for _, component := range *bom.Components {
+		refs = append(refs, component.BOMRef)
+	}
+	if expected := []string{"a", "c", "b", "a-2", "no-purl-1", "no-purl-2"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected components %v, got %v", expected, refs)
+	}
+	if nested := (*bom.Components)[2].Components; len(*nested) != 0 {
+		t.Errorf("expected nested duplicate to be collapsed, got %v", *nested)
+	}
+
+	expectedProperties := []cyclonedx.Property{{Name: "origin", Value: "x"}, {Name: "origin", Value: "y"}}
+	if properties := (*bom.Components)[0].Properties; !reflect.DeepEqual(*properties, expectedProperties) {
+		t.Errorf("expected properties %v, got %v", expectedProperties, *properties)
+	}
+
+	expectedDependencies := []cyclonedx.Dependency{
+		{Ref: "a", Dependencies: &[]string{"b", "c"}},
+		{Ref: "b", Dependencies: &[]string{"a"}},
+	}
+	if !reflect.DeepEqual(*bom.Dependencies, expectedDependencies) {
+		t.Errorf("expected dependencies %v, got %v", expectedDependencies, *bom.Dependencies)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// parsePointer parses a JSON pointer (RFC 6901) into its reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer %q: must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// extractPointers evaluates the given JSON pointers against data.
+// Values of pointers that don't resolve are nil.
+//
+// The document is scanned token by token, and only values referenced
+// by any of the pointers are decoded; everything else is skipped.
+func extractPointers(data []byte, pointers []string) (map[string]any, error) {
+	targets := make(map[string][]string, len(pointers))
+	for _, pointer := range pointers {
+		tokens, err := parsePointer(pointer)
+		if err != nil {
+			return nil, err
+		}
+		targets[pointer] = tokens
+	}
+
+	values := make(map[string]any, len(pointers))
+	for _, pointer := range pointers {
+		values[pointer] = nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := extractValue(dec, []string{}, targets, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// extractValue consumes the next value from dec, which is located at path.
+func extractValue(dec *json.Decoder, path []string, targets map[string][]string, values map[string]any) error {
+	relevant := false
+	for pointer, tokens := range targets {
+		if equalStrings(tokens, path) {
+			var value any
+			if err := dec.Decode(&value); err != nil {
+				return err
+			}
+			values[pointer] = value
+
+			// Pointers below this one can't be resolved anymore, as the value has been consumed.
+			// Extract them from the decoded value instead.
+			for other, otherTokens := range targets {
+				if other != pointer && hasPrefix(otherTokens, path) {
+					values[other] = resolvePointer(value, otherTokens[len(path):])
+				}
+			}
+			return nil
+		}
+		if hasPrefix(tokens, path) {
+			relevant = true
+		}
+	}
+	if !relevant {
+		return skipValue(dec)
+	}
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			err = extractValue(dec, append(path, key.(string)), targets, values)
+			if err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			err = extractValue(dec, append(path, strconv.Itoa(i)), targets, values)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		// A scalar can't contain the targeted values.
+		return nil
+	}
+
+	// Consume the closing delimiter.
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue consumes the next value from dec without decoding it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// resolvePointer evaluates the reference tokens of a JSON pointer against a decoded value.
+func resolvePointer(value any, tokens []string) any {
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+func hasPrefix(tokens, prefix []string) bool {
+	return len(tokens) >= len(prefix) && equalStrings(tokens[:len(prefix)], prefix)
+}
+
+// extractWriter writes values extracted from SBOMs to an NDJSON file,
+// one line per SBOM. It is safe for concurrent use.
+type extractWriter struct {
+	pointers []string
+
+	mux  sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newExtractWriter(path string, pointers []string) (*extractWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extractWriter{
+		pointers: pointers,
+		file:     f,
+		enc:      json.NewEncoder(f),
+	}, nil
+}
+
+// Write extracts values from the raw SBOM of gav and appends them as a line.
+func (w *extractWriter) Write(gav GAV, data []byte) error {
+	values, err := extractPointers(data, w.pointers)
+	if err != nil {
+		return err
+	}
+
+	row := make(map[string]any, len(values)+3)
+	for pointer, value := range values {
+		row[pointer] = value
+	}
+	row["group"] = gav.GroupID
+	row["artifact"] = gav.ArtifactID
+	row["version"] = gav.Version
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.enc.Encode(row)
+}
+
+func (w *extractWriter) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.file.Close()
+}
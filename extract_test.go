@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtractPointers(t *testing.T) {
+	data := []byte(`{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "metadata": {
+    "tools": [{"name": "a"}, {"name": "b"}],
+    "component": {"name": "lib", "purl": "pkg:maven/org.example/lib@1.0.0"}
+  },
+  "components": [{"name": "x", "a/b": 1}],
+  "version": 1
+}`)
+
+	values, err := extractPointers(data, []string{
+		"/metadata/component/purl",
+		"/metadata/component",
+		"/metadata/tools/1/name",
+		"/components/0/a~1b",
+		"/version",
+		"/missing",
+		"/components/5",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"/metadata/component/purl": "pkg:maven/org.example/lib@1.0.0",
+		"/metadata/component":      map[string]any{"name": "lib", "purl": "pkg:maven/org.example/lib@1.0.0"},
+		"/metadata/tools/1/name":   "b",
+		"/components/0/a~1b":       json.Number("1"),
+		"/version":                 json.Number("1"),
+		"/missing":                 nil,
+		"/components/5":            nil,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestExtractPointersInvalidJSON(t *testing.T) {
+	if _, err := extractPointers([]byte(`{"metadata": {`), []string{"/version"}); err == nil {
+		t.Fatal("expected an error for truncated json")
+	}
+}
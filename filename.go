@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// fileNameData is what -filename-template is executed with.
+type fileNameData struct {
+	Group       string
+	Artifact    string
+	Version     string
+	Hash        string // SHA-256 hash of the written file
+	SpecVersion string
+	Format      string // "json" or "xml"
+}
+
+// parseFileNameTemplate parses a -filename-template, and executes it once
+// with sample data, so that errors are surfaced before the crawl starts.
+func parseFileNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = executeFileNameTemplate(tmpl, fileNameData{
+		Group:       "org.example",
+		Artifact:    "lib",
+		Version:     "1.0.0",
+		Hash:        strings.Repeat("0", 64),
+		SpecVersion: cyclonedx.SpecVersion1_4.String(),
+		Format:      "json",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// templateFileName determines the file name of the SBOM in result,
+// whose encoded form is data, according to tmpl.
+func templateFileName(tmpl *template.Template, result Result, data []byte) (string, error) {
+	digest := sha256.Sum256(data)
+	fileData := fileNameData{
+		Group:    result.GAV.GroupID,
+		Artifact: result.GAV.ArtifactID,
+		Version:  result.GAV.Version,
+		Hash:     hex.EncodeToString(digest[:]),
+		Format:   formatName(result.Format),
+	}
+	if result.BOM != nil {
+		fileData.SpecVersion = result.BOM.SpecVersion.String()
+	}
+
+	return executeFileNameTemplate(tmpl, fileData)
+}
+
+func executeFileNameTemplate(tmpl *template.Template, data fileNameData) (string, error) {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return sanitizeFileName(buf.String())
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names with
+// underscores. Slashes are retained to separate directories, but the name
+// must be relative and must not escape the output directory.
+func sanitizeFileName(name string) (string, error) {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segment = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-+@", r) {
+				return r
+			}
+			return '_'
+		}, segment)
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("file name %q is not a valid relative path", name)
+		}
+		segments[i] = segment
+	}
+
+	return strings.Join(segments, "/"), nil
+}
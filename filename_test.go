@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestTemplateFileName(t *testing.T) {
+	result := Result{
+		GAV:    GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0 beta"},
+		BOM:    &cyclonedx.BOM{SpecVersion: cyclonedx.SpecVersion1_4},
+		Format: cyclonedx.BOMFileFormatJSON,
+	}
+
+	testCases := []struct {
+		template string
+		expected string
+		errMsg   string
+	}{
+		{
+			template: "{{.Group}}/{{.Artifact}}/{{.Version}}.json",
+			expected: "org.example/lib/1.0.0_beta.json",
+		},
+		{
+			template: "{{.SpecVersion}}/{{.Hash}}.cdx.{{.Format}}",
+			expected: "1.4/44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a.cdx.json",
+		},
+		{
+			template: "../{{.Artifact}}.json",
+			errMsg:   "not a valid relative path",
+		},
+		{
+			template: "/{{.Artifact}}.json",
+			errMsg:   "not a valid relative path",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			tmpl, err := parseFileNameTemplate(tc.template)
+			if err == nil {
+				var name string
+				name, err = templateFileName(tmpl, result, []byte("{}"))
+				if err == nil && name != tc.expected {
+					t.Fatalf("expected %s, got %s", tc.expected, name)
+				}
+			}
+			if tc.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+				t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestParseFileNameTemplateInvalid(t *testing.T) {
+	for _, text := range []string{"{{.Group", "{{.Unknown}}.json"} {
+		if _, err := parseFileNameTemplate(text); err == nil {
+			t.Errorf("expected %q to be rejected", text)
+		}
+	}
+}
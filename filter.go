@@ -0,0 +1,81 @@
+package main
+
+import "github.com/CycloneDX/cyclonedx-go"
+
+// Filter decides whether a decoded SBOM is kept. When it's not,
+// reason is recorded as the reason for discarding it. Filters must not modify bom.
+type Filter func(bom *cyclonedx.BOM) (keep bool, reason string)
+
+// discardCustomFilter is the reason recorded when a Filter doesn't provide one.
+const discardCustomFilter = "custom-filter"
+
+// applyFilters runs filters on bom in order. It stops at the first filter that
+// doesn't keep bom, so later filters never see SBOMs discarded by earlier ones,
+// and returns the reason for discarding it.
+func applyFilters(filters []Filter, bom *cyclonedx.BOM) (keep bool, reason string) {
+	for _, filter := range filters {
+		if keep, reason := filter(bom); !keep {
+			if reason == "" {
+				reason = discardCustomFilter
+			}
+			return false, reason
+		}
+	}
+	return true, ""
+}
+
+// builtinFilters returns the built-in filters that are implemented as Filters, in the order
+// they run: -min-spec-version and -min-components, followed by filters.Custom.
+// count counts the components of an SBOM. A built-in filter that discards an SBOM
+// stores the values that caused it in metrics.
+func builtinFilters(filters Filters, count func(*cyclonedx.BOM) ComponentCounts, metrics map[string]any) []Filter {
+	var builtin []Filter
+	if filters.MinSpecVersion != "" {
+		builtin = append(builtin, minSpecVersionFilter(filters.MinSpecVersion, metrics))
+	}
+	builtin = append(builtin, minComponentsFilter(filters.MinComponents, filters.componentCountSource(), count, metrics))
+	return append(builtin, filters.Custom...)
+}
+
+// minSpecVersionFilter discards SBOMs that declare a spec version older than min,
+// or none at all.
+func minSpecVersionFilter(min string, metrics map[string]any) Filter {
+	minMinor, _ := specMinor(min)
+	return func(bom *cyclonedx.BOM) (bool, string) {
+		version := ""
+		if bom.SpecVersion != 0 {
+			version = bom.SpecVersion.String()
+		}
+		if minor, ok := specMinor(version); ok && minor >= minMinor {
+			return true, ""
+		}
+		metrics["specVersion"] = version
+		metrics["minSpecVersion"] = min
+		return false, discardOldSpecVersion
+	}
+}
+
+// minComponentsFilter discards SBOMs with fewer than min components, as counted by count
+// and selected by source.
+func minComponentsFilter(min int, source string, count func(*cyclonedx.BOM) ComponentCounts, metrics map[string]any) Filter {
+	return func(bom *cyclonedx.BOM) (bool, string) {
+		components := count(bom).Get(source)
+		if components >= min {
+			return true, ""
+		}
+		metrics["components"] = components
+		metrics["minComponents"] = min
+		metrics["componentCountSource"] = source
+		return false, discardTooFewComponents
+	}
+}
+
+// validSpecVersion reports whether version is a spec version cyclonedx-go knows (1.0 to 1.4).
+func validSpecVersion(version string) bool {
+	for known := cyclonedx.SpecVersion1_0; known <= cyclonedx.SpecVersion1_4; known++ {
+		if known.String() == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestApplyFilters(t *testing.T) {
+	var calls []string
+	filter := func(name string, keep bool, reason string) Filter {
+		return func(bom *cyclonedx.BOM) (bool, string) {
+			calls = append(calls, name)
+			return keep, reason
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		filters    []Filter
+		wantKeep   bool
+		wantReason string
+		wantCalls  int
+	}{
+		{"NoFilters", nil, true, "", 0},
+		{"AllKeep", []Filter{filter("a", true, ""), filter("b", true, "")}, true, "", 2},
+		{"ShortCircuit", []Filter{filter("a", false, "no-license"), filter("b", false, "other")}, false, "no-license", 1},
+		{"DefaultReason", []Filter{filter("a", true, ""), filter("b", false, "")}, false, discardCustomFilter, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = nil
+			keep, reason := applyFilters(tc.filters, &cyclonedx.BOM{})
+			if keep != tc.wantKeep || reason != tc.wantReason {
+				t.Fatalf("expected (%t, %q), got (%t, %q)", tc.wantKeep, tc.wantReason, keep, reason)
+			}
+			if len(calls) != tc.wantCalls {
+				t.Fatalf("expected %d filters to be called, got %v", tc.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestFilterSBOMBuiltinAndCustomFilters(t *testing.T) {
+	gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+	components := []cyclonedx.Component{{Name: "a"}, {Name: "b"}}
+
+	var customCalls int
+	custom := func(bom *cyclonedx.BOM) (bool, string) {
+		customCalls++
+		return false, "no-license"
+	}
+
+	testCases := []struct {
+		name            string
+		specVersion     cyclonedx.SpecVersion
+		filters         Filters
+		wantReason      string
+		wantMetrics     map[string]any
+		wantCustomCalls int
+	}{
+		{
+			name:        "OldSpecVersion",
+			specVersion: cyclonedx.SpecVersion1_2,
+			filters:     Filters{MinSpecVersion: "1.3", MinComponents: 1, SampleRate: 1, Custom: []Filter{custom}},
+			wantReason:  discardOldSpecVersion,
+			wantMetrics: map[string]any{"specVersion": "1.2", "minSpecVersion": "1.3"},
+		},
+		{
+			name:        "TooFewComponents",
+			specVersion: cyclonedx.SpecVersion1_4,
+			filters:     Filters{MinSpecVersion: "1.3", MinComponents: 3, SampleRate: 1, Custom: []Filter{custom}},
+			wantReason:  discardTooFewComponents,
+			wantMetrics: map[string]any{"components": 2, "minComponents": 3, "componentCountSource": componentCountTopLevel},
+		},
+		{
+			name:            "Custom",
+			specVersion:     cyclonedx.SpecVersion1_4,
+			filters:         Filters{MinSpecVersion: "1.3", MinComponents: 2, SampleRate: 1, Custom: []Filter{custom}},
+			wantReason:      "no-license",
+			wantCustomCalls: 1,
+		},
+		{
+			name:        "Kept",
+			specVersion: cyclonedx.SpecVersion1_4,
+			filters:     Filters{MinComponents: 2, SampleRate: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			customCalls = 0
+			decoded := &decodedSBOM{
+				bom:    cyclonedx.BOM{SpecVersion: tc.specVersion, Components: &components},
+				raw:    []byte("{}"),
+				format: cyclonedx.BOMFileFormatJSON,
+			}
+
+			result, err := filterSBOM(gav, decoded, tc.filters, NewSummary())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantReason == "" {
+				if result.Discard != nil {
+					t.Fatalf("expected the sbom to be kept, got %+v", result.Discard)
+				}
+				return
+			}
+			if result.Discard == nil || result.Discard.Reason != tc.wantReason {
+				t.Fatalf("expected the sbom to be discarded with reason %s, got %+v", tc.wantReason, result.Discard)
+			}
+			if tc.wantMetrics != nil && !reflect.DeepEqual(result.Discard.Metrics, tc.wantMetrics) {
+				t.Errorf("expected metrics %v, got %v", tc.wantMetrics, result.Discard.Metrics)
+			}
+			if customCalls != tc.wantCustomCalls {
+				t.Errorf("expected the custom filter to be called %d times, got %d", tc.wantCustomCalls, customCalls)
+			}
+		})
+	}
+}
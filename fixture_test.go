@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fixture is a canned HTTP response.
+type fixture struct {
+	status int
+	body   string
+}
+
+// newFixtureRepository starts a server that serves files below /maven2 and
+// search responses at /solrsearch/select, and returns a Repository for it.
+//
+// files maps paths relative to /maven2 to their content. Paths without
+// file are answered with 404.
+// searches maps the raw query string of search requests to their response.
+func newFixtureRepository(t *testing.T, files map[string]fixture, searches map[string]string) Repository {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/maven2/", func(w http.ResponseWriter, r *http.Request) {
+		f, ok := files[r.URL.Path[len("/maven2/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+		}
+		_, _ = w.Write([]byte(f.body))
+	})
+	mux.HandleFunc("/solrsearch/select", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := searches[r.URL.RawQuery]
+		if !ok {
+			t.Errorf("unexpected search request: %s", r.URL.RawQuery)
+			http.Error(w, "unexpected search request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return Repository{
+		Client:    server.Client(),
+		SearchURL: server.URL + "/solrsearch/select",
+		BaseURL:   server.URL + "/maven2",
+	}
+}
+
+// fixtureArtifact is an artifact of org.example, as found by the search.
+type fixtureArtifact struct {
+	name     string
+	versions []fixtureVersion
+}
+
+// fixtureVersion is a version of a fixtureArtifact, along with
+// the extensions and classifiers published for it.
+type fixtureVersion struct {
+	version string
+	ec      []string
+}
+
+// sbomArtifact returns a fixtureArtifact with a -cyclonedx.json SBOM for each of versions.
+func sbomArtifact(name string, versions ...string) fixtureArtifact {
+	artifact := fixtureArtifact{name: name}
+	for _, version := range versions {
+		artifact.versions = append(artifact.versions, fixtureVersion{version: version, ec: []string{"-cyclonedx.json"}})
+	}
+	return artifact
+}
+
+// fixtureSearches returns the search responses of newFixtureRepository for a crawl
+// that discovers artifacts on a single page, and their versions on a single page each.
+// The last version of an artifact is reported as its latest version.
+func fixtureSearches(t *testing.T, artifacts ...fixtureArtifact) map[string]string {
+	t.Helper()
+
+	// A cursor that doesn't advance ends a search after the first page.
+	page := ArtifactSearchResponse{NextCursorMark: "*"}
+	searches := make(map[string]string)
+	for _, artifact := range artifacts {
+		versions := VersionSearchResponse{NextCursorMark: "*"}
+		for _, version := range artifact.versions {
+			versions.Response.Docs = append(versions.Response.Docs, VersionDoc{
+				GroupID:    "org.example",
+				ArtifactID: artifact.name,
+				Version:    version.version,
+				EC:         version.ec,
+			})
+		}
+		versions.Response.NumFound = len(versions.Response.Docs)
+
+		query := url.Values{"q": {fmt.Sprintf("g:org.example AND a:%s", artifact.name)}, "core": {"gav"}, "rows": {"150"}, "wt": {"json"}}
+		setSearchPosition(query, firstSearchPosition)
+		searches[query.Encode()] = mustMarshal(t, versions)
+
+		doc := struct {
+			GroupID       string `json:"g"`
+			ArtifactID    string `json:"a"`
+			LatestVersion string `json:"latestVersion"`
+		}{GroupID: "org.example", ArtifactID: artifact.name}
+		if len(artifact.versions) > 0 {
+			doc.LatestVersion = artifact.versions[len(artifact.versions)-1].version
+		}
+		page.Response.Docs = append(page.Response.Docs, doc)
+	}
+	searches["cursorMark=%2A&q=cyclonedx.json&rows=150&sort=id+asc&wt=json"] = mustMarshal(t, page)
+
+	return searches
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
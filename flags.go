@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// listFlag is a flag.Value for comma-separated lists.
+// Setting it replaces any previous (or default) value.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return errors.New("list must not contain empty items")
+		}
+		items = append(items, item)
+	}
+	*l = items
+	return nil
+}
+
+// multiFlag is a flag.Value for flags that may be provided multiple times.
+// Every occurrence of the flag adds another value.
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	if m == nil {
+		return ""
+	}
+	return strings.Join(*m, ", ")
+}
+
+func (m *multiFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+const (
+	flattenProperties = "properties"
+	flattenEdges      = "edges"
+
+	transitiveDependencyProperty = "cdx-central:dependency:transitive"
+)
+
+// transitiveDependencies resolves the dependency graph of bom into
+// the transitive closure of each node. Cycles are tolerated; a node
+// that is part of a cycle lists itself as transitive dependency.
+func transitiveDependencies(bom *cyclonedx.BOM) map[string][]string {
+	direct := directDependencies(bom)
+
+	closure := make(map[string][]string, len(direct))
+	for ref := range direct {
+		visited := make(map[string]bool)
+		stack := append([]string{}, direct[ref]...)
+		for len(stack) > 0 {
+			dep := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			stack = append(stack, direct[dep]...)
+		}
+
+		deps := make([]string, 0, len(visited))
+		for dep := range visited {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		closure[ref] = deps
+	}
+
+	return closure
+}
+
+// transitiveRatio returns the fraction of components reachable from the root
+// component that are not direct dependencies of it. SBOMs without root
+// component or dependency graph have a ratio of zero.
+func transitiveRatio(bom *cyclonedx.BOM) float64 {
+	if bom.Metadata == nil || bom.Metadata.Component == nil || bom.Metadata.Component.BOMRef == "" {
+		return 0
+	}
+	root := bom.Metadata.Component.BOMRef
+
+	direct := make(map[string]bool)
+	for _, dep := range directDependencies(bom)[root] {
+		direct[dep] = true
+	}
+
+	reachable, transitive := 0, 0
+	for _, dep := range transitiveDependencies(bom)[root] {
+		if dep == root {
+			continue
+		}
+		reachable++
+		if !direct[dep] {
+			transitive++
+		}
+	}
+	if reachable == 0 {
+		return 0
+	}
+
+	return float64(transitive) / float64(reachable)
+}
+
+func directDependencies(bom *cyclonedx.BOM) map[string][]string {
+	direct := make(map[string][]string)
+	if bom.Dependencies == nil {
+		return direct
+	}
+
+	for _, dep := range *bom.Dependencies {
+		if dep.Dependencies != nil {
+			direct[dep.Ref] = append(direct[dep.Ref], *dep.Dependencies...)
+		} else if _, ok := direct[dep.Ref]; !ok {
+			direct[dep.Ref] = nil
+		}
+	}
+
+	return direct
+}
+
+// flattenDependenciesToProperties adds a property for every transitive
+// dependency to each component of bom, including the root component.
+func flattenDependenciesToProperties(bom *cyclonedx.BOM) {
+	closure := transitiveDependencies(bom)
+
+	var visit func(component *cyclonedx.Component)
+	visit = func(component *cyclonedx.Component) {
+		if deps := closure[component.BOMRef]; component.BOMRef != "" && len(deps) > 0 {
+			properties := make([]cyclonedx.Property, 0, len(deps))
+			if component.Properties != nil {
+				properties = append(properties, *component.Properties...)
+			}
+			for _, dep := range deps {
+				properties = append(properties, cyclonedx.Property{
+					Name:  transitiveDependencyProperty,
+					Value: dep,
+				})
+			}
+			component.Properties = &properties
+		}
+
+		if component.Components != nil {
+			for i := range *component.Components {
+				visit(&(*component.Components)[i])
+			}
+		}
+	}
+
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		visit(bom.Metadata.Component)
+	}
+	if bom.Components != nil {
+		for i := range *bom.Components {
+			visit(&(*bom.Components)[i])
+		}
+	}
+}
+
+type dependencyEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Direct bool   `json:"direct"`
+}
+
+// dependencyEdges returns all edges of the transitive closure of bom's dependency graph,
+// sorted by their source and target.
+func dependencyEdges(bom *cyclonedx.BOM) []dependencyEdge {
+	direct := directDependencies(bom)
+	closure := transitiveDependencies(bom)
+
+	edges := make([]dependencyEdge, 0)
+	for from, deps := range closure {
+		for _, to := range deps {
+			edges = append(edges, dependencyEdge{
+				From:   from,
+				To:     to,
+				Direct: contains(direct[from], to),
+			})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	return edges
+}
+
+func encodeDependencyEdges(bom *cyclonedx.BOM) ([]byte, error) {
+	return json.MarshalIndent(struct {
+		Edges []dependencyEdge `json:"edges"`
+	}{
+		Edges: dependencyEdges(bom),
+	}, "", "  ")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func newDependencyGraphBOM() *cyclonedx.BOM {
+	bom := cyclonedx.NewBOM()
+	bom.Metadata = &cyclonedx.Metadata{
+		Component: &cyclonedx.Component{BOMRef: "root", Name: "root"},
+	}
+	bom.Components = &[]cyclonedx.Component{
+		{BOMRef: "a", Name: "a"},
+		{BOMRef: "b", Name: "b"},
+		{BOMRef: "c", Name: "c"},
+	}
+	bom.Dependencies = &[]cyclonedx.Dependency{
+		{Ref: "root", Dependencies: &[]string{"a"}},
+		{Ref: "a", Dependencies: &[]string{"b"}},
+		{Ref: "b", Dependencies: &[]string{"c", "a"}}, // cycle a -> b -> a
+		{Ref: "c"},
+	}
+	return bom
+}
+
+func TestTransitiveDependencies(t *testing.T) {
+	closure := transitiveDependencies(newDependencyGraphBOM())
+
+	expected := map[string][]string{
+		"root": {"a", "b", "c"},
+		"a":    {"a", "b", "c"},
+		"b":    {"a", "b", "c"},
+		"c":    {},
+	}
+	if !reflect.DeepEqual(closure, expected) {
+		t.Fatalf("expected %v, got %v", expected, closure)
+	}
+}
+
+func TestTransitiveRatio(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(bom *cyclonedx.BOM)
+		expected float64
+	}{
+		{
+			name:     "Graph",
+			modify:   func(bom *cyclonedx.BOM) {},
+			expected: 2.0 / 3,
+		},
+		{
+			name: "DirectOnly",
+			modify: func(bom *cyclonedx.BOM) {
+				bom.Dependencies = &[]cyclonedx.Dependency{{Ref: "root", Dependencies: &[]string{"a", "b", "c"}}}
+			},
+			expected: 0,
+		},
+		{
+			name:     "NoGraph",
+			modify:   func(bom *cyclonedx.BOM) { bom.Dependencies = nil },
+			expected: 0,
+		},
+		{
+			name:     "NoRootComponent",
+			modify:   func(bom *cyclonedx.BOM) { bom.Metadata = nil },
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bom := newDependencyGraphBOM()
+			tc.modify(bom)
+			if ratio := transitiveRatio(bom); ratio != tc.expected {
+				t.Fatalf("expected ratio %g, got %g", tc.expected, ratio)
+			}
+		})
+	}
+}
+
+func TestFlattenDependenciesToProperties(t *testing.T) {
+	bom := newDependencyGraphBOM()
+	flattenDependenciesToProperties(bom)
+
+	root := bom.Metadata.Component
+	if root.Properties == nil || len(*root.Properties) != 3 {
+		t.Fatalf("expected root to have 3 properties, got %v", root.Properties)
+	}
+	for _, property := range *root.Properties {
+		if property.Name != transitiveDependencyProperty {
+			t.Fatalf("unexpected property %s", property.Name)
+		}
+	}
+
+	if c := (*bom.Components)[2]; c.Properties != nil {
+		t.Fatalf("expected c to have no properties, got %v", *c.Properties)
+	}
+}
+
+func TestDependencyEdges(t *testing.T) {
+	edges := dependencyEdges(newDependencyGraphBOM())
+	if len(edges) != 9 {
+		t.Fatalf("expected 9 edges, got %d", len(edges))
+	}
+	if edges[0] != (dependencyEdge{From: "a", To: "a", Direct: false}) {
+		t.Fatalf("unexpected first edge %v", edges[0])
+	}
+	if edges[1] != (dependencyEdge{From: "a", To: "b", Direct: true}) {
+		t.Fatalf("unexpected second edge %v", edges[1])
+	}
+}
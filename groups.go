@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// readListFile reads a file with one entry per line.
+// Empty lines and lines starting with # are ignored.
+func readListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GroupList is a list of Maven groups.
+//
+// An entry matches the group of the same name, as well as all of its subgroups
+// ("org.apache" matches "org.apache" and "org.apache.commons", but not "org.apachefoo").
+// Entries ending with * match all groups starting with the preceding prefix.
+type GroupList []string
+
+func readGroupList(path string) (GroupList, error) {
+	return readListFile(path)
+}
+
+func (l GroupList) Matches(group string) bool {
+	for _, entry := range l {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if strings.HasPrefix(group, prefix) {
+				return true
+			}
+			continue
+		}
+		if group == entry || strings.HasPrefix(group, entry+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadGroupList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.txt")
+	content := "# apache stuff\norg.apache\n\n  com.fasterxml*  \n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := readGroupList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (GroupList{"org.apache", "com.fasterxml*"}); !reflect.DeepEqual(list, expected) {
+		t.Fatalf("expected %v, got %v", expected, list)
+	}
+}
+
+func TestGroupListMatches(t *testing.T) {
+	list := GroupList{"org.apache", "com.fasterxml*"}
+
+	testCases := map[string]bool{
+		"org.apache":               true,
+		"org.apache.commons":       true,
+		"org.apachefoo":            false,
+		"com.fasterxml":            true,
+		"com.fasterxml.jackson":    true,
+		"com.fasterxmlfoo":         true,
+		"io.github.example":        false,
+		"org":                      false,
+		"com.fasterxm":             false,
+		"org.apache.logging.log4j": true,
+	}
+
+	for group, expected := range testCases {
+		if actual := list.Matches(group); actual != expected {
+			t.Errorf("expected Matches(%q) to be %v, got %v", group, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Index records the SBOMs collected during a crawl.
+// It is safe for concurrent use.
+type Index struct {
+	mux       sync.Mutex
+	SBOMs     []IndexEntry     `json:"sboms"`
+	Discarded []DiscardedEntry `json:"discarded,omitempty"`
+	Aliases   []AliasEntry     `json:"aliases,omitempty"`
+}
+
+type IndexEntry struct {
+	GroupID    string `json:"group"`
+	ArtifactID string `json:"artifact"`
+	Version    string `json:"version"`
+	File       string `json:"file"`
+	Archive    string `json:"archive,omitempty"`
+
+	Classifiers  []string `json:"classifiers,omitempty"`
+	SerialNumber string   `json:"serialNumber,omitempty"`
+	LinkedFrom   string   `json:"linkedFrom,omitempty"`
+	NonStrict    bool     `json:"nonStrict,omitempty"`
+	QualityScore float64  `json:"qualityScore"`
+
+	// EnvelopedSignature is set if the SBOM is signed, and DetachedSignature holds
+	// the file name of its detached signature, if it has one (-fetch-signatures).
+	EnvelopedSignature bool   `json:"envelopedSignature,omitempty"`
+	DetachedSignature  string `json:"detachedSignature,omitempty"`
+
+	// VersionedRatio is the fraction of components with a concrete version.
+	// Like CPERatio, it's left out if only the metadata of the SBOM was decoded.
+	VersionedRatio *float64 `json:"versionedRatio,omitempty"`
+
+	// CPERatio is the fraction of components with a CPE.
+	CPERatio *float64 `json:"cpeRatio,omitempty"`
+
+	// SpecInconsistencies lists fields that require a newer spec version than the declared one (-check-spec-consistency).
+	SpecInconsistencies []string `json:"specInconsistencies,omitempty"`
+
+	ComponentCounts ComponentCounts `json:"componentCounts"`
+
+	// OriginalComponentCounts are the component counts before -component-types removed components.
+	OriginalComponentCounts *ComponentCounts `json:"originalComponentCounts,omitempty"`
+
+	// CollapsedDuplicates is the number of duplicate components removed by -dedup-components.
+	CollapsedDuplicates int `json:"collapsedDuplicates,omitempty"`
+
+	// Tools lists the tools that generated the SBOM, e.g. the CycloneDX Maven plugin and its version.
+	Tools []SBOMTool `json:"tools,omitempty"`
+
+	// UnresolvablePurls lists purls of components that reference artifacts that don't exist (-verify-purl-resolvable).
+	UnresolvablePurls []string `json:"unresolvablePurls,omitempty"`
+
+	// ComponentSetHash identifies the set of components in the SBOM (-dedup-by-component-set).
+	ComponentSetHash string `json:"componentSetHash,omitempty"`
+
+	Size          int `json:"size"`
+	CompactedSize int `json:"compactedSize,omitempty"`
+
+	// SHA256 is the SHA-256 hash of the SBOM file as written.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// CanonicalSHA256 is the SHA-256 hash of the SBOM in its canonical form (-canonical).
+	CanonicalSHA256 string `json:"canonicalSha256,omitempty"`
+
+	Headers *ResponseHeaders `json:"headers,omitempty"`
+}
+
+// DiscardedEntry records an SBOM that was discarded by a filter.
+type DiscardedEntry struct {
+	GroupID    string         `json:"group"`
+	ArtifactID string         `json:"artifact"`
+	Version    string         `json:"version"`
+	Reason     string         `json:"reason"`
+	Metrics    map[string]any `json:"metrics,omitempty"`
+}
+
+func (e DiscardedEntry) gav() GAV {
+	return GAV{GroupID: e.GroupID, ArtifactID: e.ArtifactID, Version: e.Version}
+}
+
+// AliasEntry records an SBOM that was not written because the SBOM of AliasOf
+// has the same set of components (-dedup-by-component-set).
+type AliasEntry struct {
+	GroupID          string `json:"group"`
+	ArtifactID       string `json:"artifact"`
+	Version          string `json:"version"`
+	AliasOf          string `json:"aliasOf"`
+	ComponentSetHash string `json:"componentSetHash"`
+}
+
+func (e AliasEntry) gav() GAV {
+	return GAV{GroupID: e.GroupID, ArtifactID: e.ArtifactID, Version: e.Version}
+}
+
+// ResponseHeaders are the HTTP response headers of an SBOM download.
+type ResponseHeaders struct {
+	LastModified  string `json:"lastModified,omitempty"`
+	ETag          string `json:"etag,omitempty"`
+	ContentLength string `json:"contentLength,omitempty"`
+	Date          string `json:"date,omitempty"`
+}
+
+func newResponseHeaders(header http.Header) ResponseHeaders {
+	return ResponseHeaders{
+		LastModified:  header.Get("Last-Modified"),
+		ETag:          header.Get("ETag"),
+		ContentLength: header.Get("Content-Length"),
+		Date:          header.Get("Date"),
+	}
+}
+
+func NewIndex() *Index {
+	return &Index{
+		SBOMs: make([]IndexEntry, 0),
+	}
+}
+
+func (i *Index) Add(entry IndexEntry) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	i.SBOMs = append(i.SBOMs, entry)
+}
+
+// Formats of index files (-index-format).
+// gob is more compact, and considerably faster to read for large indexes.
+const (
+	indexFormatJSON = "json"
+	indexFormatGob  = "gob"
+)
+
+// ReadIndexFile reads an index previously written by WriteFile.
+// The format is detected from the content, so indexes of either format can be read.
+func ReadIndexFile(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadIndex(f)
+}
+
+// ReadIndex reads an index in either format from r.
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(64)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	index := NewIndex()
+	// JSON indexes are objects, while gob streams start with the length of a type definition.
+	if trimmed := bytes.TrimLeft(head, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.NewDecoder(br).Decode(index)
+	} else {
+		err = gob.NewDecoder(br).Decode(index)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// LatestVersions returns the highest version recorded per artifact according to cmp,
+// keyed by group:artifact.
+func (i *Index) LatestVersions(cmp VersionComparator) map[string]string {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	latest := make(map[string]string)
+	for _, entry := range i.SBOMs {
+		key := Artifact{GroupID: entry.GroupID, ArtifactID: entry.ArtifactID}.String()
+		if current, ok := latest[key]; !ok || cmp.Compare(entry.Version, current) > 0 {
+			latest[key] = entry.Version
+		}
+	}
+
+	return latest
+}
+
+// SerialNumbers returns the serial numbers of all SBOMs in the index that have one.
+func (i *Index) SerialNumbers() []string {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	serials := make([]string, 0, len(i.SBOMs))
+	for _, entry := range i.SBOMs {
+		if entry.SerialNumber != "" {
+			serials = append(serials, entry.SerialNumber)
+		}
+	}
+
+	return serials
+}
+
+func (i *Index) AddDiscarded(entry DiscardedEntry) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	i.Discarded = append(i.Discarded, entry)
+}
+
+func (i *Index) AddAlias(entry AliasEntry) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	i.Aliases = append(i.Aliases, entry)
+}
+
+// WriteFile writes the index to path in format, which is either indexFormatJSON (the default if empty) or indexFormatGob.
+func (i *Index) WriteFile(path, format string) error {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	// Discarded SBOMs and aliases are recorded in the order they are encountered,
+	// which varies between runs.
+	sort.Slice(i.Discarded, func(a, b int) bool {
+		return i.Discarded[a].gav().Less(i.Discarded[b].gav())
+	})
+	sort.Slice(i.Aliases, func(a, b int) bool {
+		return i.Aliases[a].gav().Less(i.Aliases[b].gav())
+	})
+
+	return writeFileAtomic(path, false, func(w io.Writer) error {
+		switch format {
+		case indexFormatJSON, "":
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(i)
+		case indexFormatGob:
+			return gob.NewEncoder(w).Encode(i)
+		default:
+			return fmt.Errorf("unsupported index format %q", format)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexLatestVersions(t *testing.T) {
+	index := NewIndex()
+	index.Add(IndexEntry{GroupID: "org.example", ArtifactID: "a", Version: "1.9"})
+	index.Add(IndexEntry{GroupID: "org.example", ArtifactID: "a", Version: "1.10"})
+	index.Add(IndexEntry{GroupID: "org.example", ArtifactID: "a", Version: "1.10-rc1"})
+	index.Add(IndexEntry{GroupID: "org.example", ArtifactID: "b", Version: "2.0"})
+
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := index.WriteFile(path, indexFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadIndexFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"org.example:a": "1.10",
+		"org.example:b": "2.0",
+	}
+	if latest := read.LatestVersions(MavenVersionComparator{}); !reflect.DeepEqual(latest, expected) {
+		t.Fatalf("expected %v, got %v", expected, latest)
+	}
+}
+
+func TestIndexSerialNumbers(t *testing.T) {
+	index := NewIndex()
+	index.Add(IndexEntry{GroupID: "org.example", ArtifactID: "a", Version: "1.0", SerialNumber: "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79"})
+	index.Add(IndexEntry{GroupID: "org.example", ArtifactID: "b", Version: "1.0"})
+
+	expected := []string{"urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79"}
+	if serials := index.SerialNumbers(); !reflect.DeepEqual(serials, expected) {
+		t.Fatalf("expected %v, got %v", expected, serials)
+	}
+}
+
+func TestIndexFormats(t *testing.T) {
+	recursive, purlUnique := 4, 3
+	index := NewIndex()
+	index.Add(IndexEntry{
+		GroupID: "org.example", ArtifactID: "a", Version: "1.0", File: "a.cdx.json",
+		Tools:           []SBOMTool{{Name: "cyclonedx-maven-plugin", Version: "2.7.9"}},
+		ComponentCounts: ComponentCounts{TopLevel: 3, Recursive: &recursive, PurlUnique: &purlUnique},
+		Headers:         &ResponseHeaders{ETag: `"abc"`},
+	})
+	index.AddDiscarded(DiscardedEntry{GroupID: "org.example", ArtifactID: "b", Version: "1.0", Reason: discardDecodeWarnings, Metrics: map[string]any{"warnings": []string{"unknown field"}}})
+	index.AddAlias(AliasEntry{GroupID: "org.example", ArtifactID: "c", Version: "1.0", AliasOf: "org.example:a:1.0", ComponentSetHash: "hash"})
+
+	for _, format := range []string{indexFormatJSON, indexFormatGob} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index")
+			if err := index.WriteFile(path, format); err != nil {
+				t.Fatal(err)
+			}
+			read, err := ReadIndexFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(read.SBOMs, index.SBOMs) || !reflect.DeepEqual(read.Aliases, index.Aliases) {
+				t.Errorf("expected %+v, got %+v", index, read)
+			}
+			if len(read.Discarded) != 1 || read.Discarded[0].Reason != discardDecodeWarnings {
+				t.Errorf("expected the discarded entry to be read, got %+v", read.Discarded)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+)
+
+var verbose bool
+
+// debugf logs only when verbose logging is enabled.
+func debugf(format string, v ...any) {
+	if verbose {
+		log.Printf(format, v...)
+	}
+}
+
+// artifactLogs buffers log lines by artifact with -log-by-artifact. It's nil otherwise.
+var artifactLogs *artifactLogWriter
+
+// artifactLogWriter is a log output that holds back the lines mentioning an artifact
+// while it's being processed, and writes them as a contiguous block once it completes.
+// Lines are attributed by the coordinates they contain (group:artifact), which all
+// per-artifact log messages include. Other lines are written immediately.
+type artifactLogWriter struct {
+	mux     sync.Mutex
+	out     io.Writer
+	buffers map[string]*bytes.Buffer
+}
+
+func newArtifactLogWriter(out io.Writer) *artifactLogWriter {
+	return &artifactLogWriter{out: out, buffers: make(map[string]*bytes.Buffer)}
+}
+
+func (w *artifactLogWriter) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if buf := w.buffers[w.match(p)]; buf != nil {
+		return buf.Write(p)
+	}
+	return w.out.Write(p)
+}
+
+// match returns the longest key of an artifact being processed that is mentioned in line.
+// A key only matches if it isn't followed by another character of a coordinate,
+// so that org.example:lib doesn't match org.example:lib-extra.
+func (w *artifactLogWriter) match(line []byte) string {
+	var matched string
+	for key := range w.buffers {
+		if len(key) <= len(matched) {
+			continue
+		}
+		for rest := line; ; {
+			i := bytes.Index(rest, []byte(key))
+			if i < 0 {
+				break
+			}
+			rest = rest[i+len(key):]
+			if len(rest) == 0 || !isCoordinateChar(rest[0]) {
+				matched = key
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func isCoordinateChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_'
+}
+
+// Begin starts buffering the lines of artifact. It's a no-op on a nil writer.
+func (w *artifactLogWriter) Begin(artifact Artifact) {
+	if w == nil {
+		return
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.buffers[artifact.String()] = &bytes.Buffer{}
+}
+
+// End writes the buffered lines of artifact as a block. It's a no-op on a nil writer.
+func (w *artifactLogWriter) End(artifact Artifact) {
+	if w == nil {
+		return
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.flush(artifact.String(), "")
+}
+
+// Close writes the lines of all artifacts that didn't complete, e.g. because the crawl was interrupted.
+func (w *artifactLogWriter) Close() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	keys := make([]string, 0, len(w.buffers))
+	for key := range w.buffers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w.flush(key, " (incomplete)")
+	}
+}
+
+func (w *artifactLogWriter) flush(key, note string) {
+	buf := w.buffers[key]
+	delete(w.buffers, key)
+	if buf == nil || buf.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(w.out, "----- begin %s%s -----\n", key, note)
+	_, _ = buf.WriteTo(w.out)
+	fmt.Fprintf(w.out, "----- end %s%s -----\n", key, note)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestArtifactLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newArtifactLogWriter(&out)
+	logger := log.New(w, "", 0)
+
+	lib := Artifact{GroupID: "org.example", ArtifactID: "lib"}
+	extra := Artifact{GroupID: "org.example", ArtifactID: "lib-extra"}
+	w.Begin(lib)
+	w.Begin(extra)
+
+	logger.Printf("searching for versions of org.example:lib")
+	logger.Printf("found sbom for org.example:lib-extra:1.0.0 with suffix -cyclonedx.json")
+	logger.Printf("fetching artifact search results 0 - 150")
+	logger.Printf("found sbom for org.example:lib:1.0.0 with suffix -cyclonedx.json")
+	w.End(lib)
+	logger.Printf("discarding sbom for org.example:lib:2.0.0 because it was not sampled")
+	w.Close()
+
+	expected := "fetching artifact search results 0 - 150\n" +
+		"----- begin org.example:lib -----\n" +
+		"searching for versions of org.example:lib\n" +
+		"found sbom for org.example:lib:1.0.0 with suffix -cyclonedx.json\n" +
+		"----- end org.example:lib -----\n" +
+		"discarding sbom for org.example:lib:2.0.0 because it was not sampled\n" +
+		"----- begin org.example:lib-extra (incomplete) -----\n" +
+		"found sbom for org.example:lib-extra:1.0.0 with suffix -cyclonedx.json\n" +
+		"----- end org.example:lib-extra (incomplete) -----\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
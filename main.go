@@ -1,275 +1,754 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"runtime/debug"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
 
 	"github.com/CycloneDX/cyclonedx-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
-	var (
-		concurrency   int
-		minComponents int
-		outputDir     string
-	)
-	flag.IntVar(&concurrency, "concurrency", 5, "How many artifacts to process concurrently")
-	flag.IntVar(&minComponents, "min-components", 10, "Minimum number of components in an SBOM")
-	flag.StringVar(&outputDir, "output", ".", "Output directory")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "repair":
+			runRepair(os.Args[2:])
+			return
+		case "report-schema":
+			runReportSchema(os.Args[2:])
+			return
+		}
+	}
+
+	opts := Options{
+		SBOMSuffixes: []string{"-cyclonedx.json", ".cdx.json"},
+	}
+	flag.IntVar(&opts.Concurrency, "concurrency", 5, "How many artifacts to process concurrently")
+	flag.BoolVar(&opts.AutoConcurrency, "auto-concurrency", false, "Start with processing one artifact at a time, and adjust the concurrency based on error and rate limit rates")
+	flag.IntVar(&opts.MaxConcurrency, "max-concurrency", 20, "Upper bound for -auto-concurrency")
+	flag.IntVar(&opts.Filters.MaxDecodes, "max-decodes", 0, "Maximum number of SBOMs to decode concurrently, independent of -concurrency (0 for unlimited)")
+	flag.Int64Var(&opts.MemLimit, "mem-limit", 0, "Soft memory limit in bytes for the Go runtime; downloads are held back while the heap is close to it (0 for no limit)")
+	flag.IntVar(&opts.Filters.MinComponents, "min-components", 10, "Minimum number of components in an SBOM")
+	flag.IntVar(&opts.Filters.MinBytes, "min-bytes", 0, "Minimum size in bytes of a downloaded SBOM")
+	flag.IntVar(&opts.Filters.MaxBytes, "max-bytes", 0, "Maximum size in bytes of a downloaded SBOM (0 for unlimited)")
+	flag.BoolVar(&opts.Filters.CountNested, "count-nested", false, "Include nested components when counting components for -min-components (same as -component-count-source recursive)")
+	flag.StringVar(&opts.Filters.ComponentCountSource, "component-count-source", componentCountTopLevel, "How to count components for -min-components: \"top-level\", \"recursive\" (including nested components) or \"purl-unique\" (distinct purls)")
+	flag.BoolVar(&opts.Filters.RequireRootComponent, "require-root-component", false, "Discard SBOMs without a root component (metadata.component)")
+	flag.Float64Var(&opts.Filters.MinQualityScore, "min-quality-score", 0, "Minimum metadata completeness score (0-100) of an SBOM")
+	flag.Float64Var(&opts.Filters.MinVersionedRatio, "min-versioned-ratio", 0, "Minimum fraction (0-1) of components with a concrete version, as opposed to an empty version or a range")
+	flag.BoolVar(&opts.Filters.RequireCPE, "require-cpe", false, "Discard SBOMs in which no component has a CPE")
+	flag.Float64Var(&opts.Filters.MinCPERatio, "min-cpe-ratio", 0, "Minimum fraction (0-1) of components with a CPE")
+	flag.Float64Var(&opts.Filters.MinTransitiveRatio, "min-transitive-ratio", 0, "Minimum fraction (0-1) of components reachable from the root component that are not direct dependencies of it")
+	flag.Float64Var(&opts.Filters.SampleRate, "sample-rate", 1, "Fraction (0-1) of eligible SBOMs to keep")
+	flag.Int64Var(&opts.Filters.SampleSeed, "sample-seed", 0, "Seed for -sample-rate")
+	flag.Var((*listFlag)(&opts.Filters.PurlTypes), "purl-types", "Comma-separated list of purl types (e.g. maven) of components to keep; all other components are removed")
+	flag.BoolVar(&opts.Filters.DiscardEmpty, "purl-types-discard-empty", false, "Discard SBOMs without components after filtering by -purl-types")
+	flag.Var((*listFlag)(&opts.Filters.Scopes), "scopes", "Comma-separated list of scopes (required, optional, excluded) of components to keep; all other components are removed")
+	flag.Var((*listFlag)(&opts.Filters.ComponentTypes), "component-types", "Comma-separated list of types (e.g. library) of components to keep; all other components are removed")
+	flag.BoolVar(&opts.Filters.StripPurlQualifiers, "strip-purl-qualifiers", false, "Remove qualifiers (e.g. ?type=jar) from the purls of all components")
+	flag.BoolVar(&opts.Filters.DedupComponents, "dedup-components", false, "Collapse components with the same purl and version within an SBOM into one, merging their properties and dependencies")
+	flag.BoolVar(&opts.Filters.KeepRawPurls, "keep-raw", false, "Preserve the original purl in a property when it's changed by -strip-purl-qualifiers")
+	flag.BoolVar(&opts.Filters.Lenient, "lenient", false, "Tolerate values of unexpected types when decoding SBOMs, and flag affected SBOMs as non-strict in the index")
+	flag.StringVar(&opts.Filters.MinSpecVersion, "min-spec-version", "", "Minimum spec version of an SBOM, from 1.0 to 1.4 (newer versions count as 1.4)")
+	flag.BoolVar(&opts.Filters.CheckSpecConsistency, "check-spec-consistency", false, "Flag JSON SBOMs that use fields of a newer spec version than the declared one, in the log, index and summary")
+	flag.BoolVar(&opts.ValidateTimestamps, "validate-timestamps-monotonic", false, "Flag artifacts whose SBOMs have older timestamps (metadata.timestamp) for newer versions, in the log and summary")
+	flag.BoolVar(&opts.VerifyPurlResolvable, "verify-purl-resolvable", false, "Check with HEAD requests whether the artifacts referenced by a sample of the Maven purls of each SBOM exist, and flag those that don't in the log, index and summary")
+	flag.BoolVar(&opts.Filters.ToolVersions, "tool-version", false, "Aggregate the tools (name and version) that generated collected SBOMs in the summary")
+	flag.IntVar(&opts.VerifyPurlsSample, "verify-purls-sample", 10, "Maximum number of purls per SBOM to check with -verify-purl-resolvable")
+	flag.BoolVar(&opts.Filters.StrictDecode, "strict-decode", false, "Discard SBOMs that decode with warnings, such as unknown fields, component types or scopes")
+	flag.BoolVar(&opts.Filters.DecodeOnlyMetadata, "decode-only-metadata", false, "Only decode the metadata of JSON SBOMs, and count their top-level components without decoding them")
+	flag.BoolVar(&opts.Filters.DiscardAllExcluded, "discard-all-excluded", false, "Discard SBOMs in which all components have the scope excluded")
+	flag.Var((*multiFlag)(&opts.Filters.Properties), "property", "Only keep SBOMs with a component that has this property (name=value, can be repeated)")
+	flag.Var((*multiFlag)(&opts.Filters.ContainsGroups), "contains-group", "Only keep SBOMs with a Maven component of this group or its subgroups, e.g. com.fasterxml.jackson (can be repeated)")
+	flag.Var((*multiFlag)(&opts.Filters.RequiredProperties), "require-property", "Only keep SBOMs with a component that has a property with this name, regardless of its value (can be repeated)")
+	flag.StringVar(&opts.OutputDir, "output", ".", "Output directory, or - to write the SBOM of -probe to stdout")
+	flag.StringVar(&opts.FileNameTemplate, "filename-template", "", "Go template for the file names of SBOMs, relative to the output (e.g. {{.Group}}/{{.Artifact}}/{{.Version}}.json)")
+	flag.BoolVar(&opts.NoClobber, "no-clobber", false, "Don't overwrite or re-download SBOMs that already exist in the output directory (empty files are replaced)")
+	flag.StringVar(&opts.Archive, "archive", "", "Write SBOMs to a zip archive instead of the output directory")
+	flag.IntVar(&opts.ArchiveMaxEntries, "archive-max-entries", 0, "Maximum number of files per archive (0 for unlimited)")
+	flag.Int64Var(&opts.ArchiveMaxBytes, "archive-max-bytes", 0, "Maximum uncompressed size of files per archive (0 for unlimited)")
+	flag.StringVar(&opts.IndexFile, "index", "", "Write an index of all collected SBOMs to this file")
+	flag.StringVar(&opts.IndexFormat, "index-format", indexFormatJSON, "Format of -index: \"json\" or \"gob\" (compact and faster to read, for large corpora)")
+	flag.BoolVar(&opts.IndexHeaders, "index-headers", false, "Include HTTP response headers of SBOM downloads in the index")
+	flag.BoolVar(&opts.IndexDiscarded, "index-discarded", false, "Record SBOMs discarded by filters, along with the reason, in the index")
+	flag.StringVar(&opts.DiscardsFile, "discards-csv", "", "Append SBOMs discarded by filters, along with the reason and the metric that caused it, to this CSV file")
+	flag.StringVar(&opts.SummaryFile, "summary-file", "", "Write the summary as JSON to this file (see the report-schema subcommand for its schema)")
+	flag.StringVar(&opts.CatalogFile, "catalog", "", "Write a CycloneDX BOM referencing all collected SBOMs to this file")
+	flag.Var((*multiFlag)(&opts.Extract), "extract", "Extract the value at this JSON pointer (e.g. /metadata/component/purl) from every SBOM (can be repeated)")
+	flag.StringVar(&opts.ExtractFile, "extract-output", "", "Write values extracted with -extract to this NDJSON file")
+	flag.StringVar(&opts.PartitionBy, "partition-by", "", "Write SBOMs to subdirectories of the output by this property of the SBOM (\"license\")")
+	flag.BoolVar(&opts.DeterministicOrder, "deterministic-order", false, "Buffer SBOMs in memory and write them sorted by GAV at the end")
+	flag.BoolVar(&opts.RandomOrder, "random-order", false, "Discover all artifacts before processing them in random order, rather than in search order")
+	flag.Int64Var(&opts.Seed, "random-seed", 0, "Seed for -random-order (0 for a random seed, which is logged)")
+	flag.BoolVar(&opts.WriteSidecarMeta, "write-sidecar-meta", false, "Write the download metadata of each SBOM (URL, time, hash, counts, headers) to a .meta.json file next to it")
+	flag.BoolVar(&opts.FetchSignatures, "fetch-signatures", false, "Record whether SBOMs carry an enveloped signature, and download their detached signatures (.jws) alongside them")
+	flag.BoolVar(&opts.WithPOM, "with-pom", false, "Download the POM of each artifact alongside its SBOM")
+	flag.BoolVar(&opts.StatsOnly, "stats-only", false, "Download and analyze SBOMs for the summary, but don't write any files")
+	flag.IntVar(&opts.FollowBOMRefs, "follow-bom-refs", 0, "Also collect SBOMs referenced via external references of type bom, up to this depth (0 to disable)")
+	flag.IntVar(&opts.MaxLinkedBOMs, "max-linked-boms", 1000, "Maximum number of SBOMs to collect via -follow-bom-refs (0 for unlimited)")
+	flag.IntVar(&opts.MaxPerGroup, "max-per-group", 0, "Maximum number of SBOMs to keep per group (0 for unlimited)")
+	flag.IntVar(&opts.MaxSBOMs, "max-sboms", 0, "Stop downloading SBOMs once this many have been collected (0 for unlimited)")
+	flag.StringVar(&opts.Checkpoint, "checkpoint", "", "Persist the number of SBOMs collected towards -max-sboms in this file, and resume counting from it")
+	flag.IntVar(&opts.MinUniqueGroups, "min-unique-groups", 0, "With -max-sboms, keep collecting SBOMs of new groups until this many distinct groups are represented")
+	flag.Int64Var(&opts.MaxDiskBytes, "max-disk-bytes", 0, "Stop downloading SBOMs once this many bytes have been written (0 for unlimited)")
+	flag.Var((*multiFlag)(&opts.GroupPrefixes), "group-prefix", "Only crawl artifacts whose group starts with this prefix (can be repeated)")
+	flag.IntVar(&opts.SearchConcurrency, "search-concurrency", 4, "How many artifact searches to run concurrently when multiple -group-prefix values are given")
+	flag.StringVar(&opts.IncludeGroupsFile, "include-groups-file", "", "Only crawl artifacts of groups listed in this file (one group or prefix per line)")
+	flag.StringVar(&opts.ExcludeGroupsFile, "exclude-groups-file", "", "Don't crawl artifacts of groups listed in this file (one group or prefix per line)")
+	flag.StringVar(&opts.SkipListFile, "skip-list-file", "", "Never download SBOMs of artifacts or versions listed in this file (one group:artifact or group:artifact:version per line)")
+	flag.StringVar(&opts.NewerThanIndex, "newer-than-index", "", "Only download versions newer than the highest version of the same artifact recorded in this index")
+	flag.BoolVar(&opts.UniqueSerial, "unique-serial", false, "Don't write SBOMs whose serial number was already seen during the crawl")
+	flag.BoolVar(&opts.DedupComponentSet, "dedup-by-component-set", false, "Don't write SBOMs whose set of components (by purl) equals that of an SBOM already written during the crawl")
+	flag.StringVar(&opts.UniqueSerialIndex, "unique-serial-index", "", "Treat the serial numbers recorded in this index as already seen for -unique-serial")
+	flag.StringVar(&opts.VersionSource, "version-source", versionSourceSolr, "Where to enumerate the versions of each artifact from: Solr search (\"solr\") or maven-metadata.xml (\"metadata\")")
+	flag.Var((*listFlag)(&opts.SBOMSuffixes), "sbom-suffixes", "Comma-separated list of file name suffixes to try, in order, when downloading SBOMs")
+	flag.BoolVar(&opts.RetryAsXML, "retry-404-as-xml", true, "Fall back to the XML SBOM (-cyclonedx.xml) when none of -sbom-suffixes exists")
+	flag.BoolVar(&opts.Compact, "compact", false, "Re-encode SBOMs as JSON without indentation before writing them")
+	flag.BoolVar(&opts.Canonical, "canonical", false, "Re-encode JSON SBOMs according to the JSON Canonicalization Scheme (RFC 8785) before writing them")
+	flag.StringVar(&opts.FlattenDependencies, "flatten-dependencies", "", "Flatten the dependency graph into component properties (\"properties\") or an edge list file (\"edges\")")
+	flag.BoolVar(&opts.ForceHTTP1, "force-http1", false, "Disable HTTP/2, e.g. for proxies that don't support it")
+	flag.StringVar(&opts.HTTPCache, "http-cache", "", "Store successful responses in this directory and serve repeated requests from it")
+	flag.DurationVar(&opts.CacheTTL, "cache-ttl", 24*time.Hour, "Maximum age of responses served from -http-cache (0 for no expiry)")
+	flag.DurationVar(&opts.SearchTimeout, "search-timeout", 30*time.Second, "Timeout for individual search requests")
+	flag.DurationVar(&opts.DownloadTimeout, "download-timeout", 5*time.Minute, "Timeout for individual SBOM and POM downloads")
+	flag.DurationVar(&opts.RetryBaseDelay, "retry-base-delay", 5*time.Second, "Delay before the first retry when rate limited, doubled with every further retry")
+	flag.DurationVar(&opts.RetryMaxDelay, "retry-max-delay", 2*time.Minute, "Upper bound for the delay between retries when rate limited")
+	flag.StringVar(&opts.RetryJitter, "retry-jitter", retryJitterFull, "Randomize delays between retries (\"full\") or not (\"none\")")
+	flag.DurationVar(&opts.GAVBudget, "gav-budget", 0, "Maximum total time to spend on the SBOM of a single version, including retries (0 for unlimited)")
+	flag.DurationVar(&opts.ArtifactDeadline, "artifact-deadline", 0, "Maximum total time to spend on a single artifact, including the search for its versions and all downloads (0 for unlimited)")
+	flag.DurationVar(&opts.StuckTimeout, "stuck-timeout", 0, "Warn about workers that made no progress for this long while searching or downloading (0 to disable)")
+	flag.BoolVar(&opts.CancelStuck, "cancel-stuck", false, "Cancel the search or download of workers that tripped -stuck-timeout")
+	flag.Var((*multiFlag)(&opts.Headers), "header", "Add a header (\"Name: Value\") to every request (can be repeated)")
+	flag.Var((*listFlag)(&opts.AllowedHosts), "allowed-hosts", "Comma-separated list of hosts that requests may be redirected to, in addition to Maven Central")
+	flag.Var((*listFlag)(&opts.FallbackBaseURLs), "fallback-base-urls", "Comma-separated list of repository URLs to try, in order, when a file is not found on Maven Central (e.g. https://s01.oss.sonatype.org/content/repositories/releases)")
+	flag.StringVar(&opts.OTelEndpoint, "otel-endpoint", "", "Export traces via OTLP/HTTP to this endpoint (e.g. http://localhost:4318)")
+	flag.StringVar(&opts.QueueFile, "queue-file", "", "Persist the queue of artifacts to crawl to this file, and resume from it if it exists")
+	flag.StringVar(&opts.Source, "source", "", "Read SBOMs from an archive written with -archive (archive:<path>) instead of Maven Central")
+	flag.IntVar(&opts.EmptyExitCode, "empty-exit-code", 3, "Exit code to use when the crawl succeeded, but no SBOM passed the filters")
+	flag.BoolVar(&opts.FailFast, "fail-fast", false, "Abort the crawl and exit with a non-zero code on the first unexpected error")
+	flag.StringVar(&opts.RegistryIndex, "registry-index", "", "Only write a report of how many versions of each artifact have an SBOM to this file (NDJSON), without downloading anything")
+	flag.BoolVar(&opts.DiscoverClassifiers, "discover-classifiers", false, "Only tally which SBOM classifiers (e.g. -cyclonedx.json) versions were published with, without downloading anything")
+	flag.StringVar(&opts.Probe, "probe", "", "Only process the given group:artifact:version with verbose logging and exit")
+	flag.BoolVar(&verbose, "verbose", false, "Enable verbose logging")
+	flag.BoolVar(&opts.LogByArtifact, "log-by-artifact", false, "Hold back the log lines of each artifact and write them as a contiguous block once it completes")
 	flag.Parse()
 
-	wg := sync.WaitGroup{}
-	artifactsChan := make(chan Artifact, 1)
+	// Catch invalid flag combinations before any network activity happens.
+	err := opts.Validate()
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "invalid flags: %v\n", err)
+		os.Exit(2)
+	}
 
-	for i := 0; i < concurrency; i++ {
-		go func() {
-			defer wg.Done()
+	opts.Filters.DecodeOnlyMetadata = opts.metadataOnly()
 
-			for artifact := range artifactsChan {
-				versions, err := collectVersions(artifact)
-				if err != nil {
-					log.Fatalf("failed to collect versions for %s: %v", artifact, err)
-				}
+	// Exiting with a non-zero code must not skip deferred cleanups, like flushing traces.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
-				for _, version := range versions {
-					err = downloadSBOM(version, minComponents, outputDir)
-					if err != nil {
-						log.Printf("failed to download sbom for %s: %v", version, err)
-					}
-				}
-			}
-		}()
+	if opts.IncludeGroupsFile != "" {
+		opts.IncludeGroups, err = readGroupList(opts.IncludeGroupsFile)
+		if err != nil {
+			log.Fatalf("failed to read -include-groups-file: %v", err)
+		}
+	}
+	if opts.ExcludeGroupsFile != "" {
+		opts.ExcludeGroups, err = readGroupList(opts.ExcludeGroupsFile)
+		if err != nil {
+			log.Fatalf("failed to read -exclude-groups-file: %v", err)
+		}
+	}
+	if opts.SkipListFile != "" {
+		opts.SkipList, err = readSkipList(opts.SkipListFile)
+		if err != nil {
+			log.Fatalf("failed to read -skip-list-file: %v", err)
+		}
+	}
+	if opts.NewerThanIndex != "" {
+		prior, err := ReadIndexFile(opts.NewerThanIndex)
+		if err != nil {
+			log.Fatalf("failed to read -newer-than-index: %v", err)
+		}
+		opts.LatestVersions = prior.LatestVersions(opts.versionComparator())
 	}
 
-	artifacts, err := collectArtifacts()
+	headers, err := parseHeaders(opts.Headers)
 	if err != nil {
-		log.Fatalf("failed to collect artifacts: %v", err)
+		log.Fatalf("failed to parse -header: %v", err)
 	}
-
-	for _, artifact := range artifacts {
-		artifactsChan <- artifact
+	for name, values := range headers {
+		for _, value := range values {
+			debugf("adding header to all requests: %s: %s", name, redactHeader(name, value))
+		}
+	}
+	repo := mavenCentral(newHTTPClient(opts.ForceHTTP1, headers))
+	repo.FallbackBaseURLs = opts.FallbackBaseURLs
+	if opts.HTTPCache != "" {
+		cache, err := newCacheTransport(repo.Client.Transport, opts.HTTPCache, opts.CacheTTL)
+		if err != nil {
+			log.Fatalf("failed to create -http-cache: %v", err)
+		}
+		repo.Client.Transport = cache
+	}
+	if len(opts.AllowedHosts) > 0 {
+		repo.Client.CheckRedirect = allowedHostsRedirectPolicy(append(repo.hosts(), opts.AllowedHosts...))
+	}
+	searchTimeout = opts.SearchTimeout
+	downloadTimeout = opts.DownloadTimeout
+	retryBaseDelay = opts.RetryBaseDelay
+	retryMaxDelay = opts.RetryMaxDelay
+	retryJitter = opts.RetryJitter
+	if opts.MemLimit > 0 {
+		debug.SetMemoryLimit(opts.MemLimit)
 	}
 
-	close(artifactsChan)
-	wg.Wait()
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-type ArtifactSearchResponse struct {
-	Response struct {
-		Docs []struct {
-			GroupID       string `json:"g"`
-			ArtifactID    string `json:"a"`
-			LatestVersion string `json:"latestVersion"`
-		} `json:"docs"`
-	} `json:"response"`
-}
+	summary := NewSummary()
 
-type VersionSearchResponse struct {
-	Response struct {
-		Docs []struct {
-			GroupID    string   `json:"g"`
-			ArtifactID string   `json:"a"`
-			Version    string   `json:"v"`
-			Packaging  string   `json:"p"`  // "jar", "pom", etc.
-			EC         []string `json:"ec"` // "-sources.jar", ".jar", "-cyclonedx.json", etc.
+	if opts.Probe != "" {
+		runProbe(ctx, repo, opts, summary)
+		return
+	}
+	if opts.DiscoverClassifiers {
+		runClassifierDiscovery(ctx, repo, opts, summary)
+		return
+	}
+	if opts.RegistryIndex != "" {
+		if !runRegistryIndex(ctx, repo, opts, summary) {
+			exitCode = 1
 		}
-	} `json:"response"`
-}
+		return
+	}
 
-type Artifact struct {
-	GroupID       string
-	ArtifactID    string
-	LatestVersion string
-}
+	logSummaryOnSignal(ctx, summary)
 
-func (a Artifact) String() string {
-	return fmt.Sprintf("%s:%s", a.GroupID, a.ArtifactID)
-}
+	if opts.LogByArtifact {
+		artifactLogs = newArtifactLogWriter(log.Writer())
+		log.SetOutput(artifactLogs)
+	}
 
-type GAV struct {
-	GroupID    string
-	ArtifactID string
-	Version    string
-}
+	// With -fail-fast, the crawl is cancelled on the first unexpected error.
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+	var (
+		failFastOnce sync.Once
+		failedFast   bool
+	)
+	failFast := func(gav GAV, err error) {
+		if !opts.FailFast {
+			return
+		}
+		failFastOnce.Do(func() {
+			if gav == (GAV{}) {
+				log.Printf("aborting crawl because of -fail-fast: %v", err)
+			} else {
+				log.Printf("aborting crawl because of -fail-fast: %s: %v", gav, err)
+			}
+			failedFast = true
+			abort()
+		})
+	}
 
-func (g GAV) String() string {
-	return fmt.Sprintf("%s:%s:%s", g.GroupID, g.ArtifactID, g.Version)
-}
+	if opts.OTelEndpoint != "" {
+		shutdown, err := setupTracing(ctx, opts.OTelEndpoint)
+		if err != nil {
+			log.Fatalf("failed to setup tracing: %v", err)
+		}
+		defer func() {
+			// ctx may already be cancelled at this point.
+			err := shutdown(context.Background())
+			if err != nil {
+				log.Printf("failed to flush traces: %v", err)
+			}
+		}()
+	}
 
-func collectArtifacts() ([]Artifact, error) {
-	log.Println("searching for artifacts with cdx sbom")
-	start := 0
-	artifacts := make([]Artifact, 0)
-	for {
-		g, err := searchArtifacts(150, start)
+	ctx, span := tracer.Start(ctx, "crawl")
+	defer span.End()
+
+	index := NewIndex()
+	catalog := NewCatalog()
+	budget := newDiskBudget(opts.MaxDiskBytes).withSBOMLimit(opts.MaxSBOMs, opts.MinUniqueGroups)
+
+	if opts.Checkpoint != "" {
+		err := budget.openCheckpoint(opts.Checkpoint)
 		if err != nil {
-			log.Fatalf("failed to search for artifacts: %v", err)
+			log.Fatalf("failed to open -checkpoint: %v", err)
 		}
-		if len(g) == 0 {
-			break
+		defer budget.Close()
+		if sboms := budget.SBOMs(); sboms > 0 {
+			log.Printf("resuming with %d of -max-sboms %d sboms from -checkpoint", sboms, opts.MaxSBOMs)
 		}
-		artifacts = append(artifacts, g...)
-		start += len(g)
 	}
-	log.Printf("no more search results")
-	return artifacts, nil
-}
 
-func searchArtifacts(rows, start int) ([]Artifact, error) {
-	log.Printf("fetching artifact search results %d - %d", start, start+rows)
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://search.maven.org/solrsearch/select?q=cyclonedx.json&rows=%d&start=%d&wt=json", rows, start), nil)
+	output, err := newOutput(opts)
 	if err != nil {
-		return nil, err
+		log.Fatalf("failed to setup output: %v", err)
 	}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	var fileNameTemplate *template.Template
+	if opts.FileNameTemplate != "" {
+		fileNameTemplate, err = parseFileNameTemplate(opts.FileNameTemplate)
+		if err != nil {
+			log.Fatalf("failed to parse -filename-template: %v", err)
+		}
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	var extracts *extractWriter
+	if opts.ExtractFile != "" {
+		extracts, err = newExtractWriter(opts.ExtractFile, opts.Extract)
+		if err != nil {
+			log.Fatalf("failed to create -extract-output: %v", err)
+		}
 	}
 
-	var resJSON ArtifactSearchResponse
-	err = json.NewDecoder(res.Body).Decode(&resJSON)
-	if err != nil {
-		return nil, err
+	var discards *discardsWriter
+	if opts.DiscardsFile != "" {
+		discards, err = newDiscardsWriter(opts.DiscardsFile)
+		if err != nil {
+			log.Fatalf("failed to open -discards-csv: %v", err)
+		}
 	}
 
-	artifacts := make([]Artifact, len(resJSON.Response.Docs))
-	for i := 0; i < len(resJSON.Response.Docs); i++ {
-		artifacts[i] = Artifact{
-			GroupID:       resJSON.Response.Docs[i].GroupID,
-			ArtifactID:    resJSON.Response.Docs[i].ArtifactID,
-			LatestVersion: resJSON.Response.Docs[i].LatestVersion,
+	serials := newSerialSet()
+	if opts.UniqueSerialIndex != "" {
+		prior, err := ReadIndexFile(opts.UniqueSerialIndex)
+		if err != nil {
+			log.Fatalf("failed to read -unique-serial-index: %v", err)
+		}
+		for _, serial := range prior.SerialNumbers() {
+			serials.Add(serial)
 		}
 	}
 
-	return artifacts, nil
-}
+	componentSets := newComponentSets()
+	var collected atomic.Int64
+
+	recordDiscarded := func(gav GAV, reason string, metrics map[string]any) {
+		if opts.IndexDiscarded {
+			index.AddDiscarded(DiscardedEntry{
+				GroupID:    gav.GroupID,
+				ArtifactID: gav.ArtifactID,
+				Version:    gav.Version,
+				Reason:     reason,
+				Metrics:    metrics,
+			})
+		}
+		if discards != nil {
+			if err := discards.Write(gav, reason, metrics); err != nil {
+				log.Printf("failed to record discarded sbom for %s in -discards-csv: %v", gav, err)
+			}
+		}
+	}
+
+	write := func(result Result) error {
+		if opts.UniqueSerial && !serials.Add(result.BOM.SerialNumber) {
+			log.Printf("discarding sbom for %s because its serial number %s was already seen", result.GAV, result.BOM.SerialNumber)
+			summary.AddDiscarded(discardDuplicateSerial)
+			recordDiscarded(result.GAV, discardDuplicateSerial, map[string]any{"serialNumber": result.BOM.SerialNumber})
+			return nil
+		}
+		var componentSet string
+		if opts.DedupComponentSet {
+			componentSet = componentSetHash(result.BOM)
+			if first, ok := componentSets.Add(componentSet, result.GAV); !ok {
+				log.Printf("discarding sbom for %s because it has the same components as the sbom for %s", result.GAV, first)
+				summary.AddDiscarded(discardDuplicateComponents)
+				index.AddAlias(AliasEntry{
+					GroupID:          result.GAV.GroupID,
+					ArtifactID:       result.GAV.ArtifactID,
+					Version:          result.GAV.Version,
+					AliasOf:          first.String(),
+					ComponentSetHash: componentSet,
+				})
+				if discards != nil {
+					if err := discards.Write(result.GAV, discardDuplicateComponents, map[string]any{"aliasOf": first.String()}); err != nil {
+						log.Printf("failed to record discarded sbom for %s in -discards-csv: %v", result.GAV, err)
+					}
+				}
+				return nil
+			}
+		}
+		if !budget.ReserveSBOM(result.GAV) {
+			debugf("discarding sbom for %s because -max-sboms was reached and its group is already represented", result.GAV)
+			summary.AddDiscarded(discardSBOMLimit)
+			recordDiscarded(result.GAV, discardSBOMLimit, map[string]any{"maxSBOMs": opts.MaxSBOMs})
+			return nil
+		}
+		// The SBOM only counts towards -max-sboms once it was written.
+		taken := false
+		defer func() {
+			if !taken {
+				budget.ReleaseSBOM(result.GAV)
+			}
+		}()
+		if opts.Filters.ToolVersions || opts.IndexFile != "" {
+			result.Tools = sbomTools(result.BOM)
+		}
+		var signed bool
+		if opts.FetchSignatures {
+			signed = hasEnvelopedSignature(&result)
+			if signed {
+				log.Printf("sbom for %s has an enveloped signature", result.GAV)
+			}
+		}
+		if extracts != nil {
+			if result.Format != cyclonedx.BOMFileFormatJSON {
+				debugf("not extracting values from sbom for %s because it's not json", result.GAV)
+			} else if err := extracts.Write(result.GAV, result.Raw); err != nil {
+				log.Printf("failed to extract values from sbom for %s: %v", result.GAV, err)
+			}
+		}
+		if opts.StatsOnly {
+			summary.AddCollected(result, opts.Filters.ToolVersions)
+			collected.Add(1)
+			budget.TakeSBOM(result.GAV)
+			taken = true
+			return nil
+		}
+
+		_, span := tracer.Start(ctx, "write", trace.WithAttributes(gavAttributes(result.GAV)...))
+		defer span.End()
+
+		data, dataHash := result.Raw, result.SHA256
+		modified := result.Modified
+		if opts.FlattenDependencies == flattenProperties {
+			flattenDependenciesToProperties(result.BOM)
+			modified = true
+		}
+		if opts.Compact || modified {
+			encoded, err := encodeBOM(result.BOM, result.Format, !opts.Compact)
+			if err != nil {
+				log.Printf("failed to encode sbom for %s: %v", result.GAV, err)
+				span.SetStatus(codes.Error, err.Error())
+				return err
+			}
+			data, dataHash = encoded, sha256Hex(encoded)
+		}
+		var canonicalHash string
+		if opts.Canonical {
+			if result.Format != cyclonedx.BOMFileFormatJSON {
+				debugf("not canonicalizing sbom for %s because it's not json", result.GAV)
+			} else {
+				canonical, err := canonicalizeJSON(data)
+				if err != nil {
+					log.Printf("failed to canonicalize sbom for %s: %v", result.GAV, err)
+					span.SetStatus(codes.Error, err.Error())
+					return err
+				}
+				data = canonical
+				canonicalHash = sha256Hex(data)
+				dataHash = canonicalHash
+			}
+		}
 
-func collectVersions(artifact Artifact) ([]GAV, error) {
-	log.Printf("searching for versions of %s with cdx sbom", artifact)
-	start := 0
-	gavs := make([]GAV, 0)
-	for {
-		g, err := searchVersions(artifact, 150, start)
+		var partition string
+		if opts.PartitionBy == partitionByLicense {
+			partition = licensePartition(result.BOM) + "/"
+		}
+		fileName := partition + sbomFileName(result.GAV, result.Format)
+		if fileNameTemplate != nil {
+			templated, err := templateFileName(fileNameTemplate, result, data)
+			if err != nil {
+				log.Printf("failed to determine file name for %s: %v", result.GAV, err)
+				span.SetStatus(codes.Error, err.Error())
+				return err
+			}
+			fileName = partition + templated
+		}
+		entry := IndexEntry{
+			GroupID:    result.GAV.GroupID,
+			ArtifactID: result.GAV.ArtifactID,
+			Version:    result.GAV.Version,
+			File:       fileName,
+			Size:       len(result.Raw),
+
+			Classifiers:  result.Classifiers,
+			SerialNumber: result.BOM.SerialNumber,
+			LinkedFrom:   result.LinkedFrom,
+			NonStrict:    result.NonStrict,
+			QualityScore: result.QualityScore,
+
+			VersionedRatio: result.VersionedRatio,
+			CPERatio:       result.CPERatio,
+
+			EnvelopedSignature: signed,
+
+			SpecInconsistencies: result.SpecInconsistencies,
+
+			ComponentCounts:         result.ComponentCounts,
+			OriginalComponentCounts: result.OriginalComponentCounts,
+			CollapsedDuplicates:     result.CollapsedDuplicates,
+			UnresolvablePurls:       result.UnresolvablePurls,
+			Tools:                   result.Tools,
+
+			ComponentSetHash: componentSet,
+			SHA256:           dataHash,
+			CanonicalSHA256:  canonicalHash,
+		}
+		if opts.Compact {
+			entry.CompactedSize = len(data)
+		}
+		if opts.IndexHeaders {
+			entry.Headers = &result.Headers
+		}
+
+		archive, err := output.Write(fileName, data)
+		if errors.Is(err, errFileExists) {
+			// The SBOM isn't counted as collected, but the existing file stays in the index.
+			log.Printf("not overwriting existing sbom for %s because of -no-clobber", result.GAV)
+			if existing, err := os.ReadFile(filepath.Join(opts.OutputDir, filepath.FromSlash(fileName))); err == nil {
+				entry.SHA256 = sha256Hex(existing)
+				index.Add(entry)
+			}
+			return nil
+		}
 		if err != nil {
-			log.Fatalf("failed to search for versions of %s: %v", artifact, err)
+			log.Printf("failed to write sbom for %s: %v", result.GAV, err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		span.SetAttributes(attribute.Int("size", len(data)))
+		summary.AddWritten()
+		summary.AddCollected(result, opts.Filters.ToolVersions)
+		collected.Add(1)
+		budget.TakeSBOM(result.GAV)
+		taken = true
+		budget.Add(len(data))
+
+		var detachedSignature string
+		if opts.FetchSignatures && result.URL != "" {
+			signature, err := downloadDetachedSignature(ctx, repo, result.GAV, result.URL)
+			if err == nil && signature != nil {
+				detachedSignature = fileName + detachedSignatureSuffix
+				_, err = output.Write(detachedSignature, signature)
+				if err != nil {
+					detachedSignature = ""
+				} else {
+					budget.Add(len(signature))
+				}
+			}
+			if err != nil {
+				log.Printf("failed to fetch detached signature for %s: %v", result.GAV, err)
+			}
 		}
-		if len(g) == 0 {
-			break
+		if opts.FetchSignatures {
+			summary.AddSignatures(signed, detachedSignature != "")
 		}
-		gavs = append(gavs, g...)
-		start += len(g)
-	}
-	log.Printf("no more versions of %s", artifact)
-	return gavs, nil
-}
 
-func searchVersions(artifact Artifact, rows, start int) ([]GAV, error) {
-	log.Printf("fetching version search results for %s: %d - %d", artifact, start, start+rows)
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://search.maven.org/solrsearch/select?q=g:%s+AND+a:%s&core=gav&rows=%d&start=%d&wt=json", artifact.GroupID, artifact.ArtifactID, rows, start), nil)
-	if err != nil {
-		return nil, err
-	}
+		entry.Archive, entry.DetachedSignature = archive, detachedSignature
+		index.Add(entry)
+		if opts.CatalogFile != "" {
+			catalog.Add(result)
+		}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
+		if opts.WriteSidecarMeta {
+			meta, err := encodeSidecarMeta(result, data)
+			if err == nil {
+				_, err = output.Write(sidecarFileName(fileName), meta)
+			}
+			if err != nil {
+				log.Printf("failed to write sidecar metadata for %s: %v", result.GAV, err)
+			} else {
+				budget.Add(len(meta))
+			}
+		}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
-	}
+		if opts.FlattenDependencies == flattenEdges {
+			edges, err := encodeDependencyEdges(result.BOM)
+			if err == nil {
+				_, err = output.Write(partition+edgesFileName(result.GAV), edges)
+			}
+			if err != nil {
+				log.Printf("failed to write dependency edges for %s: %v", result.GAV, err)
+			} else {
+				budget.Add(len(edges))
+			}
+		}
 
-	var resJSON VersionSearchResponse
-	err = json.NewDecoder(res.Body).Decode(&resJSON)
-	if err != nil {
-		return nil, err
+		if opts.WithPOM {
+			pom, err := downloadPOM(ctx, repo, result.GAV)
+			if err != nil {
+				log.Printf("failed to download pom for %s: %v", result.GAV, err)
+				return nil
+			}
+			if pom != nil {
+				_, err = output.Write(partition+pomFileName(result.GAV), pom)
+				if err != nil {
+					log.Printf("failed to write pom for %s: %v", result.GAV, err)
+					return nil
+				}
+				budget.Add(len(pom))
+			}
+		}
+
+		return nil
 	}
 
-	gavs := make([]GAV, 0)
-	for i := 0; i < len(resJSON.Response.Docs); i++ {
-		doc := resJSON.Response.Docs[i]
-		if contains(doc.EC, "-cyclonedx.json") {
-			gavs = append(gavs, GAV{
-				GroupID:    doc.GroupID,
-				ArtifactID: doc.ArtifactID,
-				Version:    doc.Version,
-			})
+	safeWrite := func(result Result) {
+		err := safely(result.GAV, summary, func() error {
+			return write(result)
+		})
+		if err != nil {
+			summary.AddFailed()
+			failFast(result.GAV, err)
 		}
 	}
 
-	return gavs, nil
-}
+	// In deterministic mode, SBOMs are held in memory until the crawl
+	// completes, and written in GAV order afterwards.
+	var (
+		buffered    []Result
+		bufferedMux sync.Mutex
+	)
 
-func downloadSBOM(gav GAV, minComponents int, outputDir string) error {
-	log.Printf("downloading sbom for %s", gav)
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://repo1.maven.org/maven2/%s/%s/%s/%s-%s-cyclonedx.json", strings.ReplaceAll(gav.GroupID, ".", "/"), gav.ArtifactID, gav.Version, gav.ArtifactID, gav.Version), nil)
-	if err != nil {
-		return err
+	var source Source = NewCrawler(repo, opts, summary, budget)
+	if path, ok := opts.archiveSource(); ok {
+		source = NewArchiveSource(path, opts, summary, budget)
 	}
+	results := source.Stream(ctx)
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	wg := sync.WaitGroup{}
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for result := range results {
+				if result.Err != nil {
+					if errors.Is(result.Err, context.Canceled) {
+						continue
+					}
+					if result.GAV == (GAV{}) {
+						log.Printf("%v", result.Err)
+					} else {
+						log.Printf("failed to download sbom for %s: %v", result.GAV, result.Err)
+					}
+					summary.AddFailed()
+					if !errors.Is(result.Err, errSBOMNotFound) {
+						failFast(result.GAV, result.Err)
+					}
+					continue
+				}
+				if result.Discard != nil {
+					recordDiscarded(result.GAV, result.Discard.Reason, result.Discard.Metrics)
+					continue
+				}
+
+				if opts.DeterministicOrder {
+					bufferedMux.Lock()
+					buffered = append(buffered, result)
+					bufferedMux.Unlock()
+					continue
+				}
+
+				safeWrite(result)
+			}
+		}()
 	}
-	defer res.Body.Close()
+	wg.Wait()
 
-	resBytes, err := io.ReadAll(res.Body)
-	if err != nil {
-		return err
+	interrupted := ctx.Err() != nil && !failedFast
+	if interrupted {
+		log.Printf("crawl was interrupted")
 	}
 
-	var sbom cyclonedx.BOM
-	err = cyclonedx.NewBOMDecoder(bytes.NewReader(resBytes), cyclonedx.BOMFileFormatJSON).Decode(&sbom)
-	if err != nil {
-		return err
+	if opts.DeterministicOrder {
+		sort.Slice(buffered, func(i, j int) bool {
+			return buffered[i].GAV.Less(buffered[j].GAV)
+		})
+		for _, result := range buffered {
+			safeWrite(result)
+		}
 	}
 
-	componentCount := 0
-	if sbom.Components != nil {
-		componentCount = len(*sbom.Components)
+	err = output.Close()
+	if err != nil {
+		log.Printf("failed to close output: %v", err)
 	}
-	if componentCount < minComponents {
-		log.Printf("discarding sbom for %s because it has too few components (%d/%d)", gav, componentCount, minComponents)
-		return nil
+
+	if opts.IndexFile != "" {
+		err = index.WriteFile(opts.IndexFile, opts.IndexFormat)
+		if err != nil {
+			log.Printf("failed to write index: %v", err)
+		}
 	}
 
-	fileName := fmt.Sprintf("%s_%s_%s.cdx.json", gav.GroupID, gav.ArtifactID, gav.Version)
-	f, err := os.Create(filepath.Join(outputDir, fileName))
-	if err != nil {
-		return err
+	if extracts != nil {
+		err = extracts.Close()
+		if err != nil {
+			log.Printf("failed to close -extract-output: %v", err)
+		}
 	}
-	defer f.Close()
 
-	_, err = f.Write(resBytes)
-	if err != nil {
-		return err
+	if discards != nil {
+		err = discards.Close()
+		if err != nil {
+			log.Printf("failed to close -discards-csv: %v", err)
+		}
 	}
 
-	return nil
-}
+	if opts.CatalogFile != "" {
+		err = catalog.WriteFile(opts.CatalogFile)
+		if err != nil {
+			log.Printf("failed to write catalog: %v", err)
+		}
+	}
 
-func contains(haystack []string, needle string) bool {
-	for _, candidate := range haystack {
-		if candidate == needle {
-			return true
+	if artifactLogs != nil {
+		artifactLogs.Close()
+	}
+	if opts.MaxSBOMs > 0 {
+		summary.SetUniqueGroups(budget.Groups())
+	}
+	summary.Log()
+	if opts.SummaryFile != "" {
+		err = summary.WriteReport(opts.SummaryFile)
+		if err != nil {
+			log.Printf("failed to write -summary-file: %v", err)
 		}
 	}
+	if opts.DedupComponentSet {
+		sboms, sets := componentSets.Collapsed()
+		log.Printf("summary: collapsed %d sboms into %d distinct component sets", sboms, sets)
+	}
 
-	return false
+	// An empty result is only meaningful if the crawl completed without failures.
+	_, failed, _ := summary.requestCounts()
+	if failedFast {
+		exitCode = 1
+	} else if collected.Load() == 0 && !interrupted && failed == 0 {
+		log.Printf("no sboms were collected, exiting with code %d", opts.EmptyExitCode)
+		exitCode = opts.EmptyExitCode
+	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Downloads are held back while the heap exceeds memoryHighWater of -mem-limit,
+// and the heap is checked again every memoryPollInterval.
+const memoryHighWater = 0.8
+
+var memoryPollInterval = 100 * time.Millisecond
+
+// memoryThrottle holds back downloads while the heap is close to -mem-limit,
+// so that many concurrent downloads of large SBOMs don't exceed it.
+// One download may always proceed, so that the crawl never stalls.
+// A nil memoryThrottle doesn't throttle. It is safe for concurrent use.
+type memoryThrottle struct {
+	mux        sync.Mutex
+	threshold  uint64
+	active     int
+	throttling bool
+
+	// heapBytes returns the current size of the heap. It's a field so tests can replace it.
+	heapBytes func() uint64
+}
+
+func newMemoryThrottle(limit int64) *memoryThrottle {
+	if limit <= 0 {
+		return nil
+	}
+	return &memoryThrottle{
+		threshold: uint64(float64(limit) * memoryHighWater),
+		heapBytes: heapBytes,
+	}
+}
+
+// Acquire blocks until a download may proceed, or ctx is done.
+// Every successful call must be followed by a call to Release.
+func (t *memoryThrottle) Acquire(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	for {
+		t.mux.Lock()
+		heap := t.heapBytes()
+		if t.active == 0 || heap < t.threshold {
+			if t.throttling {
+				log.Printf("resuming downloads, the heap shrank to %d bytes", heap)
+				t.throttling = false
+			}
+			t.active++
+			t.mux.Unlock()
+			return nil
+		}
+		if !t.throttling {
+			log.Printf("throttling downloads because the heap is close to -mem-limit (%d/%d bytes)", heap, t.threshold)
+			t.throttling = true
+		}
+		t.mux.Unlock()
+
+		if err := sleepContext(ctx, memoryPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *memoryThrottle) Release() {
+	if t == nil {
+		return
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.active--
+}
+
+// heapBytes returns the memory occupied by live and not yet collected heap objects.
+// Unlike runtime.ReadMemStats, it doesn't stop the world.
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
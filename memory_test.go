@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryThrottle(t *testing.T) {
+	defer func(interval time.Duration) { memoryPollInterval = interval }(memoryPollInterval)
+	memoryPollInterval = time.Millisecond
+
+	if newMemoryThrottle(0) != nil {
+		t.Fatal("expected no throttle without limit")
+	}
+
+	var heap atomic.Uint64
+	heap.Store(900)
+	throttle := newMemoryThrottle(1000)
+	throttle.heapBytes = heap.Load
+
+	// One download may always proceed.
+	if err := throttle.Acquire(context.Background()); err != nil {
+		t.Fatalf("expected first download to proceed, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := throttle.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected second download to be held back, got %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- throttle.Acquire(context.Background())
+	}()
+	heap.Store(500)
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("expected second download to proceed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected second download to proceed once the heap shrank")
+	}
+
+	throttle.Release()
+	throttle.Release()
+	heap.Store(900)
+	if err := throttle.Acquire(context.Background()); err != nil {
+		t.Fatalf("expected download to proceed once no other is active, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+const mergedRootRef = "merged-root"
+
+func runMerge(args []string) {
+	var (
+		outputFile string
+		name       string
+		version    string
+	)
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s merge:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "  %s merge [flags] FILE...\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&outputFile, "output", "-", "Output file (\"-\" for stdout)")
+	fs.StringVar(&name, "name", "merged", "Name of the synthesized root component")
+	fs.StringVar(&version, "version", "", "Version of the synthesized root component")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	boms := make([]*cyclonedx.BOM, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		bom, err := decodeBOMFile(path)
+		if err != nil {
+			log.Fatalf("failed to decode %s: %v", path, err)
+		}
+		boms = append(boms, bom)
+	}
+
+	merged := mergeBOMs(boms, &cyclonedx.Component{
+		BOMRef:  mergedRootRef,
+		Type:    cyclonedx.ComponentTypeApplication,
+		Name:    name,
+		Version: version,
+	})
+
+	encode := func(w io.Writer) error {
+		return cyclonedx.NewBOMEncoder(w, cyclonedx.BOMFileFormatJSON).SetPretty(true).Encode(merged)
+	}
+	var err error
+	if outputFile == "-" {
+		err = encode(os.Stdout)
+	} else {
+		err = writeFileAtomic(outputFile, false, encode)
+	}
+	if err != nil {
+		log.Fatalf("failed to write merged bom: %v", err)
+	}
+}
+
+// decodeBOMFile decodes the SBOM at path the same way the crawler decodes downloaded SBOMs
+// (see decodeSBOM), in the format its suffix suggests.
+func decodeBOMFile(path string) (*cyclonedx.BOM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var bom cyclonedx.BOM
+	if _, _, _, err := decodeSBOM(data, suffixFormat(path), false, false, &bom); err != nil {
+		return nil, err
+	}
+
+	return &bom, nil
+}
+
+// mergeBOMs aggregates multiple BOMs into a single one with root as its subject.
+//
+// Top-level components are deduplicated by purl and version. The root components
+// of all inputs are added as components, and root depends on each of them.
+// BOMs are processed in the given order: the first occurrence of a bom-ref keeps it,
+// later colliding bom-refs are rebased by prefixing them with the index of their input.
+// Refs in dependencies that don't belong to a component, e.g. those of services,
+// are rebased the same way, so that they can't collide with refs of other inputs.
+func mergeBOMs(boms []*cyclonedx.BOM, root *cyclonedx.Component) *cyclonedx.BOM {
+	m := bomMerger{
+		refs:       map[string]bool{root.BOMRef: true},
+		purlRefs:   make(map[string]string),
+		components: make([]cyclonedx.Component, 0),
+		deps:       make(map[string]map[string]bool),
+	}
+
+	rootDeps := make(map[string]bool)
+	for i, bom := range boms {
+		refMap := make(map[string]string)
+
+		if bom.Metadata != nil && bom.Metadata.Component != nil {
+			if ref := m.addComponent(i, *bom.Metadata.Component, refMap); ref != "" {
+				rootDeps[ref] = true
+			}
+		}
+		if bom.Components != nil {
+			for _, component := range *bom.Components {
+				m.addComponent(i, component, refMap)
+			}
+		}
+		if bom.Dependencies != nil {
+			for _, dep := range *bom.Dependencies {
+				ref := m.rebaseRef(i, dep.Ref, refMap)
+				if _, ok := m.deps[ref]; !ok {
+					m.deps[ref] = make(map[string]bool)
+				}
+				if dep.Dependencies != nil {
+					for _, dependsOn := range *dep.Dependencies {
+						m.deps[ref][m.rebaseRef(i, dependsOn, refMap)] = true
+					}
+				}
+			}
+		}
+	}
+	m.deps[root.BOMRef] = rootDeps
+
+	merged := cyclonedx.NewBOM()
+	merged.Metadata = &cyclonedx.Metadata{
+		Component: root,
+	}
+	merged.Components = &m.components
+
+	refs := make([]string, 0, len(m.deps))
+	for ref := range m.deps {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	dependencies := make([]cyclonedx.Dependency, 0, len(refs))
+	for _, ref := range refs {
+		dependsOn := make([]string, 0, len(m.deps[ref]))
+		for d := range m.deps[ref] {
+			dependsOn = append(dependsOn, d)
+		}
+		sort.Strings(dependsOn)
+		dependencies = append(dependencies, cyclonedx.Dependency{
+			Ref:          ref,
+			Dependencies: &dependsOn,
+		})
+	}
+	merged.Dependencies = &dependencies
+
+	return merged
+}
+
+type bomMerger struct {
+	refs       map[string]bool   // bom-refs in use
+	purlRefs   map[string]string // purl+version -> bom-ref
+	components []cyclonedx.Component
+	deps       map[string]map[string]bool
+}
+
+// addComponent adds component, which originates from the input with index i,
+// unless an equivalent component has already been added.
+// Mappings from original to rebased bom-refs are recorded in refMap.
+// It returns the (possibly rebased) bom-ref of the component.
+func (m *bomMerger) addComponent(i int, component cyclonedx.Component, refMap map[string]string) string {
+	key := ""
+	if component.PackageURL != "" {
+		key = component.PackageURL + "@" + component.Version
+		if ref, ok := m.purlRefs[key]; ok {
+			if component.BOMRef != "" {
+				refMap[component.BOMRef] = ref
+			}
+			return ref
+		}
+	}
+
+	m.rebaseRefs(i, &component, refMap)
+	if key != "" {
+		m.purlRefs[key] = component.BOMRef
+	}
+	m.components = append(m.components, component)
+
+	return component.BOMRef
+}
+
+// rebaseRefs assigns collision-free bom-refs to component and all of its
+// nested components.
+func (m *bomMerger) rebaseRefs(i int, component *cyclonedx.Component, refMap map[string]string) {
+	if component.BOMRef != "" {
+		ref := m.allocateRef(i, component.BOMRef)
+		refMap[component.BOMRef] = ref
+		component.BOMRef = ref
+	}
+
+	if component.Components != nil {
+		nested := make([]cyclonedx.Component, len(*component.Components))
+		copy(nested, *component.Components)
+		for j := range nested {
+			m.rebaseRefs(i, &nested[j], refMap)
+		}
+		component.Components = &nested
+	}
+}
+
+// rebaseRef returns the rebased bom-ref of ref, which originates from the input with index i.
+// Refs that weren't rebased along with a component are rebased on first use.
+func (m *bomMerger) rebaseRef(i int, ref string, refMap map[string]string) string {
+	if rebased, ok := refMap[ref]; ok {
+		return rebased
+	}
+	rebased := m.allocateRef(i, ref)
+	refMap[ref] = rebased
+	return rebased
+}
+
+// allocateRef returns ref, or a variant of it prefixed with the index of its input
+// if it's already in use, and marks the returned bom-ref as in use.
+func (m *bomMerger) allocateRef(i int, ref string) string {
+	allocated := ref
+	for n := 0; m.refs[allocated]; n++ {
+		if n == 0 {
+			allocated = fmt.Sprintf("%d:%s", i, ref)
+		} else {
+			allocated = fmt.Sprintf("%d-%d:%s", i, n, ref)
+		}
+	}
+	m.refs[allocated] = true
+	return allocated
+}
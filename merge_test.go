@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestMergeBOMs(t *testing.T) {
+	testCases := []struct {
+		name         string
+		boms         []*cyclonedx.BOM
+		components   []string // bom-refs of the merged components, including nested ones, in order
+		dependencies map[string][]string
+	}{
+		{
+			name: "DuplicateComponents",
+			boms: []*cyclonedx.BOM{
+				{
+					Components:   &[]cyclonedx.Component{{BOMRef: "a", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"}},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: "a"}},
+				},
+				{
+					Components: &[]cyclonedx.Component{
+						{BOMRef: "other-a", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"},
+						{BOMRef: "b", PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"},
+					},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: "b", Dependencies: &[]string{"other-a"}}},
+				},
+			},
+			components: []string{"a", "b"},
+			dependencies: map[string][]string{
+				mergedRootRef: {},
+				"a":           {},
+				"b":           {"a"},
+			},
+		},
+		{
+			name: "RefCollision",
+			boms: []*cyclonedx.BOM{
+				{
+					Components: &[]cyclonedx.Component{
+						{BOMRef: "lib", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"},
+						{BOMRef: "dep", PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"},
+					},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: "lib", Dependencies: &[]string{"dep"}}},
+				},
+				{
+					Components: &[]cyclonedx.Component{
+						{BOMRef: "lib", PackageURL: "pkg:maven/org.example/c@1.0.0", Version: "1.0.0"},
+						{BOMRef: "dep", PackageURL: "pkg:maven/org.example/d@1.0.0", Version: "1.0.0"},
+					},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: "lib", Dependencies: &[]string{"dep"}}},
+				},
+			},
+			components: []string{"lib", "dep", "1:lib", "1:dep"},
+			dependencies: map[string][]string{
+				mergedRootRef: {},
+				"lib":         {"dep"},
+				"1:lib":       {"1:dep"},
+			},
+		},
+		{
+			name: "RepeatedRefCollision",
+			boms: []*cyclonedx.BOM{
+				{Components: &[]cyclonedx.Component{{BOMRef: "1:lib", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"}}},
+				{Components: &[]cyclonedx.Component{
+					{BOMRef: "lib", PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"},
+					{BOMRef: "nested", Components: &[]cyclonedx.Component{{BOMRef: "lib", Name: "c"}}},
+				}},
+			},
+			components: []string{"1:lib", "lib", "nested", "1-1:lib"},
+			dependencies: map[string][]string{
+				mergedRootRef: {},
+			},
+		},
+		{
+			name: "RootRefCollision",
+			boms: []*cyclonedx.BOM{
+				{
+					Metadata:     &cyclonedx.Metadata{Component: &cyclonedx.Component{BOMRef: mergedRootRef, Name: "app"}},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: mergedRootRef}},
+				},
+			},
+			components: []string{"0:" + mergedRootRef},
+			dependencies: map[string][]string{
+				mergedRootRef:        {"0:" + mergedRootRef},
+				"0:" + mergedRootRef: {},
+			},
+		},
+		{
+			name: "RefsWithoutComponent",
+			boms: []*cyclonedx.BOM{
+				{
+					Components:   &[]cyclonedx.Component{{BOMRef: "app", PackageURL: "pkg:maven/org.example/a@1.0.0", Version: "1.0.0"}},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: "app", Dependencies: &[]string{"service"}}},
+				},
+				{
+					Components:   &[]cyclonedx.Component{{BOMRef: "other", PackageURL: "pkg:maven/org.example/b@1.0.0", Version: "1.0.0"}},
+					Dependencies: &[]cyclonedx.Dependency{{Ref: "service", Dependencies: &[]string{"other"}}},
+				},
+			},
+			components: []string{"app", "other"},
+			dependencies: map[string][]string{
+				mergedRootRef: {},
+				"app":         {"service"},
+				"1:service":   {"other"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeBOMs(tc.boms, &cyclonedx.Component{BOMRef: mergedRootRef, Name: "merged"})
+
+			var components []string
+			var collect func([]cyclonedx.Component)
+			collect = func(nested []cyclonedx.Component) {
+				for _, component := range nested {
+					components = append(components, component.BOMRef)
+					if component.Components != nil {
+						collect(*component.Components)
+					}
+				}
+			}
+			collect(*merged.Components)
+			if !reflect.DeepEqual(components, tc.components) {
+				t.Errorf("expected components %v, got %v", tc.components, components)
+			}
+
+			dependencies := make(map[string][]string)
+			for _, dep := range *merged.Dependencies {
+				dependencies[dep.Ref] = *dep.Dependencies
+			}
+			if !reflect.DeepEqual(dependencies, tc.dependencies) {
+				t.Errorf("expected dependencies %v, got %v", tc.dependencies, dependencies)
+			}
+		})
+	}
+}
+
+func TestDecodeBOMFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.cdx.json")
+	data := `{"bomFormat": "CycloneDX", "specVersion": "1.5", "metadata": {"tools": {"components": [{"name": "a"}]}}, "components": [{"type": "library", "name": "b"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bom, err := decodeBOMFile(path)
+	if err != nil {
+		t.Fatalf("failed to decode a 1.5 sbom: %v", err)
+	}
+	if bom.SpecVersion != cyclonedx.SpecVersion1_4 || bom.Components == nil || len(*bom.Components) != 1 {
+		t.Errorf("unexpected sbom: %+v", bom)
+	}
+	if bom.Metadata == nil || bom.Metadata.Tools == nil || len(*bom.Metadata.Tools) != 1 {
+		t.Errorf("expected the tools to be decoded, got %+v", bom.Metadata)
+	}
+}
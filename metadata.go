@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Sources of the versions of an artifact, for -version-source.
+const (
+	versionSourceSolr     = "solr"
+	versionSourceMetadata = "metadata"
+)
+
+// mavenMetadata is the part of an artifact's maven-metadata.xml that lists its versions.
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+var errMetadataNotFound = errors.New("no maven-metadata.xml found")
+
+// metadataURL returns the URL of the maven-metadata.xml of artifact.
+func (r Repository) metadataURL(artifact Artifact) string {
+	return fmt.Sprintf("%s/%s/%s/maven-metadata.xml", r.BaseURL, strings.ReplaceAll(artifact.GroupID, ".", "/"), artifact.ArtifactID)
+}
+
+// collectMetadataVersions enumerates the versions of artifact listed in its maven-metadata.xml.
+// Unlike the Solr search, the metadata doesn't tell which versions have an SBOM,
+// so the returned matches have no classifiers.
+// If newerThan is not empty, only versions newer than it according to cmp are returned.
+func collectMetadataVersions(ctx context.Context, repo Repository, artifact Artifact, newerThan string, cmp VersionComparator) ([]VersionMatch, error) {
+	log.Printf("fetching maven-metadata.xml of %s", artifact)
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	metadataURL := repo.metadataURL(artifact)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := repo.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch maven-metadata.xml of %s: %w", artifact, err)
+	}
+	defer res.Body.Close()
+
+	debugf("GET %s: %s", metadataURL, res.Status)
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w for %s", errMetadataNotFound, artifact)
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch maven-metadata.xml of %s: unexpected status code: %d", artifact, res.StatusCode)
+	}
+
+	var metadata mavenMetadata
+	err = xml.NewDecoder(res.Body).Decode(&metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse maven-metadata.xml of %s: %w", artifact, err)
+	}
+
+	seen := make(map[string]bool, len(metadata.Versions))
+	matches := make([]VersionMatch, 0, len(metadata.Versions))
+	for _, version := range metadata.Versions {
+		version = strings.TrimSpace(version)
+		if version == "" || seen[version] {
+			continue
+		}
+		seen[version] = true
+		matches = append(matches, VersionMatch{GAV: GAV{GroupID: artifact.GroupID, ArtifactID: artifact.ArtifactID, Version: version}})
+	}
+	log.Printf("found %d versions of %s in maven-metadata.xml", len(matches), artifact)
+
+	return newerVersions(matches, artifact, newerThan, cmp), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCollectMetadataVersions(t *testing.T) {
+	repo := newFixtureRepository(t, map[string]fixture{
+		"org/example/lib/maven-metadata.xml": {body: `<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>org.example</groupId>
+  <artifactId>lib</artifactId>
+  <versioning>
+    <latest>2.0.0</latest>
+    <release>2.0.0</release>
+    <versions>
+      <version>1.0.0</version>
+      <version>1.1.0</version>
+      <version>2.0.0</version>
+    </versions>
+  </versioning>
+</metadata>`},
+	}, nil)
+
+	testCases := []struct {
+		name      string
+		artifact  Artifact
+		newerThan string
+		want      []string
+		wantErr   error
+	}{
+		{"AllVersions", Artifact{GroupID: "org.example", ArtifactID: "lib"}, "", []string{"1.0.0", "1.1.0", "2.0.0"}, nil},
+		{"NewerThan", Artifact{GroupID: "org.example", ArtifactID: "lib"}, "1.0.0", []string{"1.1.0", "2.0.0"}, nil},
+		{"NotFound", Artifact{GroupID: "org.example", ArtifactID: "other"}, "", nil, errMetadataNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := collectMetadataVersions(context.Background(), repo, tc.artifact, tc.newerThan, MavenVersionComparator{})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			var versions []string
+			for _, match := range matches {
+				versions = append(versions, match.GAV.Version)
+			}
+			if !reflect.DeepEqual(versions, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, versions)
+			}
+		})
+	}
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options holds the configuration of a crawl.
+type Options struct {
+	Concurrency          int
+	AutoConcurrency      bool
+	MaxConcurrency       int
+	OutputDir            string
+	NoClobber            bool
+	FileNameTemplate     string
+	PartitionBy          string
+	Archive              string
+	ArchiveMaxEntries    int
+	ArchiveMaxBytes      int64
+	IndexFile            string
+	IndexFormat          string
+	IndexHeaders         bool
+	IndexDiscarded       bool
+	CatalogFile          string
+	SummaryFile          string
+	DiscardsFile         string
+	Extract              []string
+	ExtractFile          string
+	DeterministicOrder   bool
+	RandomOrder          bool
+	Seed                 int64
+	WithPOM              bool
+	FetchSignatures      bool
+	WriteSidecarMeta     bool
+	StatsOnly            bool
+	Compact              bool
+	Canonical            bool
+	FlattenDependencies  string
+	Probe                string
+	DiscoverClassifiers  bool
+	RegistryIndex        string
+	FailFast             bool
+	LogByArtifact        bool
+	EmptyExitCode        int
+	QueueFile            string
+	Source               string
+	ForceHTTP1           bool
+	HTTPCache            string
+	CacheTTL             time.Duration
+	Headers              []string
+	AllowedHosts         []string
+	FallbackBaseURLs     []string
+	OTelEndpoint         string
+	SearchTimeout        time.Duration
+	DownloadTimeout      time.Duration
+	RetryBaseDelay       time.Duration
+	RetryMaxDelay        time.Duration
+	RetryJitter          string
+	GAVBudget            time.Duration
+	ArtifactDeadline     time.Duration
+	StuckTimeout         time.Duration
+	CancelStuck          bool
+	SBOMSuffixes         []string
+	RetryAsXML           bool
+	GroupPrefixes        []string
+	SearchConcurrency    int
+	IncludeGroupsFile    string
+	ExcludeGroupsFile    string
+	SkipListFile         string
+	NewerThanIndex       string
+	UniqueSerial         bool
+	UniqueSerialIndex    string
+	DedupComponentSet    bool
+	VerifyPurlResolvable bool
+	ValidateTimestamps   bool
+	VerifyPurlsSample    int
+	Filters              Filters
+	MaxPerGroup          int
+	FollowBOMRefs        int
+	MaxLinkedBOMs        int
+	MaxDiskBytes         int64
+	MemLimit             int64
+	MaxSBOMs             int
+	VersionSource        string
+	MinUniqueGroups      int
+	Checkpoint           string
+
+	// Populated from IncludeGroupsFile, ExcludeGroupsFile, SkipListFile and NewerThanIndex.
+	IncludeGroups  GroupList
+	ExcludeGroups  GroupList
+	SkipList       *SkipList
+	LatestVersions map[string]string
+
+	// VersionComparator orders versions. It can't be configured via flags,
+	// and defaults to MavenVersionComparator.
+	VersionComparator VersionComparator
+}
+
+// Filters controls which downloaded SBOMs are kept.
+type Filters struct {
+	MinComponents        int
+	MinBytes             int
+	MaxBytes             int
+	CountNested          bool
+	ComponentCountSource string
+	RequireRootComponent bool
+	SampleRate           float64
+	SampleSeed           int64
+	PurlTypes            []string
+	DiscardEmpty         bool
+	Scopes               []string
+	ComponentTypes       []string
+	DiscardAllExcluded   bool
+	Properties           []string
+	RequiredProperties   []string
+	ContainsGroups       []string
+	MinTransitiveRatio   float64
+	MinVersionedRatio    float64
+	RequireCPE           bool
+	MinCPERatio          float64
+	MinQualityScore      float64
+	StripPurlQualifiers  bool
+	DedupComponents      bool
+	KeepRawPurls         bool
+	Lenient              bool
+	StrictDecode         bool
+	DecodeOnlyMetadata   bool
+	CheckSpecConsistency bool
+	ToolVersions         bool
+	MaxDecodes           int
+	MinSpecVersion       string
+
+	// decodeSlots is shared by all workers of a source to enforce MaxDecodes.
+	// It's populated by NewCrawler and NewArchiveSource.
+	decodeSlots decodeSlots
+
+	// Custom filters run right after the built-in ones that are implemented as Filters
+	// (see builtinFilters). They can't be configured via flags.
+	Custom []Filter
+}
+
+const xmlFallbackSuffix = "-cyclonedx.xml"
+
+// sbomSuffixes returns the suffixes to try when downloading SBOMs, in order.
+func (o Options) sbomSuffixes() []string {
+	if !o.RetryAsXML || contains(o.SBOMSuffixes, xmlFallbackSuffix) {
+		return o.SBOMSuffixes
+	}
+
+	return append(append([]string{}, o.SBOMSuffixes...), xmlFallbackSuffix)
+}
+
+// versionComparator returns the VersionComparator to order versions with.
+func (o Options) versionComparator() VersionComparator {
+	if o.VersionComparator == nil {
+		return MavenVersionComparator{}
+	}
+	return o.VersionComparator
+}
+
+// stdoutOutput is the -output value for writing the SBOM of a probe to stdout.
+const stdoutOutput = "-"
+
+// archiveSource returns the path of the archive to read SBOMs from,
+// if -source refers to an archive.
+func (o Options) archiveSource() (string, bool) {
+	return strings.CutPrefix(o.Source, archiveSourcePrefix)
+}
+
+// yieldDiscarded reports whether discarded SBOMs are yielded by crawls,
+// so that they can be recorded in the index or in the -discards-csv file.
+func (o Options) yieldDiscarded() bool {
+	return o.IndexDiscarded || o.DiscardsFile != ""
+}
+
+// metadataOnly reports whether only the metadata of JSON SBOMs is to be decoded.
+// Besides -decode-only-metadata, this is the case with -extract, which reads values
+// from the raw bytes, unless other flags need the components, or record metrics of them.
+func (o Options) metadataOnly() bool {
+	return o.Filters.DecodeOnlyMetadata || len(o.Extract) > 0 && len(o.componentFlags()) == 0 && len(o.componentMetricFlags()) == 0
+}
+
+// Validate checks for invalid values and incompatible combinations of options.
+func (o Options) Validate() error {
+	if o.Concurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1, but is %d", o.Concurrency)
+	}
+	if o.AutoConcurrency && o.MaxConcurrency < 1 {
+		return fmt.Errorf("-max-concurrency must be at least 1, but is %d", o.MaxConcurrency)
+	}
+	if o.SearchConcurrency < 1 {
+		return fmt.Errorf("-search-concurrency must be at least 1, but is %d", o.SearchConcurrency)
+	}
+	if o.SearchTimeout <= 0 || o.DownloadTimeout <= 0 {
+		return errors.New("-search-timeout and -download-timeout must be positive")
+	}
+	if o.RetryBaseDelay <= 0 {
+		return fmt.Errorf("-retry-base-delay must be positive, but is %s", o.RetryBaseDelay)
+	}
+	if o.RetryMaxDelay < o.RetryBaseDelay {
+		return fmt.Errorf("-retry-max-delay (%s) must not be less than -retry-base-delay (%s)", o.RetryMaxDelay, o.RetryBaseDelay)
+	}
+	if o.RetryJitter != retryJitterFull && o.RetryJitter != retryJitterNone {
+		return fmt.Errorf("-retry-jitter must be %q or %q, but is %q", retryJitterFull, retryJitterNone, o.RetryJitter)
+	}
+	if o.GAVBudget < 0 {
+		return fmt.Errorf("-gav-budget must not be negative, but is %s", o.GAVBudget)
+	}
+	if o.StuckTimeout < 0 {
+		return fmt.Errorf("-stuck-timeout must not be negative, but is %s", o.StuckTimeout)
+	}
+	if o.CancelStuck && o.StuckTimeout == 0 {
+		return errors.New("-cancel-stuck requires -stuck-timeout")
+	}
+	if o.ArtifactDeadline < 0 {
+		return fmt.Errorf("-artifact-deadline must not be negative, but is %s", o.ArtifactDeadline)
+	}
+	if o.Filters.MaxDecodes < 0 {
+		return fmt.Errorf("-max-decodes must not be negative, but is %d", o.Filters.MaxDecodes)
+	}
+	if o.Filters.MinBytes < 0 || o.Filters.MaxBytes < 0 {
+		return errors.New("-min-bytes and -max-bytes must not be negative")
+	}
+	if o.Filters.MaxBytes > 0 && o.Filters.MaxBytes < o.Filters.MinBytes {
+		return fmt.Errorf("-max-bytes (%d) must not be less than -min-bytes (%d)", o.Filters.MaxBytes, o.Filters.MinBytes)
+	}
+	if o.Filters.MinComponents < 0 {
+		return fmt.Errorf("-min-components must not be negative, but is %d", o.Filters.MinComponents)
+	}
+	if o.Filters.MinSpecVersion != "" && !validSpecVersion(o.Filters.MinSpecVersion) {
+		return fmt.Errorf("-min-spec-version must be a spec version from 1.0 to 1.4, but is %q", o.Filters.MinSpecVersion)
+	}
+	if o.MaxPerGroup < 0 {
+		return fmt.Errorf("-max-per-group must not be negative, but is %d", o.MaxPerGroup)
+	}
+	if o.Filters.KeepRawPurls && !o.Filters.StripPurlQualifiers {
+		return errors.New("-keep-raw requires -strip-purl-qualifiers")
+	}
+	if o.EmptyExitCode < 0 || o.EmptyExitCode > 125 {
+		return fmt.Errorf("-empty-exit-code must be between 0 and 125, but is %d", o.EmptyExitCode)
+	}
+	if flags := o.componentFlags(); o.Filters.DecodeOnlyMetadata && len(flags) > 0 {
+		return fmt.Errorf("-decode-only-metadata can't be used with %s, as they need all components", strings.Join(flags, ", "))
+	}
+	if o.Filters.Lenient && o.Filters.StrictDecode {
+		return errors.New("-lenient and -strict-decode are mutually exclusive")
+	}
+	if o.FollowBOMRefs < 0 || o.MaxLinkedBOMs < 0 {
+		return errors.New("-follow-bom-refs and -max-linked-boms must not be negative")
+	}
+	if o.MaxDiskBytes < 0 {
+		return fmt.Errorf("-max-disk-bytes must not be negative, but is %d", o.MaxDiskBytes)
+	}
+	if o.MaxDiskBytes > 0 && (o.StatsOnly || o.DeterministicOrder) {
+		return errors.New("-max-disk-bytes can't be used with -stats-only or -deterministic-order")
+	}
+	if o.VerifyPurlResolvable && o.VerifyPurlsSample < 1 {
+		return fmt.Errorf("-verify-purls-sample must be positive, but is %d", o.VerifyPurlsSample)
+	}
+	if o.MemLimit < 0 {
+		return fmt.Errorf("-mem-limit must not be negative, but is %d", o.MemLimit)
+	}
+	if o.MaxSBOMs < 0 || o.MinUniqueGroups < 0 {
+		return errors.New("-max-sboms and -min-unique-groups must not be negative")
+	}
+	if o.MinUniqueGroups > 0 && o.MaxSBOMs == 0 {
+		return errors.New("-min-unique-groups requires -max-sboms")
+	}
+	if o.Checkpoint != "" && o.MaxSBOMs == 0 {
+		return errors.New("-checkpoint requires -max-sboms")
+	}
+	if o.MaxSBOMs > 0 && o.DeterministicOrder {
+		return errors.New("-max-sboms can't be used with -deterministic-order")
+	}
+	if o.Filters.SampleRate < 0 || o.Filters.SampleRate > 1 {
+		return fmt.Errorf("-sample-rate must be between 0 and 1, but is %g", o.Filters.SampleRate)
+	}
+	if o.Filters.SampleRate == 0 {
+		return errors.New("-sample-rate 0 would discard all sboms")
+	}
+	if o.Filters.MinQualityScore < 0 || o.Filters.MinQualityScore > 100 {
+		return fmt.Errorf("-min-quality-score must be between 0 and 100, but is %g", o.Filters.MinQualityScore)
+	}
+	if o.Filters.MinTransitiveRatio < 0 || o.Filters.MinTransitiveRatio > 1 {
+		return fmt.Errorf("-min-transitive-ratio must be between 0 and 1, but is %g", o.Filters.MinTransitiveRatio)
+	}
+	if o.Filters.MinVersionedRatio < 0 || o.Filters.MinVersionedRatio > 1 {
+		return fmt.Errorf("-min-versioned-ratio must be between 0 and 1, but is %g", o.Filters.MinVersionedRatio)
+	}
+	if o.Filters.MinCPERatio < 0 || o.Filters.MinCPERatio > 1 {
+		return fmt.Errorf("-min-cpe-ratio must be between 0 and 1, but is %g", o.Filters.MinCPERatio)
+	}
+	if o.StatsOnly && o.WithPOM {
+		return errors.New("-stats-only and -with-pom are mutually exclusive")
+	}
+	if o.StatsOnly && o.FetchSignatures {
+		return errors.New("-stats-only and -fetch-signatures are mutually exclusive")
+	}
+	if o.StatsOnly && o.DeterministicOrder {
+		return errors.New("-stats-only and -deterministic-order are mutually exclusive")
+	}
+	if o.Archive == "" && (o.ArchiveMaxEntries > 0 || o.ArchiveMaxBytes > 0) {
+		return errors.New("-archive-max-entries and -archive-max-bytes require -archive")
+	}
+	if o.ArchiveMaxEntries < 0 || o.ArchiveMaxBytes < 0 {
+		return errors.New("-archive-max-entries and -archive-max-bytes must not be negative")
+	}
+	if o.IndexHeaders && o.IndexFile == "" {
+		return errors.New("-index-headers requires -index")
+	}
+	if o.IndexDiscarded && o.IndexFile == "" {
+		return errors.New("-index-discarded requires -index")
+	}
+	if o.IndexFormat != "" && o.IndexFormat != indexFormatJSON && o.IndexFormat != indexFormatGob {
+		return fmt.Errorf("-index-format must be either %q or %q, but is %q", indexFormatJSON, indexFormatGob, o.IndexFormat)
+	}
+	if o.UniqueSerialIndex != "" && !o.UniqueSerial {
+		return errors.New("-unique-serial-index requires -unique-serial")
+	}
+	if o.StatsOnly && o.CatalogFile != "" {
+		return errors.New("-stats-only and -catalog are mutually exclusive")
+	}
+	if o.Canonical && o.Compact {
+		return errors.New("-canonical and -compact are mutually exclusive")
+	}
+	if o.StatsOnly && o.Archive != "" {
+		return errors.New("-stats-only and -archive are mutually exclusive")
+	}
+	if (len(o.Extract) > 0) != (o.ExtractFile != "") {
+		return errors.New("-extract and -extract-output must be used together")
+	}
+	for _, pointer := range o.Extract {
+		if _, err := parsePointer(pointer); err != nil {
+			return fmt.Errorf("-extract: %w", err)
+		}
+	}
+	if o.FileNameTemplate != "" {
+		if _, err := parseFileNameTemplate(o.FileNameTemplate); err != nil {
+			return fmt.Errorf("-filename-template: %w", err)
+		}
+	}
+	switch o.VersionSource {
+	case "", versionSourceSolr, versionSourceMetadata:
+	default:
+		return fmt.Errorf("-version-source must be either %q or %q, but is %q", versionSourceSolr, versionSourceMetadata, o.VersionSource)
+	}
+	switch o.Filters.ComponentCountSource {
+	case "", componentCountTopLevel, componentCountRecursive, componentCountPurlUnique:
+	default:
+		return fmt.Errorf("-component-count-source must be one of %q, %q or %q, but is %q", componentCountTopLevel, componentCountRecursive, componentCountPurlUnique, o.Filters.ComponentCountSource)
+	}
+	if o.Filters.CountNested && o.Filters.ComponentCountSource == componentCountPurlUnique {
+		return errors.New("-count-nested can't be used with -component-count-source purl-unique")
+	}
+	switch o.FlattenDependencies {
+	case "", flattenProperties, flattenEdges:
+	default:
+		return fmt.Errorf("-flatten-dependencies must be either %q or %q, but is %q", flattenProperties, flattenEdges, o.FlattenDependencies)
+	}
+	if o.Filters.DiscardEmpty && len(o.Filters.PurlTypes) == 0 {
+		return errors.New("-purl-types-discard-empty requires -purl-types")
+	}
+	for _, scope := range o.Filters.Scopes {
+		if _, err := parseScope(scope); err != nil {
+			return fmt.Errorf("-scopes: %w", err)
+		}
+	}
+	for _, typ := range o.Filters.ComponentTypes {
+		if _, err := parseComponentType(typ); err != nil {
+			return fmt.Errorf("-component-types: %w", err)
+		}
+	}
+	for _, header := range o.Headers {
+		if _, _, err := parseHeader(header); err != nil {
+			return fmt.Errorf("-header: %w", err)
+		}
+	}
+	for _, host := range o.AllowedHosts {
+		if strings.ContainsAny(host, "/:") {
+			return fmt.Errorf("-allowed-hosts: expected a host name without scheme, port or path, but got %q", host)
+		}
+	}
+	for _, baseURL := range o.FallbackBaseURLs {
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("-fallback-base-urls: expected an http(s) URL, but got %q", baseURL)
+		}
+	}
+	for _, property := range o.Filters.Properties {
+		if _, err := parsePropertyFilter(property); err != nil {
+			return fmt.Errorf("-property: %w", err)
+		}
+	}
+	if len(o.SBOMSuffixes) == 0 {
+		return errors.New("-sbom-suffixes must not be empty")
+	}
+	for _, prefix := range o.GroupPrefixes {
+		if !groupPrefixRegex.MatchString(prefix) {
+			return fmt.Errorf("-group-prefix: invalid group prefix %q", prefix)
+		}
+	}
+	if o.OTelEndpoint != "" {
+		u, err := url.Parse(o.OTelEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("-otel-endpoint must be an http(s) url, but is %q", o.OTelEndpoint)
+		}
+	}
+	if o.Probe != "" {
+		if _, err := ParseGAV(o.Probe); err != nil {
+			return fmt.Errorf("-probe: %w", err)
+		}
+	}
+	if o.DiscoverClassifiers && (o.Probe != "" || o.Source != "") {
+		return errors.New("-discover-classifiers can't be used with -probe or -source")
+	}
+	if o.RegistryIndex != "" && (o.Probe != "" || o.Source != "" || o.DiscoverClassifiers) {
+		return errors.New("-registry-index can't be used with -probe, -source or -discover-classifiers")
+	}
+	if o.QueueFile != "" && (o.Probe != "" || o.Source != "" || o.DiscoverClassifiers || o.DeterministicOrder) {
+		return errors.New("-queue-file can't be used with -probe, -source, -discover-classifiers or -deterministic-order")
+	}
+	if o.RandomOrder && (o.Probe != "" || o.Source != "" || o.DiscoverClassifiers || o.RegistryIndex != "" || o.QueueFile != "") {
+		return errors.New("-random-order can't be used with -probe, -source, -discover-classifiers, -registry-index or -queue-file")
+	}
+	if o.ValidateTimestamps && (o.Probe != "" || o.Source != "" || o.NewerThanIndex != "") {
+		return errors.New("-validate-timestamps-monotonic requires the full version history, and can't be used with -probe, -source or -newer-than-index")
+	}
+	if o.Seed != 0 && !o.RandomOrder {
+		return errors.New("-random-seed requires -random-order")
+	}
+	if o.CacheTTL < 0 {
+		return fmt.Errorf("-cache-ttl must not be negative, but is %s", o.CacheTTL)
+	}
+	if o.LogByArtifact && (o.Probe != "" || o.Source != "" || o.DiscoverClassifiers || o.RegistryIndex != "") {
+		return errors.New("-log-by-artifact can't be used with -probe, -source, -discover-classifiers or -registry-index")
+	}
+	if o.DiscardsFile != "" && o.Probe != "" {
+		return errors.New("-discards-csv can't be used with -probe")
+	}
+	if o.PartitionBy != "" && o.PartitionBy != partitionByLicense {
+		return fmt.Errorf("-partition-by must be %q, but is %q", partitionByLicense, o.PartitionBy)
+	}
+	if o.NoClobber && (o.Archive != "" || o.StatsOnly) {
+		return errors.New("-no-clobber can't be used with -archive or -stats-only")
+	}
+	if o.Source != "" {
+		path, ok := o.archiveSource()
+		if !ok || path == "" {
+			return fmt.Errorf("-source must be of the form archive:<path>, but is %q", o.Source)
+		}
+		if _, err := archiveFormat(path); err != nil {
+			return fmt.Errorf("-source: %w", err)
+		}
+		if o.WithPOM || o.Probe != "" || o.CatalogFile != "" || o.NewerThanIndex != "" || o.VerifyPurlResolvable {
+			return errors.New("-source can't be used with -with-pom, -probe, -catalog, -newer-than-index or -verify-purl-resolvable")
+		}
+		if len(o.GroupPrefixes) > 0 || o.IncludeGroupsFile != "" || o.ExcludeGroupsFile != "" || o.SkipListFile != "" {
+			return errors.New("-source can't be used with -group-prefix, -include-groups-file, -exclude-groups-file or -skip-list-file")
+		}
+		if o.Archive != "" && filepath.Clean(o.Archive) == filepath.Clean(path) {
+			return errors.New("-archive must not overwrite the archive read with -source")
+		}
+	}
+
+	if o.OutputDir == stdoutOutput {
+		if o.Probe == "" {
+			return errors.New("-output - requires -probe, as only a single sbom can be written to stdout")
+		}
+		return nil
+	}
+
+	fi, err := os.Stat(o.OutputDir)
+	if err != nil {
+		return fmt.Errorf("-output: %w", err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("-output: %s is not a directory", o.OutputDir)
+	}
+
+	return nil
+}
+
+// componentFlags returns the flags that need the components of SBOMs, or that re-encode them.
+func (o Options) componentFlags() []string {
+	var flags []string
+	for flag, set := range map[string]bool{
+		"-purl-types":             len(o.Filters.PurlTypes) > 0,
+		"-scopes":                 len(o.Filters.Scopes) > 0,
+		"-component-types":        len(o.Filters.ComponentTypes) > 0,
+		"-discard-all-excluded":   o.Filters.DiscardAllExcluded,
+		"-property":               len(o.Filters.Properties) > 0,
+		"-require-property":       len(o.Filters.RequiredProperties) > 0,
+		"-contains-group":         len(o.Filters.ContainsGroups) > 0,
+		"-min-transitive-ratio":   o.Filters.MinTransitiveRatio > 0,
+		"-min-versioned-ratio":    o.Filters.MinVersionedRatio > 0,
+		"-require-cpe":            o.Filters.RequireCPE,
+		"-min-cpe-ratio":          o.Filters.MinCPERatio > 0,
+		"-min-quality-score":      o.Filters.MinQualityScore > 0,
+		"-strip-purl-qualifiers":  o.Filters.StripPurlQualifiers,
+		"-dedup-components":       o.Filters.DedupComponents,
+		"-count-nested":           o.Filters.CountNested,
+		"-component-count-source": o.Filters.ComponentCountSource != "" && o.Filters.ComponentCountSource != componentCountTopLevel,
+		"-lenient":                o.Filters.Lenient,
+		"-strict-decode":          o.Filters.StrictDecode,
+		"-dedup-by-component-set": o.DedupComponentSet,
+		"-compact":                o.Compact,
+		"-flatten-dependencies":   o.FlattenDependencies != "",
+		"-follow-bom-refs":        o.FollowBOMRefs > 0,
+		"-write-sidecar-meta":     o.WriteSidecarMeta,
+		"-partition-by license":   o.PartitionBy == partitionByLicense,
+		"-verify-purl-resolvable": o.VerifyPurlResolvable,
+	} {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// componentMetricFlags returns the flags that record metrics of the components of SBOMs,
+// like the quality score, the recursive component count or the component type statistics.
+// These metrics only reflect the metadata if the components weren't decoded.
+func (o Options) componentMetricFlags() []string {
+	var flags []string
+	for flag, set := range map[string]bool{
+		"-stats-only":   o.StatsOnly,
+		"-index":        o.IndexFile != "",
+		"-summary-file": o.SummaryFile != "",
+	} {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
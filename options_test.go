@@ -0,0 +1,541 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	outputDir := t.TempDir()
+	outputFile := filepath.Join(outputDir, "file")
+	if err := os.WriteFile(outputFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() Options {
+		return Options{
+			Concurrency:       5,
+			SearchConcurrency: 4,
+			OutputDir:         outputDir,
+			SBOMSuffixes:      []string{"-cyclonedx.json"},
+			SearchTimeout:     30 * time.Second,
+			DownloadTimeout:   5 * time.Minute,
+			RetryBaseDelay:    5 * time.Second,
+			RetryMaxDelay:     2 * time.Minute,
+			RetryJitter:       retryJitterFull,
+			Filters: Filters{
+				MinComponents: 10,
+				SampleRate:    1,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(o *Options)
+		errMsg string
+	}{
+		{
+			name:   "Valid",
+			modify: func(o *Options) {},
+		},
+		{
+			name:   "ZeroConcurrency",
+			modify: func(o *Options) { o.Concurrency = 0 },
+			errMsg: "-concurrency must be at least 1",
+		},
+		{
+			name:   "NegativeMinComponents",
+			modify: func(o *Options) { o.Filters.MinComponents = -1 },
+			errMsg: "-min-components must not be negative",
+		},
+		{
+			name:   "MinSpecVersionTooNew",
+			modify: func(o *Options) { o.Filters.MinSpecVersion = "1.5" },
+			errMsg: `-min-spec-version must be a spec version from 1.0 to 1.4, but is "1.5"`,
+		},
+		{
+			name:   "SampleRateTooHigh",
+			modify: func(o *Options) { o.Filters.SampleRate = 1.5 },
+			errMsg: "-sample-rate must be between 0 and 1",
+		},
+		{
+			name:   "SampleRateZero",
+			modify: func(o *Options) { o.Filters.SampleRate = 0 },
+			errMsg: "-sample-rate 0 would discard all sboms",
+		},
+		{
+			name:   "InvalidScope",
+			modify: func(o *Options) { o.Filters.Scopes = []string{"test"} },
+			errMsg: "-scopes: invalid scope",
+		},
+		{
+			name:   "InvalidComponentType",
+			modify: func(o *Options) { o.Filters.ComponentTypes = []string{"platform"} },
+			errMsg: "-component-types: invalid component type",
+		},
+		{
+			name:   "InvalidFileNameTemplate",
+			modify: func(o *Options) { o.FileNameTemplate = "{{.Unknown}}" },
+			errMsg: "-filename-template:",
+		},
+		{
+			name: "CanonicalAndCompact",
+			modify: func(o *Options) {
+				o.Canonical = true
+				o.Compact = true
+			},
+			errMsg: "-canonical and -compact are mutually exclusive",
+		},
+		{
+			name:   "NegativeGAVBudget",
+			modify: func(o *Options) { o.GAVBudget = -time.Second },
+			errMsg: "-gav-budget must not be negative",
+		},
+		{
+			name: "NonPositiveVerifyPurlsSample",
+			modify: func(o *Options) {
+				o.VerifyPurlResolvable = true
+				o.VerifyPurlsSample = 0
+			},
+			errMsg: "-verify-purls-sample must be positive",
+		},
+		{
+			name: "RandomOrderWithQueueFile",
+			modify: func(o *Options) {
+				o.RandomOrder = true
+				o.QueueFile = "queue.txt"
+			},
+			errMsg: "-random-order can't be used with",
+		},
+		{
+			name: "ValidateTimestampsWithNewerThanIndex",
+			modify: func(o *Options) {
+				o.ValidateTimestamps = true
+				o.NewerThanIndex = "index.json"
+			},
+			errMsg: "-validate-timestamps-monotonic requires the full version history",
+		},
+		{
+			name:   "InvalidIndexFormat",
+			modify: func(o *Options) { o.IndexFormat = "ndjson" },
+			errMsg: "-index-format must be either",
+		},
+		{
+			name:   "SeedWithoutRandomOrder",
+			modify: func(o *Options) { o.Seed = 42 },
+			errMsg: "-random-seed requires -random-order",
+		},
+		{
+			name:   "NegativeStuckTimeout",
+			modify: func(o *Options) { o.StuckTimeout = -time.Second },
+			errMsg: "-stuck-timeout must not be negative",
+		},
+		{
+			name:   "CancelStuckWithoutStuckTimeout",
+			modify: func(o *Options) { o.CancelStuck = true },
+			errMsg: "-cancel-stuck requires -stuck-timeout",
+		},
+		{
+			name:   "NegativeMemLimit",
+			modify: func(o *Options) { o.MemLimit = -1 },
+			errMsg: "-mem-limit must not be negative",
+		},
+		{
+			name:   "NegativeArtifactDeadline",
+			modify: func(o *Options) { o.ArtifactDeadline = -time.Second },
+			errMsg: "-artifact-deadline must not be negative",
+		},
+		{
+			name:   "AllowedHostWithScheme",
+			modify: func(o *Options) { o.AllowedHosts = []string{"https://mirror.example.com"} },
+			errMsg: "-allowed-hosts: expected a host name",
+		},
+		{
+			name:   "InvalidFallbackBaseURL",
+			modify: func(o *Options) { o.FallbackBaseURLs = []string{"s01.oss.sonatype.org"} },
+			errMsg: "-fallback-base-urls: expected an http(s) URL",
+		},
+		{
+			name:   "InvalidSource",
+			modify: func(o *Options) { o.Source = "corpus.zip" },
+			errMsg: "-source must be of the form archive:<path>",
+		},
+		{
+			name: "SourceWithPOM",
+			modify: func(o *Options) {
+				o.Source = "archive:corpus.zip"
+				o.WithPOM = true
+			},
+			errMsg: "-source can't be used with -with-pom",
+		},
+		{
+			name:   "MinTransitiveRatioTooHigh",
+			modify: func(o *Options) { o.Filters.MinTransitiveRatio = 2 },
+			errMsg: "-min-transitive-ratio must be between 0 and 1",
+		},
+		{
+			name: "StatsOnlyWithPOM",
+			modify: func(o *Options) {
+				o.StatsOnly = true
+				o.WithPOM = true
+			},
+			errMsg: "-stats-only and -with-pom are mutually exclusive",
+		},
+		{
+			name: "StatsOnlyDeterministicOrder",
+			modify: func(o *Options) {
+				o.StatsOnly = true
+				o.DeterministicOrder = true
+			},
+			errMsg: "-stats-only and -deterministic-order are mutually exclusive",
+		},
+		{
+			name: "StatsOnlyCatalog",
+			modify: func(o *Options) {
+				o.StatsOnly = true
+				o.CatalogFile = "catalog.cdx.json"
+			},
+			errMsg: "-stats-only and -catalog are mutually exclusive",
+		},
+		{
+			name:   "ZeroSearchConcurrency",
+			modify: func(o *Options) { o.SearchConcurrency = 0 },
+			errMsg: "-search-concurrency must be at least 1, but is 0",
+		},
+		{
+			name:   "ZeroSearchTimeout",
+			modify: func(o *Options) { o.SearchTimeout = 0 },
+			errMsg: "-search-timeout and -download-timeout must be positive",
+		},
+		{
+			name:   "ArchiveLimitsWithoutArchive",
+			modify: func(o *Options) { o.ArchiveMaxEntries = 100 },
+			errMsg: "require -archive",
+		},
+		{
+			name: "ArchiveWithLimits",
+			modify: func(o *Options) {
+				o.Archive = "corpus.zip"
+				o.ArchiveMaxBytes = 1 << 30
+			},
+		},
+		{
+			name: "StatsOnlyArchive",
+			modify: func(o *Options) {
+				o.StatsOnly = true
+				o.Archive = "corpus.zip"
+			},
+			errMsg: "-stats-only and -archive are mutually exclusive",
+		},
+		{
+			name:   "IndexHeadersWithoutIndex",
+			modify: func(o *Options) { o.IndexHeaders = true },
+			errMsg: "-index-headers requires -index",
+		},
+		{
+			name:   "ProbeInvalidCoordinates",
+			modify: func(o *Options) { o.Probe = "org.example:foo" },
+			errMsg: "-probe: invalid coordinates",
+		},
+		{
+			name:   "ValidGroupPrefixes",
+			modify: func(o *Options) { o.GroupPrefixes = []string{"org.apache", "com.fasterxml."} },
+		},
+		{
+			name:   "InvalidGroupPrefix",
+			modify: func(o *Options) { o.GroupPrefixes = []string{"org.apache*"} },
+			errMsg: "-group-prefix: invalid group prefix",
+		},
+		{
+			name:   "InvalidFlattenDependencies",
+			modify: func(o *Options) { o.FlattenDependencies = "graph" },
+			errMsg: "-flatten-dependencies must be either",
+		},
+		{
+			name:   "DiscardEmptyWithoutPurlTypes",
+			modify: func(o *Options) { o.Filters.DiscardEmpty = true },
+			errMsg: "-purl-types-discard-empty requires -purl-types",
+		},
+		{
+			name:   "KeepRawWithoutStrip",
+			modify: func(o *Options) { o.Filters.KeepRawPurls = true },
+			errMsg: "-keep-raw requires -strip-purl-qualifiers",
+		},
+		{
+			name: "QueueFileDeterministicOrder",
+			modify: func(o *Options) {
+				o.QueueFile = "queue.txt"
+				o.DeterministicOrder = true
+			},
+			errMsg: "-queue-file can't be used with -probe, -source, -discover-classifiers or -deterministic-order",
+		},
+		{
+			name:   "InvalidMinQualityScore",
+			modify: func(o *Options) { o.Filters.MinQualityScore = 101 },
+			errMsg: "-min-quality-score must be between 0 and 100, but is 101",
+		},
+		{
+			name:   "InvalidEmptyExitCode",
+			modify: func(o *Options) { o.EmptyExitCode = 255 },
+			errMsg: "-empty-exit-code must be between 0 and 125, but is 255",
+		},
+		{
+			name: "LenientStrictDecode",
+			modify: func(o *Options) {
+				o.Filters.Lenient = true
+				o.Filters.StrictDecode = true
+			},
+			errMsg: "-lenient and -strict-decode are mutually exclusive",
+		},
+		{
+			name:   "NegativeFollowBOMRefs",
+			modify: func(o *Options) { o.FollowBOMRefs = -1 },
+			errMsg: "-follow-bom-refs and -max-linked-boms must not be negative",
+		},
+		{
+			name:   "NegativeMaxDiskBytes",
+			modify: func(o *Options) { o.MaxDiskBytes = -1 },
+			errMsg: "-max-disk-bytes must not be negative",
+		},
+		{
+			name: "MaxDiskBytesDeterministicOrder",
+			modify: func(o *Options) {
+				o.MaxDiskBytes = 1
+				o.DeterministicOrder = true
+			},
+			errMsg: "-max-disk-bytes can't be used with -stats-only or -deterministic-order",
+		},
+		{
+			name:   "MinUniqueGroupsWithoutMaxSBOMs",
+			modify: func(o *Options) { o.MinUniqueGroups = 10 },
+			errMsg: "-min-unique-groups requires -max-sboms",
+		},
+		{
+			name:   "CheckpointWithoutMaxSBOMs",
+			modify: func(o *Options) { o.Checkpoint = "checkpoint" },
+			errMsg: "-checkpoint requires -max-sboms",
+		},
+		{
+			name: "MaxSBOMsDeterministicOrder",
+			modify: func(o *Options) {
+				o.MaxSBOMs = 100
+				o.DeterministicOrder = true
+			},
+			errMsg: "-max-sboms can't be used with -deterministic-order",
+		},
+		{
+			name:   "InvalidVersionSource",
+			modify: func(o *Options) { o.VersionSource = "sonatype" },
+			errMsg: "-version-source must be either",
+		},
+		{
+			name:   "InvalidComponentCountSource",
+			modify: func(o *Options) { o.Filters.ComponentCountSource = "declared" },
+			errMsg: "-component-count-source must be one of",
+		},
+		{
+			name: "CountNestedPurlUnique",
+			modify: func(o *Options) {
+				o.Filters.CountNested = true
+				o.Filters.ComponentCountSource = componentCountPurlUnique
+			},
+			errMsg: "-count-nested can't be used with -component-count-source purl-unique",
+		},
+		{
+			name: "MaxBytesLessThanMinBytes",
+			modify: func(o *Options) {
+				o.Filters.MinBytes = 1024
+				o.Filters.MaxBytes = 512
+			},
+			errMsg: "-max-bytes (512) must not be less than -min-bytes (1024)",
+		},
+		{
+			name: "DecodeOnlyMetadataWithComponentFilters",
+			modify: func(o *Options) {
+				o.Filters.DecodeOnlyMetadata = true
+				o.Filters.Scopes = []string{"required"}
+				o.Compact = true
+			},
+			errMsg: "-decode-only-metadata can't be used with -compact, -scopes, as they need all components",
+		},
+		{
+			name: "RegistryIndexWithProbe",
+			modify: func(o *Options) {
+				o.RegistryIndex = "coverage.ndjson"
+				o.Probe = "org.example:lib:1.0.0"
+			},
+			errMsg: "-registry-index can't be used with -probe, -source or -discover-classifiers",
+		},
+		{
+			name: "NoClobberWithArchive",
+			modify: func(o *Options) {
+				o.NoClobber = true
+				o.Archive = "corpus.zip"
+			},
+			errMsg: "-no-clobber can't be used with -archive or -stats-only",
+		},
+		{
+			name: "DiscardsCSVWithProbe",
+			modify: func(o *Options) {
+				o.DiscardsFile = "discards.csv"
+				o.Probe = "org.example:lib:1.0.0"
+			},
+			errMsg: "-discards-csv can't be used with -probe",
+		},
+		{
+			name:   "NegativeCacheTTL",
+			modify: func(o *Options) { o.CacheTTL = -time.Minute },
+			errMsg: "-cache-ttl must not be negative, but is -1m0s",
+		},
+		{
+			name:   "ZeroRetryBaseDelay",
+			modify: func(o *Options) { o.RetryBaseDelay = 0 },
+			errMsg: "-retry-base-delay must be positive, but is 0s",
+		},
+		{
+			name:   "RetryMaxDelayBelowBaseDelay",
+			modify: func(o *Options) { o.RetryMaxDelay = time.Second },
+			errMsg: "-retry-max-delay (1s) must not be less than -retry-base-delay (5s)",
+		},
+		{
+			name:   "InvalidRetryJitter",
+			modify: func(o *Options) { o.RetryJitter = "equal" },
+			errMsg: "-retry-jitter must be \"full\" or \"none\", but is \"equal\"",
+		},
+		{
+			name:   "InvalidPartitionBy",
+			modify: func(o *Options) { o.PartitionBy = "group" },
+			errMsg: "-partition-by must be \"license\", but is \"group\"",
+		},
+		{
+			name:   "CPERatioTooHigh",
+			modify: func(o *Options) { o.Filters.MinCPERatio = 1.5 },
+			errMsg: "-min-cpe-ratio must be between 0 and 1, but is 1.5",
+		},
+		{
+			name:   "VersionedRatioTooHigh",
+			modify: func(o *Options) { o.Filters.MinVersionedRatio = 1.5 },
+			errMsg: "-min-versioned-ratio must be between 0 and 1, but is 1.5",
+		},
+		{
+			name: "FetchSignaturesWithStatsOnly",
+			modify: func(o *Options) {
+				o.FetchSignatures = true
+				o.StatsOnly = true
+			},
+			errMsg: "-stats-only and -fetch-signatures are mutually exclusive",
+		},
+		{
+			name: "LogByArtifactWithSource",
+			modify: func(o *Options) {
+				o.LogByArtifact = true
+				o.Source = "archive:corpus.zip"
+			},
+			errMsg: "-log-by-artifact can't be used with -probe, -source, -discover-classifiers or -registry-index",
+		},
+		{
+			name:   "InvalidHeader",
+			modify: func(o *Options) { o.Headers = []string{"X-Tenant 42"} },
+			errMsg: "-header: invalid header",
+		},
+		{
+			name:   "IndexDiscardedWithoutIndex",
+			modify: func(o *Options) { o.IndexDiscarded = true },
+			errMsg: "-index-discarded requires -index",
+		},
+		{
+			name:   "InvalidPropertyFilter",
+			modify: func(o *Options) { o.Filters.Properties = []string{"cdx:maven:package:test"} },
+			errMsg: "-property: invalid property filter",
+		},
+		{
+			name:   "InvalidOTelEndpoint",
+			modify: func(o *Options) { o.OTelEndpoint = "localhost:4318" },
+			errMsg: "-otel-endpoint must be an http(s) url",
+		},
+		{
+			name:   "ExtractWithoutOutput",
+			modify: func(o *Options) { o.Extract = []string{"/metadata/component/purl"} },
+			errMsg: "-extract and -extract-output must be used together",
+		},
+		{
+			name: "InvalidExtractPointer",
+			modify: func(o *Options) {
+				o.Extract = []string{"metadata"}
+				o.ExtractFile = "extracts.ndjson"
+			},
+			errMsg: "-extract: invalid json pointer",
+		},
+		{
+			name:   "NoSBOMSuffixes",
+			modify: func(o *Options) { o.SBOMSuffixes = nil },
+			errMsg: "-sbom-suffixes must not be empty",
+		},
+		{
+			name:   "StdoutWithoutProbe",
+			modify: func(o *Options) { o.OutputDir = "-" },
+			errMsg: "-output - requires -probe",
+		},
+		{
+			name:   "OutputDirMissing",
+			modify: func(o *Options) { o.OutputDir = filepath.Join(outputDir, "missing") },
+			errMsg: "-output:",
+		},
+		{
+			name:   "OutputDirIsFile",
+			modify: func(o *Options) { o.OutputDir = outputFile },
+			errMsg: "is not a directory",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := valid()
+			tc.modify(&opts)
+
+			err := opts.Validate()
+			if tc.errMsg == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", tc.errMsg)
+			}
+			if !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf("expected error containing %q, got %q", tc.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestOptionsMetadataOnly(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     Options
+		expected bool
+	}{
+		{"Default", Options{}, false},
+		{"DecodeOnlyMetadata", Options{Filters: Filters{DecodeOnlyMetadata: true}}, true},
+		{"DecodeOnlyMetadataWithIndex", Options{Filters: Filters{DecodeOnlyMetadata: true}, IndexFile: "index.jsonl"}, true},
+		{"Extract", Options{Extract: []string{"/metadata/component/purl"}}, true},
+		{"ExtractWithComponentFilter", Options{Extract: []string{"/specVersion"}, Filters: Filters{PurlTypes: []string{"maven"}}}, false},
+		{"ExtractWithCompact", Options{Extract: []string{"/specVersion"}, Compact: true}, false},
+		{"ExtractWithStatsOnly", Options{Extract: []string{"/specVersion"}, StatsOnly: true}, false},
+		{"ExtractWithIndex", Options{Extract: []string{"/specVersion"}, IndexFile: "index.jsonl"}, false},
+		{"ExtractWithSummaryFile", Options{Extract: []string{"/specVersion"}, SummaryFile: "summary.json"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if metadataOnly := tc.opts.metadataOnly(); metadataOnly != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, metadataOnly)
+			}
+		})
+	}
+}
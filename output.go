@@ -0,0 +1,301 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// Output is where SBOMs and related files are stored.
+// Implementations must be safe for concurrent use.
+type Output interface {
+	// Write stores data under name. name may contain slashes,
+	// in which case data is stored in subdirectories.
+	// It returns the name of the archive data was written to,
+	// or an empty string if it was not written to an archive.
+	Write(name string, data []byte) (string, error)
+
+	Close() error
+}
+
+func newOutput(opts Options) (Output, error) {
+	if opts.Archive == "" {
+		return &dirOutput{dir: opts.OutputDir, noClobber: opts.NoClobber}, nil
+	}
+
+	return &archiveOutput{
+		path:       opts.Archive,
+		maxEntries: opts.ArchiveMaxEntries,
+		maxBytes:   opts.ArchiveMaxBytes,
+	}, nil
+}
+
+// errFileExists is returned by dirOutput.Write with -no-clobber,
+// when a non-empty file already exists.
+var errFileExists = errors.New("file already exists")
+
+// dirOutput writes files to a directory.
+type dirOutput struct {
+	dir string
+
+	// noClobber prevents existing files from being overwritten, unless they are empty.
+	noClobber bool
+}
+
+func (d dirOutput) Write(name string, data []byte) (string, error) {
+	path := filepath.Join(d.dir, filepath.FromSlash(name))
+	if strings.Contains(name, "/") {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := writeFileAtomic(path, d.noClobber, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	return "", err
+}
+
+// writeFileAtomic writes a temporary file in the directory of path with write,
+// and moves it to path once it's complete, so that readers never observe partial
+// files and interrupted writes leave no garbage behind.
+//
+// If noClobber is set, an existing non-empty file at path is left as is,
+// and errFileExists is returned. Empty files are considered incomplete, and replaced.
+func writeFileAtomic(path string, noClobber bool, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !noClobber {
+		return os.Rename(f.Name(), path)
+	}
+	// Unlike renaming, linking fails if path exists, so that
+	// concurrent writers can't overwrite each other's files.
+	err = os.Link(f.Name(), path)
+	if !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	if fi, statErr := os.Stat(path); statErr != nil || fi.Size() > 0 {
+		return fmt.Errorf("%w: %s", errFileExists, path)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// sbomExists determines whether the SBOM of gav was already written to dir
+// in any format, under its default file name. Empty files don't count.
+func sbomExists(dir string, gav GAV) bool {
+	for _, format := range []cyclonedx.BOMFileFormat{cyclonedx.BOMFileFormatJSON, cyclonedx.BOMFileFormatXML} {
+		fi, err := os.Stat(filepath.Join(dir, sbomFileName(gav, format)))
+		if err == nil && fi.Size() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writtenSBOMTimestamp returns the metadata.timestamp of the SBOM of gav that was already
+// written to dir under its default file name, or an empty string if it can't be read.
+func writtenSBOMTimestamp(dir string, gav GAV) string {
+	for _, format := range []cyclonedx.BOMFileFormat{cyclonedx.BOMFileFormatJSON, cyclonedx.BOMFileFormatXML} {
+		data, err := os.ReadFile(filepath.Join(dir, sbomFileName(gav, format)))
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		var bom cyclonedx.BOM
+		if format == cyclonedx.BOMFileFormatJSON {
+			_, _, err = decodeSBOMMetadata(data, &bom)
+		} else {
+			err = cyclonedx.NewBOMDecoder(bytes.NewReader(data), format).Decode(&bom)
+		}
+		if err != nil || bom.Metadata == nil {
+			return ""
+		}
+		return bom.Metadata.Timestamp
+	}
+	return ""
+}
+
+func (d dirOutput) Close() error {
+	return nil
+}
+
+// archiveOutput writes files to zip archives.
+//
+// When maxEntries or maxBytes are set, files are spread across multiple
+// archives (corpus-0001.zip, corpus-0002.zip, ...) with a new archive
+// being started once the current one reached either limit.
+// maxBytes refers to the uncompressed size of the archived files.
+//
+// Files are compressed by the calling goroutine before the lock is taken,
+// so that workers only contend for appending the compressed bytes.
+// Writes to the archive file are buffered, and flushed when the archive is closed.
+type archiveOutput struct {
+	path       string
+	maxEntries int
+	maxBytes   int64
+
+	mux     sync.Mutex
+	shard   int
+	file    *os.File
+	buf     *bufio.Writer
+	zw      *zip.Writer
+	entries int
+	bytes   int64
+}
+
+func (a *archiveOutput) Write(name string, data []byte) (string, error) {
+	compressed, err := deflate(data)
+	if err != nil {
+		return "", err
+	}
+	header := &zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(compressed)),
+		UncompressedSize64: uint64(len(data)),
+	}
+	header.SetMode(0o644)
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if a.zw == nil || a.full(len(data)) {
+		err := a.rollover()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	w, err := a.zw.CreateRaw(header)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write(compressed)
+	if err != nil {
+		return "", err
+	}
+
+	a.entries++
+	a.bytes += int64(len(data))
+
+	return a.file.Name(), nil
+}
+
+// full determines whether a file of size n fits into the current archive.
+// An empty archive always accepts a file, even if it exceeds maxBytes on its own.
+func (a *archiveOutput) full(n int) bool {
+	if a.entries == 0 {
+		return false
+	}
+	if a.maxEntries > 0 && a.entries >= a.maxEntries {
+		return true
+	}
+	if a.maxBytes > 0 && a.bytes+int64(n) > a.maxBytes {
+		return true
+	}
+	return false
+}
+
+func (a *archiveOutput) sharded() bool {
+	return a.maxEntries > 0 || a.maxBytes > 0
+}
+
+func (a *archiveOutput) rollover() error {
+	err := a.closeCurrent()
+	if err != nil {
+		return err
+	}
+
+	path := a.path
+	if a.sharded() {
+		a.shard++
+		ext := filepath.Ext(a.path)
+		path = fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(a.path, ext), a.shard, ext)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	a.file = f
+	a.buf = bufio.NewWriterSize(f, archiveBufferSize)
+	a.zw = zip.NewWriter(a.buf)
+	a.entries = 0
+	a.bytes = 0
+
+	return nil
+}
+
+func (a *archiveOutput) closeCurrent() error {
+	if a.zw == nil {
+		return nil
+	}
+
+	err := a.zw.Close()
+	if err == nil {
+		err = a.buf.Flush()
+	}
+	if err != nil {
+		a.file.Close()
+		return err
+	}
+	a.zw = nil
+
+	return a.file.Close()
+}
+
+const archiveBufferSize = 1 << 20
+
+func deflate(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	fw, err := flate.NewWriter(&b, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fw.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = fw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (a *archiveOutput) Close() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.closeCurrent()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestArchiveOutputRollover(t *testing.T) {
+	dir := t.TempDir()
+	output := &archiveOutput{
+		path:       filepath.Join(dir, "corpus.zip"),
+		maxEntries: 2,
+	}
+
+	archives := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		archive, err := output.Write(fmt.Sprintf("%d.cdx.json", i), []byte("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, filepath.Base(archive))
+	}
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"corpus-0001.zip", "corpus-0001.zip", "corpus-0002.zip", "corpus-0002.zip", "corpus-0003.zip"}
+	for i := range expected {
+		if archives[i] != expected[i] {
+			t.Fatalf("expected file %d to be written to %s, but was written to %s", i, expected[i], archives[i])
+		}
+	}
+
+	for archive, entries := range map[string]int{"corpus-0001.zip": 2, "corpus-0002.zip": 2, "corpus-0003.zip": 1} {
+		zr, err := zip.OpenReader(filepath.Join(dir, archive))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(zr.File) != entries {
+			t.Fatalf("expected %s to contain %d entries, but it contains %d", archive, entries, len(zr.File))
+		}
+		zr.Close()
+	}
+}
+
+func TestArchiveOutputMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	output := &archiveOutput{
+		path:     filepath.Join(dir, "corpus.zip"),
+		maxBytes: 10,
+	}
+
+	var archives []string
+	for _, size := range []int{6, 4, 1, 20} {
+		archive, err := output.Write(fmt.Sprintf("%d.cdx.json", size), make([]byte, size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, filepath.Base(archive))
+	}
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"corpus-0001.zip", "corpus-0001.zip", "corpus-0002.zip", "corpus-0003.zip"}
+	for i := range expected {
+		if archives[i] != expected[i] {
+			t.Fatalf("expected file %d to be written to %s, but was written to %s", i, expected[i], archives[i])
+		}
+	}
+}
+
+func TestArchiveOutputContent(t *testing.T) {
+	dir := t.TempDir()
+	output := &archiveOutput{
+		path: filepath.Join(dir, "corpus.zip"),
+	}
+
+	data := bytes.Repeat([]byte(`{"components":[]}`), 100)
+	if _, err := output.Write("a.cdx.json", data); err != nil {
+		t.Fatal(err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(dir, "corpus.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	if len(zr.File) != 1 || zr.File[0].Name != "a.cdx.json" {
+		t.Fatalf("unexpected archive entries: %v", zr.File)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Fatalf("archived content does not match the written data")
+	}
+}
+
+func TestDirOutputSubdirectories(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := (dirOutput{dir: dir}).Write("org.example/lib/1.0.0.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "org.example", "lib", "1.0.0.json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirOutputReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	output := dirOutput{dir: dir}
+	for _, data := range []string{"{}", `{"a": 1}`} {
+		if _, err := output.Write("lib.json", []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "lib.json" {
+		t.Fatalf("expected only lib.json, got %v", entries)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "lib.json")); err != nil || string(data) != `{"a": 1}` {
+		t.Fatalf("unexpected content %q (err: %v)", data, err)
+	}
+}
+
+func TestDirOutputNoClobber(t *testing.T) {
+	dir := t.TempDir()
+	output := dirOutput{dir: dir, noClobber: true}
+	gav := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+	name := sbomFileName(gav, cyclonedx.BOMFileFormatJSON)
+
+	// An empty file is left behind by an interrupted download, and replaced.
+	if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if sbomExists(dir, gav) {
+		t.Fatal("expected empty sbom not to count as existing")
+	}
+	if _, err := output.Write(name, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if !sbomExists(dir, gav) {
+		t.Fatal("expected sbom to exist")
+	}
+
+	if _, err := output.Write(name, []byte(`{"a": 1}`)); !errors.Is(err, errFileExists) {
+		t.Fatalf("expected errFileExists, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != "{}" {
+		t.Fatalf("expected existing sbom to be kept, got %q (err: %v)", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected no temporary files to be left behind, got %v", entries)
+	}
+}
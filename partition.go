@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// partitionByLicense is the -partition-by value for partitioning the output by license.
+const partitionByLicense = "license"
+
+// unknownLicensePartition is the partition of SBOMs whose license can't be determined.
+const unknownLicensePartition = "unknown"
+
+// licenseName returns the SPDX ID, name or expression of a license choice, in that order of preference.
+func licenseName(choice cyclonedx.LicenseChoice) string {
+	switch {
+	case choice.License != nil && choice.License.ID != "":
+		return choice.License.ID
+	case choice.License != nil && choice.License.Name != "":
+		return choice.License.Name
+	default:
+		return choice.Expression
+	}
+}
+
+// primaryLicense determines the license of an SBOM's subject. It's the first license of the
+// root component, or, if it has none, the license declared by most top-level components.
+// Ties are broken alphabetically. It returns an empty string if no license is declared at all.
+func primaryLicense(bom *cyclonedx.BOM) string {
+	if bom.Metadata != nil && bom.Metadata.Component != nil && bom.Metadata.Component.Licenses != nil {
+		for _, choice := range *bom.Metadata.Component.Licenses {
+			if name := licenseName(choice); name != "" {
+				return name
+			}
+		}
+	}
+	if bom.Components == nil {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	for _, component := range *bom.Components {
+		if component.Licenses == nil {
+			continue
+		}
+		for _, choice := range *component.Licenses {
+			if name := licenseName(choice); name != "" {
+				counts[name]++
+			}
+		}
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// licensePartition returns the directory for the SBOM in bom with -partition-by license.
+func licensePartition(bom *cyclonedx.BOM) string {
+	license := primaryLicense(bom)
+	if license == "" {
+		return unknownLicensePartition
+	}
+	// Licenses like "GPL/LGPL" must not result in nested directories.
+	partition, err := sanitizeFileName(strings.ReplaceAll(license, "/", "_"))
+	if err != nil {
+		return unknownLicensePartition
+	}
+	return partition
+}
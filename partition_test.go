@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestLicensePartition(t *testing.T) {
+	licenses := func(choices ...cyclonedx.LicenseChoice) *cyclonedx.Licenses {
+		l := cyclonedx.Licenses(choices)
+		return &l
+	}
+	id := func(id string) cyclonedx.LicenseChoice {
+		return cyclonedx.LicenseChoice{License: &cyclonedx.License{ID: id}}
+	}
+
+	testCases := []struct {
+		name     string
+		bom      cyclonedx.BOM
+		expected string
+	}{
+		{
+			name: "RootComponent",
+			bom: cyclonedx.BOM{
+				Metadata:   &cyclonedx.Metadata{Component: &cyclonedx.Component{Licenses: licenses(id("Apache-2.0"), id("MIT"))}},
+				Components: &[]cyclonedx.Component{{Licenses: licenses(id("MIT"))}},
+			},
+			expected: "Apache-2.0",
+		},
+		{
+			name: "Plurality",
+			bom: cyclonedx.BOM{
+				Metadata: &cyclonedx.Metadata{Component: &cyclonedx.Component{}},
+				Components: &[]cyclonedx.Component{
+					{Licenses: licenses(id("MIT"))},
+					{Licenses: licenses(id("BSD-3-Clause"))},
+					{Licenses: licenses(id("BSD-3-Clause"))},
+					{},
+				},
+			},
+			expected: "BSD-3-Clause",
+		},
+		{
+			name: "PluralityTie",
+			bom: cyclonedx.BOM{
+				Components: &[]cyclonedx.Component{
+					{Licenses: licenses(id("MIT"))},
+					{Licenses: licenses(cyclonedx.LicenseChoice{License: &cyclonedx.License{Name: "Apache License"}})},
+				},
+			},
+			expected: "Apache_License",
+		},
+		{
+			name: "Expression",
+			bom: cyclonedx.BOM{
+				Metadata: &cyclonedx.Metadata{Component: &cyclonedx.Component{Licenses: licenses(cyclonedx.LicenseChoice{Expression: "GPL-2.0/LGPL-2.1 OR MIT"})}},
+			},
+			expected: "GPL-2.0_LGPL-2.1_OR_MIT",
+		},
+		{
+			name:     "Unknown",
+			bom:      cyclonedx.BOM{Components: &[]cyclonedx.Component{{}}},
+			expected: unknownLicensePartition,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if partition := licensePartition(&tc.bom); partition != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, partition)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+// runProbe runs the download and filter path for a single GAV with verbose
+// logging enabled, and reports whether its SBOM would be written.
+// Nothing is written to the output, unless it's stdout.
+func runProbe(ctx context.Context, repo Repository, opts Options, summary *Summary) {
+	verbose = true
+
+	gav, err := ParseGAV(opts.Probe)
+	if err != nil {
+		log.Fatalf("invalid probe: %v", err)
+	}
+
+	log.Printf("probing %s", gav)
+	result, err := downloadSBOM(ctx, repo, gav, opts, summary)
+	if err != nil {
+		log.Printf("probe of %s failed: %v", gav, err)
+		os.Exit(1)
+	}
+	if result.Discard != nil {
+		log.Printf("sbom for %s would not be written (%s)", gav, result.Discard.Reason)
+		return
+	}
+
+	if opts.OutputDir != stdoutOutput {
+		log.Printf("sbom for %s would be written as %s", gav, sbomFileName(gav, result.Format))
+		return
+	}
+
+	// Logs go to stderr, so stdout only contains the SBOM.
+	_, err = os.Stdout.Write(result.Raw)
+	if err != nil {
+		log.Printf("failed to write sbom for %s to stdout: %v", gav, err)
+		os.Exit(1)
+	}
+}
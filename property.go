@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// propertyMatcher matches component properties by name and, optionally, value.
+type propertyMatcher struct {
+	name     string
+	value    string
+	anyValue bool
+}
+
+// parsePropertyFilter parses a filter in the form name=value.
+func parsePropertyFilter(s string) (propertyMatcher, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return propertyMatcher{}, fmt.Errorf("invalid property filter %q: expected name=value", s)
+	}
+	return propertyMatcher{name: name, value: value}, nil
+}
+
+// propertyMatchers builds matchers for the -property and -require-property filters.
+func propertyMatchers(filters Filters) []propertyMatcher {
+	matchers := make([]propertyMatcher, 0, len(filters.Properties)+len(filters.RequiredProperties))
+	for _, property := range filters.Properties {
+		// Validated beforehand.
+		matcher, _ := parsePropertyFilter(property)
+		matchers = append(matchers, matcher)
+	}
+	for _, name := range filters.RequiredProperties {
+		matchers = append(matchers, propertyMatcher{name: name, anyValue: true})
+	}
+	return matchers
+}
+
+func (m propertyMatcher) matches(property cyclonedx.Property) bool {
+	return property.Name == m.name && (m.anyValue || property.Value == m.value)
+}
+
+// countPropertyMatches counts the components of bom, including the root component
+// and nested components, that have a property matching any of the matchers.
+func countPropertyMatches(bom *cyclonedx.BOM, matchers []propertyMatcher) int {
+	count := 0
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		count += countComponentPropertyMatches(&[]cyclonedx.Component{*bom.Metadata.Component}, matchers)
+	}
+	return count + countComponentPropertyMatches(bom.Components, matchers)
+}
+
+func countComponentPropertyMatches(components *[]cyclonedx.Component, matchers []propertyMatcher) int {
+	if components == nil {
+		return 0
+	}
+
+	count := 0
+	for _, component := range *components {
+		if component.Properties != nil && anyPropertyMatches(*component.Properties, matchers) {
+			count++
+		}
+		count += countComponentPropertyMatches(component.Components, matchers)
+	}
+	return count
+}
+
+func anyPropertyMatches(properties []cyclonedx.Property, matchers []propertyMatcher) bool {
+	for _, property := range properties {
+		for _, matcher := range matchers {
+			if matcher.matches(property) {
+				return true
+			}
+		}
+	}
+	return false
+}
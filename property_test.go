@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestCountPropertyMatches(t *testing.T) {
+	bom := &cyclonedx.BOM{
+		Metadata: &cyclonedx.Metadata{
+			Component: &cyclonedx.Component{
+				Name:       "root",
+				Properties: &[]cyclonedx.Property{{Name: "generator", Value: "example"}},
+			},
+		},
+		Components: &[]cyclonedx.Component{
+			{
+				Name:       "a",
+				Properties: &[]cyclonedx.Property{{Name: "cdx:maven:package:test", Value: "true"}},
+			},
+			{
+				Name: "b",
+				Components: &[]cyclonedx.Component{
+					{
+						Name:       "b1",
+						Properties: &[]cyclonedx.Property{{Name: "cdx:maven:package:test", Value: "false"}},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		filters  Filters
+		expected int
+	}{
+		{
+			name:     "NameAndValue",
+			filters:  Filters{Properties: []string{"cdx:maven:package:test=true"}},
+			expected: 1,
+		},
+		{
+			name:     "NameOnly",
+			filters:  Filters{RequiredProperties: []string{"cdx:maven:package:test"}},
+			expected: 2,
+		},
+		{
+			name:     "RootComponent",
+			filters:  Filters{Properties: []string{"generator=example"}},
+			expected: 1,
+		},
+		{
+			name:     "Any",
+			filters:  Filters{Properties: []string{"generator=example"}, RequiredProperties: []string{"cdx:maven:package:test"}},
+			expected: 3,
+		},
+		{
+			name:     "NoMatch",
+			filters:  Filters{Properties: []string{"cdx:maven:package:test=maybe"}},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if matches := countPropertyMatches(bom, propertyMatchers(tc.filters)); matches != tc.expected {
+				t.Fatalf("expected %d matches, got %d", tc.expected, matches)
+			}
+		})
+	}
+}
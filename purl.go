@@ -0,0 +1,228 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// purlType returns the type of a package URL, e.g. "maven" for
+// "pkg:maven/org.example/example@1.0.0", or an empty string if it has none.
+func purlType(purl string) string {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return ""
+	}
+	typ, _, ok := strings.Cut(strings.TrimLeft(rest, "/"), "/")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(typ)
+}
+
+// purlNamespace returns the namespace of a package URL, e.g. "org.example" for
+// "pkg:maven/org.example/example@1.0.0", or an empty string if it has none.
+func purlNamespace(purl string) string {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return ""
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, _, _ = strings.Cut(rest, "@")
+	_, rest, ok = strings.Cut(strings.TrimLeft(rest, "/"), "/")
+	if !ok {
+		return ""
+	}
+	i := strings.LastIndex(rest, "/")
+	if i < 0 {
+		return ""
+	}
+	namespace, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return ""
+	}
+	return namespace
+}
+
+// mavenPurlGAV returns the coordinates of a Maven package URL, e.g. org.example:example:1.0.0 for
+// "pkg:maven/org.example/example@1.0.0?type=jar". Qualifiers and subpath are ignored.
+// ok is false if purl is not a Maven package URL with namespace, name and version.
+func mavenPurlGAV(purl string) (gav GAV, ok bool) {
+	if purlType(purl) != "maven" {
+		return GAV{}, false
+	}
+	group := purlNamespace(purl)
+	rest, _, _ := strings.Cut(purl, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, version, hasVersion := strings.Cut(rest, "@")
+	if group == "" || !hasVersion {
+		return GAV{}, false
+	}
+
+	artifact, err := url.PathUnescape(rest[strings.LastIndex(rest, "/")+1:])
+	if err != nil || artifact == "" {
+		return GAV{}, false
+	}
+	if version, err = url.PathUnescape(version); err != nil || version == "" {
+		return GAV{}, false
+	}
+	return GAV{GroupID: group, ArtifactID: artifact, Version: version}, true
+}
+
+// componentsInGroups returns the package URLs of all Maven components of bom,
+// including nested ones, whose group is matched by groups (-contains-group).
+// The root component is not considered.
+func componentsInGroups(bom *cyclonedx.BOM, groups GroupList) []string {
+	var matched []string
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			if purlType(component.PackageURL) == "maven" {
+				if group := purlNamespace(component.PackageURL); group != "" && groups.Matches(group) {
+					matched = append(matched, component.PackageURL)
+				}
+			}
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+	return matched
+}
+
+// filterPurlTypes removes all components from bom whose package URL is not of one
+// of the given types, including components without package URL.
+// Nested components are filtered as well, and dependencies on removed components are dropped.
+// It returns the number of removed components.
+func filterPurlTypes(bom *cyclonedx.BOM, types []string) int {
+	allowed := make(map[string]bool, len(types))
+	for _, typ := range types {
+		allowed[strings.ToLower(typ)] = true
+	}
+
+	return filterComponents(bom, func(component cyclonedx.Component) bool {
+		return allowed[purlType(component.PackageURL)]
+	})
+}
+
+// filterComponents removes all components from bom for which keep returns false,
+// along with their nested components. Dependencies on removed components are dropped.
+// It returns the number of removed components.
+func filterComponents(bom *cyclonedx.BOM, keep func(cyclonedx.Component) bool) int {
+	removedRefs := make(map[string]bool)
+	removed := filterNestedComponents(bom.Components, keep, removedRefs)
+	if bom.Dependencies == nil || len(removedRefs) == 0 {
+		return removed
+	}
+
+	dependencies := make([]cyclonedx.Dependency, 0, len(*bom.Dependencies))
+	for _, dep := range *bom.Dependencies {
+		if removedRefs[dep.Ref] {
+			continue
+		}
+		if dep.Dependencies != nil {
+			dependsOn := make([]string, 0, len(*dep.Dependencies))
+			for _, ref := range *dep.Dependencies {
+				if !removedRefs[ref] {
+					dependsOn = append(dependsOn, ref)
+				}
+			}
+			dep.Dependencies = &dependsOn
+		}
+		dependencies = append(dependencies, dep)
+	}
+	bom.Dependencies = &dependencies
+
+	return removed
+}
+
+func filterNestedComponents(components *[]cyclonedx.Component, keep func(cyclonedx.Component) bool, removedRefs map[string]bool) int {
+	if components == nil {
+		return 0
+	}
+
+	removed := 0
+	kept := make([]cyclonedx.Component, 0, len(*components))
+	for _, component := range *components {
+		if !keep(component) {
+			removed += 1 + countComponents(component.Components, true)
+			collectRefs(component, removedRefs)
+			continue
+		}
+		removed += filterNestedComponents(component.Components, keep, removedRefs)
+		kept = append(kept, component)
+	}
+	*components = kept
+
+	return removed
+}
+
+// collectRefs adds the bom-refs of component and all of its nested components to refs.
+func collectRefs(component cyclonedx.Component, refs map[string]bool) {
+	if component.BOMRef != "" {
+		refs[component.BOMRef] = true
+	}
+	if component.Components != nil {
+		for _, nested := range *component.Components {
+			collectRefs(nested, refs)
+		}
+	}
+}
+
+// rawPurlProperty holds the original package URL of a component
+// whose qualifiers were stripped (-keep-raw).
+const rawPurlProperty = "cdx-central:purl:raw"
+
+// stripQualifiers removes the qualifiers (e.g. ?type=jar&classifier=sources) from purl.
+// The subpath is retained.
+func stripQualifiers(purl string) string {
+	rest, subpath, hasSubpath := strings.Cut(purl, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	if hasSubpath {
+		return rest + "#" + subpath
+	}
+	return rest
+}
+
+// stripPurlQualifiers removes the qualifiers from the package URLs of all components
+// of bom, including the root component and nested components. With keepRaw, the original
+// package URL is preserved in a property. It returns the number of modified components.
+func stripPurlQualifiers(bom *cyclonedx.BOM, keepRaw bool) int {
+	stripped := 0
+	var visit func(component *cyclonedx.Component)
+	visit = func(component *cyclonedx.Component) {
+		if purl := stripQualifiers(component.PackageURL); purl != component.PackageURL {
+			if keepRaw {
+				var properties []cyclonedx.Property
+				if component.Properties != nil {
+					properties = *component.Properties
+				}
+				properties = append(properties, cyclonedx.Property{Name: rawPurlProperty, Value: component.PackageURL})
+				component.Properties = &properties
+			}
+			component.PackageURL = purl
+			stripped++
+		}
+
+		if component.Components != nil {
+			for i := range *component.Components {
+				visit(&(*component.Components)[i])
+			}
+		}
+	}
+
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		visit(bom.Metadata.Component)
+	}
+	if bom.Components != nil {
+		for i := range *bom.Components {
+			visit(&(*bom.Components)[i])
+		}
+	}
+
+	return stripped
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestPurlType(t *testing.T) {
+	testCases := map[string]string{
+		"pkg:maven/org.example/example@1.0.0": "maven",
+		"pkg:NPM/example@1.0.0":               "npm",
+		"pkg://golang/example.com/foo@v1":     "golang",
+		"pkg:generic":                         "",
+		"cpe:2.3:a:example:example:1.0.0":     "",
+		"":                                    "",
+	}
+
+	for purl, expected := range testCases {
+		if typ := purlType(purl); typ != expected {
+			t.Errorf("purlType(%q): expected %q, got %q", purl, expected, typ)
+		}
+	}
+}
+
+func TestPurlNamespace(t *testing.T) {
+	testCases := map[string]string{
+		"pkg:maven/org.example/example@1.0.0?type=jar":      "org.example",
+		"pkg:npm/%40angular/core@1.0.0":                     "@angular",
+		"pkg:golang/example.com/foo/bar@v1#sub/path":        "example.com/foo",
+		"pkg:maven/com.fasterxml.jackson.core/jackson-core": "com.fasterxml.jackson.core",
+		"pkg:npm/example@1.0.0":                             "",
+		"pkg:generic":                                       "",
+		"":                                                  "",
+	}
+
+	for purl, expected := range testCases {
+		if namespace := purlNamespace(purl); namespace != expected {
+			t.Errorf("purlNamespace(%q): expected %q, got %q", purl, expected, namespace)
+		}
+	}
+}
+
+func TestMavenPurlGAV(t *testing.T) {
+	testCases := map[string]*GAV{
+		"pkg:maven/org.example/example@1.0.0?type=jar#sub": {GroupID: "org.example", ArtifactID: "example", Version: "1.0.0"},
+		"pkg:maven/org.example/ex%20ample@1.0.0%2B1":       {GroupID: "org.example", ArtifactID: "ex ample", Version: "1.0.0+1"},
+		"pkg:maven/org.example/example":                    nil,
+		"pkg:maven/example@1.0.0":                          nil,
+		"pkg:maven/org.example/@1.0.0":                     nil,
+		"pkg:npm/%40angular/core@1.0.0":                    nil,
+		"":                                                 nil,
+	}
+
+	for purl, expected := range testCases {
+		gav, ok := mavenPurlGAV(purl)
+		if expected == nil {
+			if ok {
+				t.Errorf("mavenPurlGAV(%q): expected no coordinates, got %s", purl, gav)
+			}
+		} else if !ok || gav != *expected {
+			t.Errorf("mavenPurlGAV(%q): expected %s, got %s (%v)", purl, expected, gav, ok)
+		}
+	}
+}
+
+func TestComponentsInGroups(t *testing.T) {
+	bom := cyclonedx.BOM{
+		Metadata: &cyclonedx.Metadata{
+			Component: &cyclonedx.Component{PackageURL: "pkg:maven/com.fasterxml.jackson/root@1.0.0"},
+		},
+		Components: &[]cyclonedx.Component{
+			{
+				PackageURL: "pkg:maven/org.example/a@1.0.0",
+				Components: &[]cyclonedx.Component{
+					{PackageURL: "pkg:maven/com.fasterxml.jackson.core/jackson-core@2.15.0"},
+				},
+			},
+			{PackageURL: "pkg:maven/com.fasterxml.jacksonfoo/b@1.0.0"},
+			{PackageURL: "pkg:npm/com.fasterxml.jackson/c@1.0.0"},
+			{PackageURL: "pkg:maven/com.fasterxml.jackson/d@1.0.0"},
+		},
+	}
+
+	matched := componentsInGroups(&bom, GroupList{"com.fasterxml.jackson"})
+	expected := []string{
+		"pkg:maven/com.fasterxml.jackson.core/jackson-core@2.15.0",
+		"pkg:maven/com.fasterxml.jackson/d@1.0.0",
+	}
+	if !reflect.DeepEqual(matched, expected) {
+		t.Errorf("expected %v, got %v", expected, matched)
+	}
+}
+
+func TestFilterPurlTypes(t *testing.T) {
+	bom := cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{
+				BOMRef:     "a",
+				PackageURL: "pkg:maven/org.example/a@1.0.0",
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "a-nested", PackageURL: "pkg:npm/a-nested@1.0.0"},
+				},
+			},
+			{
+				BOMRef:     "b",
+				PackageURL: "pkg:npm/b@1.0.0",
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "b-nested", PackageURL: "pkg:maven/org.example/b-nested@1.0.0"},
+				},
+			},
+			{BOMRef: "c"},
+		},
+		Dependencies: &[]cyclonedx.Dependency{
+			{Ref: "a", Dependencies: &[]string{"a-nested", "b", "c"}},
+			{Ref: "b", Dependencies: &[]string{"b-nested"}},
+		},
+	}
+
+	removed := filterPurlTypes(&bom, []string{"maven"})
+	if removed != 4 {
+		t.Errorf("expected 4 components to be removed, got %d", removed)
+	}
+
+	if len(*bom.Components) != 1 || (*bom.Components)[0].BOMRef != "a" {
+		t.Fatalf("expected only component a to be kept, got %v", *bom.Components)
+	}
+	if nested := (*bom.Components)[0].Components; len(*nested) != 0 {
+		t.Errorf("expected nested components of a to be removed, got %v", *nested)
+	}
+
+	expectedDeps := []cyclonedx.Dependency{
+		{Ref: "a", Dependencies: &[]string{}},
+	}
+	if !reflect.DeepEqual(*bom.Dependencies, expectedDeps) {
+		t.Errorf("expected dependencies %v, got %v", expectedDeps, *bom.Dependencies)
+	}
+}
+
+func TestStripQualifiers(t *testing.T) {
+	testCases := map[string]string{
+		"pkg:maven/org.example/a@1.0.0?type=jar&classifier=sources": "pkg:maven/org.example/a@1.0.0",
+		"pkg:maven/org.example/a@1.0.0?type=jar#sub/path":           "pkg:maven/org.example/a@1.0.0#sub/path",
+		"pkg:maven/org.example/a@1.0.0":                             "pkg:maven/org.example/a@1.0.0",
+		"":                                                          "",
+	}
+
+	for purl, expected := range testCases {
+		if stripped := stripQualifiers(purl); stripped != expected {
+			t.Errorf("stripQualifiers(%q): expected %q, got %q", purl, expected, stripped)
+		}
+	}
+}
+
+func TestStripPurlQualifiers(t *testing.T) {
+	bom := cyclonedx.BOM{
+		Metadata: &cyclonedx.Metadata{
+			Component: &cyclonedx.Component{PackageURL: "pkg:maven/org.example/root@1.0.0?type=pom"},
+		},
+		Components: &[]cyclonedx.Component{
+			{
+				PackageURL: "pkg:maven/org.example/a@1.0.0",
+				Components: &[]cyclonedx.Component{
+					{
+						PackageURL: "pkg:maven/org.example/b@1.0.0?type=jar",
+						Properties: &[]cyclonedx.Property{{Name: "foo", Value: "bar"}},
+					},
+				},
+			},
+		},
+	}
+
+	if stripped := stripPurlQualifiers(&bom, true); stripped != 2 {
+		t.Fatalf("expected 2 stripped purls, got %d", stripped)
+	}
+	if purl := bom.Metadata.Component.PackageURL; purl != "pkg:maven/org.example/root@1.0.0" {
+		t.Errorf("unexpected root purl %q", purl)
+	}
+	if (*bom.Components)[0].Properties != nil {
+		t.Errorf("expected no property for unchanged purl")
+	}
+
+	nested := (*(*bom.Components)[0].Components)[0]
+	expected := []cyclonedx.Property{
+		{Name: "foo", Value: "bar"},
+		{Name: rawPurlProperty, Value: "pkg:maven/org.example/b@1.0.0?type=jar"},
+	}
+	if nested.PackageURL != "pkg:maven/org.example/b@1.0.0" || !reflect.DeepEqual(*nested.Properties, expected) {
+		t.Errorf("unexpected nested component %+v", nested)
+	}
+}
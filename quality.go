@@ -0,0 +1,63 @@
+package main
+
+import "github.com/CycloneDX/cyclonedx-go"
+
+// Points awarded by qualityScore, adding up to 100.
+const (
+	qualityPointsTimestamp = 10
+	qualityPointsAuthors   = 10
+	qualityPointsSupplier  = 10
+	qualityPointsTools     = 10
+	qualityPointsRootPurl  = 20
+	qualityPointsLicenses  = 20 // scaled by the fraction of components with licenses
+	qualityPointsHashes    = 20 // scaled by the fraction of components with hashes
+)
+
+// qualityScore rates the metadata completeness of bom from 0 to 100.
+// The rubric is documented in the README.
+func qualityScore(bom *cyclonedx.BOM) float64 {
+	score := 0.0
+	if metadata := bom.Metadata; metadata != nil {
+		if metadata.Timestamp != "" {
+			score += qualityPointsTimestamp
+		}
+		if metadata.Authors != nil && len(*metadata.Authors) > 0 {
+			score += qualityPointsAuthors
+		}
+		if metadata.Supplier != nil && metadata.Supplier.Name != "" {
+			score += qualityPointsSupplier
+		}
+		if metadata.Tools != nil && len(*metadata.Tools) > 0 {
+			score += qualityPointsTools
+		}
+		if metadata.Component != nil && metadata.Component.PackageURL != "" {
+			score += qualityPointsRootPurl
+		}
+	}
+
+	total, withLicenses, withHashes := 0, 0, 0
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			total++
+			if component.Licenses != nil && len(*component.Licenses) > 0 {
+				withLicenses++
+			}
+			if component.Hashes != nil && len(*component.Hashes) > 0 {
+				withHashes++
+			}
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+
+	if total > 0 {
+		score += qualityPointsLicenses * float64(withLicenses) / float64(total)
+		score += qualityPointsHashes * float64(withHashes) / float64(total)
+	}
+
+	return score
+}
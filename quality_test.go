@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestQualityScore(t *testing.T) {
+	licensed := cyclonedx.Component{
+		Name:     "a",
+		Licenses: &cyclonedx.Licenses{{License: &cyclonedx.License{ID: "Apache-2.0"}}},
+		Hashes:   &[]cyclonedx.Hash{{Algorithm: cyclonedx.HashAlgoSHA256, Value: "00"}},
+	}
+	bare := cyclonedx.Component{Name: "b"}
+
+	testCases := []struct {
+		name     string
+		bom      cyclonedx.BOM
+		expected float64
+	}{
+		{
+			name:     "Empty",
+			bom:      cyclonedx.BOM{},
+			expected: 0,
+		},
+		{
+			name: "Complete",
+			bom: cyclonedx.BOM{
+				Metadata: &cyclonedx.Metadata{
+					Timestamp: "2023-01-01T00:00:00Z",
+					Authors:   &[]cyclonedx.OrganizationalContact{{Name: "Jane Doe"}},
+					Supplier:  &cyclonedx.OrganizationalEntity{Name: "Example"},
+					Tools:     &[]cyclonedx.Tool{{Name: "cyclonedx-maven-plugin"}},
+					Component: &cyclonedx.Component{PackageURL: "pkg:maven/org.example/root@1.0.0"},
+				},
+				Components: &[]cyclonedx.Component{licensed},
+			},
+			expected: 100,
+		},
+		{
+			name: "PartialComponents",
+			bom: cyclonedx.BOM{
+				Metadata: &cyclonedx.Metadata{Timestamp: "2023-01-01T00:00:00Z"},
+				Components: &[]cyclonedx.Component{
+					{Name: "c", Components: &[]cyclonedx.Component{licensed}},
+					bare,
+					licensed,
+					bare,
+				},
+			},
+			expected: 10 + 20*0.4 + 20*0.4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if score := qualityScore(&tc.bom); score != tc.expected {
+				t.Fatalf("expected %g, got %g", tc.expected, score)
+			}
+		})
+	}
+}
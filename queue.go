@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Records of the -queue-file journal, one per line with tab-separated fields.
+const (
+	queueRecordAdd        = "A" // A <group> <artifact> <latest version>: artifact was enqueued
+	queueRecordDone       = "D" // D <group> <artifact>: all versions of artifact were processed
+	queueRecordSearch     = "S" // S <start> <cursor>: the artifact search continues at this position
+	queueRecordSearchDone = "E" // E: all search results were enqueued
+)
+
+// artifactQueue is a FIFO queue of artifacts that is persisted to a journal file,
+// so that only artifacts whose processing hadn't completed are processed
+// when a crawl is resumed. While crawling, only the artifacts currently being
+// processed are held in memory. It is safe for concurrent use.
+type artifactQueue struct {
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	file *os.File
+
+	// readOffset and writeOffset are the positions of the next record
+	// to read and write, respectively. reader reads the records up to readEnd,
+	// which are known to be complete.
+	reader      *bufio.Reader
+	readOffset  int64
+	readEnd     int64
+	writeOffset int64
+
+	inFlight    map[string]bool
+	pending     int
+	searchPos   searchPosition
+	searchDone  bool
+	searchEnded bool // no more artifacts will be enqueued during this run
+	closed      bool
+}
+
+// openArtifactQueue opens the queue journaled in path, creating it if it doesn't exist.
+// An existing journal is compacted, so that it only holds the artifacts
+// that are still pending, in their original order.
+func openArtifactQueue(path string) (*artifactQueue, error) {
+	q := &artifactQueue{inFlight: make(map[string]bool), searchPos: firstSearchPosition}
+	q.cond = sync.NewCond(&q.mux)
+
+	pending, err := q.replay(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compact the journal by writing the pending artifacts to a new one.
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	for _, artifact := range pending {
+		q.writeOffset += int64(writeQueueRecord(w, queueRecordAdd, artifact.GroupID, artifact.ArtifactID, artifact.LatestVersion))
+	}
+	q.writeOffset += int64(writeQueueRecord(w, queueRecordSearch, strconv.Itoa(q.searchPos.Start), q.searchPos.Cursor))
+	if q.searchDone {
+		q.writeOffset += int64(writeQueueRecord(w, queueRecordSearchDone))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return nil, err
+	}
+
+	q.file, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	q.pending = len(pending)
+	q.searchEnded = q.searchDone
+
+	return q, nil
+}
+
+// replay reads the journal in path, if it exists,
+// and returns the artifacts that are still pending.
+func (q *artifactQueue) replay(path string) ([]Artifact, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var artifacts []Artifact
+	done := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch {
+		case fields[0] == queueRecordAdd && len(fields) == 4:
+			artifacts = append(artifacts, Artifact{GroupID: fields[1], ArtifactID: fields[2], LatestVersion: fields[3]})
+		case fields[0] == queueRecordDone && len(fields) == 3:
+			done[Artifact{GroupID: fields[1], ArtifactID: fields[2]}.String()] = true
+		case fields[0] == queueRecordSearch && len(fields) == 3:
+			q.searchPos.Start, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid search start: %w", path, line, err)
+			}
+			q.searchPos.Cursor = fields[2]
+		case fields[0] == queueRecordSearch && len(fields) == 2:
+			// Journals written before cursors were used only record the offset.
+			q.searchPos.Start, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid search start: %w", path, line, err)
+			}
+			q.searchPos.Cursor = ""
+		case fields[0] == queueRecordSearchDone && len(fields) == 1:
+			q.searchDone = true
+		default:
+			return nil, fmt.Errorf("%s:%d: invalid record %q", path, line, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []Artifact
+	for _, artifact := range artifacts {
+		if !done[artifact.String()] {
+			pending = append(pending, artifact)
+		}
+	}
+	return pending, nil
+}
+
+func writeQueueRecord(w io.Writer, fields ...string) int {
+	n, _ := io.WriteString(w, strings.Join(fields, "\t")+"\n")
+	return n
+}
+
+// SearchPosition returns where the artifact search is to continue, and whether it already completed.
+func (q *artifactQueue) SearchPosition() (searchPosition, bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.searchPos, q.searchDone
+}
+
+// Pending returns the number of artifacts that were enqueued, but not processed yet.
+func (q *artifactQueue) Pending() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.pending
+}
+
+// Push enqueues artifacts, and records that the search continues at next.
+func (q *artifactQueue) Push(artifacts []Artifact, next searchPosition) error {
+	var buf strings.Builder
+	for _, artifact := range artifacts {
+		writeQueueRecord(&buf, queueRecordAdd, artifact.GroupID, artifact.ArtifactID, artifact.LatestVersion)
+	}
+	writeQueueRecord(&buf, queueRecordSearch, strconv.Itoa(next.Start), next.Cursor)
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	err := q.append(buf.String())
+	if err != nil {
+		return err
+	}
+	q.searchPos = next
+	q.pending += len(artifacts)
+	q.cond.Broadcast()
+	return nil
+}
+
+// EndSearch signals that no more artifacts will be enqueued during this run.
+// If complete is set, the search is recorded as completed, so that it's not
+// performed again when the crawl is resumed.
+func (q *artifactQueue) EndSearch(complete bool) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.searchEnded = true
+	q.cond.Broadcast()
+	if !complete {
+		return nil
+	}
+	q.searchDone = true
+	return q.append(queueRecordSearchDone + "\n")
+}
+
+// Pop returns the next artifact, waiting for it to be enqueued if necessary.
+// It returns false once all artifacts were popped and the search ended,
+// or if ctx was cancelled.
+func (q *artifactQueue) Pop(ctx context.Context) (Artifact, bool, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	for {
+		for q.readOffset >= q.writeOffset && !q.searchEnded && !q.closed && ctx.Err() == nil {
+			q.cond.Wait()
+		}
+		if q.readOffset >= q.writeOffset || q.closed || ctx.Err() != nil {
+			return Artifact{}, false, nil
+		}
+
+		if q.readOffset >= q.readEnd {
+			q.reader = bufio.NewReader(io.NewSectionReader(q.file, q.readOffset, q.writeOffset-q.readOffset))
+			q.readEnd = q.writeOffset
+		}
+		line, err := q.reader.ReadString('\n')
+		if err != nil {
+			return Artifact{}, false, fmt.Errorf("failed to read -queue-file: %w", err)
+		}
+		q.readOffset += int64(len(line))
+
+		fields := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+		if fields[0] != queueRecordAdd || len(fields) != 4 {
+			continue
+		}
+		artifact := Artifact{GroupID: fields[1], ArtifactID: fields[2], LatestVersion: fields[3]}
+		q.inFlight[artifact.String()] = true
+		return artifact, true, nil
+	}
+}
+
+// Done records that all versions of artifact were processed.
+func (q *artifactQueue) Done(artifact Artifact) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if !q.inFlight[artifact.String()] {
+		return nil
+	}
+	delete(q.inFlight, artifact.String())
+	q.pending--
+
+	var buf strings.Builder
+	writeQueueRecord(&buf, queueRecordDone, artifact.GroupID, artifact.ArtifactID)
+	return q.append(buf.String())
+}
+
+// append writes records to the journal, and syncs it so that they survive a crash.
+// The caller must hold q.mux.
+func (q *artifactQueue) append(records string) error {
+	n, err := q.file.WriteString(records)
+	q.writeOffset += int64(n)
+	if err == nil {
+		err = q.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write -queue-file: %w", err)
+	}
+	return nil
+}
+
+// wakeOnDone wakes up Pop once ctx is done, so it can return.
+func (q *artifactQueue) wakeOnDone(ctx context.Context) {
+	<-ctx.Done()
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.cond.Broadcast()
+}
+
+func (q *artifactQueue) Close() error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+	return q.file.Close()
+}
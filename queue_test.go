@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.txt")
+	a := Artifact{GroupID: "org.example", ArtifactID: "a", LatestVersion: "1.0.0"}
+	b := Artifact{GroupID: "org.example", ArtifactID: "b", LatestVersion: "2.0.0"}
+	c := Artifact{GroupID: "org.example", ArtifactID: "c"}
+
+	queue, err := openArtifactQueue(path)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	if pos, done := queue.SearchPosition(); pos != firstSearchPosition || done {
+		t.Fatalf("expected a new search, got position %+v (done: %t)", pos, done)
+	}
+	if err := queue.Push([]Artifact{a, b}, searchPosition{Cursor: "AoE1", Start: 150}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	if err := queue.Push([]Artifact{c}, searchPosition{Cursor: "AoE2", Start: 300}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	for _, expected := range []Artifact{a, b} {
+		artifact, ok, err := queue.Pop(context.Background())
+		if err != nil || !ok || artifact != expected {
+			t.Fatalf("expected to pop %s, got %s (ok: %t, err: %v)", expected, artifact, ok, err)
+		}
+	}
+	if err := queue.Done(a); err != nil {
+		t.Fatalf("failed to mark %s as done: %v", a, err)
+	}
+	if pending := queue.Pending(); pending != 2 {
+		t.Fatalf("expected 2 pending artifacts, got %d", pending)
+	}
+	if err := queue.Close(); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	// b was popped but not done, so it must be processed again.
+	queue, err = openArtifactQueue(path)
+	if err != nil {
+		t.Fatalf("failed to reopen queue: %v", err)
+	}
+	if pos, done := queue.SearchPosition(); pos.Cursor != "AoE2" || pos.Start != 300 || done {
+		t.Fatalf("expected search to continue at AoE2, got position %+v (done: %t)", pos, done)
+	}
+	if err := queue.EndSearch(true); err != nil {
+		t.Fatalf("failed to end search: %v", err)
+	}
+	for _, expected := range []Artifact{b, c} {
+		artifact, ok, err := queue.Pop(context.Background())
+		if err != nil || !ok || artifact != expected {
+			t.Fatalf("expected to pop %s, got %s (ok: %t, err: %v)", expected, artifact, ok, err)
+		}
+		if err := queue.Done(artifact); err != nil {
+			t.Fatalf("failed to mark %s as done: %v", artifact, err)
+		}
+	}
+	if _, ok, _ := queue.Pop(context.Background()); ok {
+		t.Fatal("expected queue to be drained")
+	}
+	if err := queue.Close(); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	queue, err = openArtifactQueue(path)
+	if err != nil {
+		t.Fatalf("failed to reopen queue: %v", err)
+	}
+	defer queue.Close()
+	if _, done := queue.SearchPosition(); !done || queue.Pending() != 0 {
+		t.Fatalf("expected a completed crawl, got %d pending artifacts (search done: %t)", queue.Pending(), done)
+	}
+}
+
+func TestArtifactQueueOffsetRecord(t *testing.T) {
+	// Journals written before cursors were used record only the offset of the search.
+	path := filepath.Join(t.TempDir(), "queue.txt")
+	if err := os.WriteFile(path, []byte("A\torg.example\ta\t1.0.0\nS\t150\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	queue, err := openArtifactQueue(path)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer queue.Close()
+	if pos, done := queue.SearchPosition(); pos != (searchPosition{Start: 150}) || done {
+		t.Fatalf("expected search to continue at offset 150, got position %+v (done: %t)", pos, done)
+	}
+	if pending := queue.Pending(); pending != 1 {
+		t.Fatalf("expected 1 pending artifact, got %d", pending)
+	}
+}
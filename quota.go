@@ -0,0 +1,47 @@
+package main
+
+import "sync"
+
+// groupQuota limits the number of SBOMs kept per group.
+// It is safe for concurrent use.
+type groupQuota struct {
+	max int
+
+	mux    sync.Mutex
+	counts map[string]int
+}
+
+func newGroupQuota(max int) *groupQuota {
+	return &groupQuota{
+		max:    max,
+		counts: make(map[string]int),
+	}
+}
+
+// Exhausted determines whether the quota for group has been used up.
+// It always returns false when no quota is configured.
+func (q *groupQuota) Exhausted(group string) bool {
+	if q.max <= 0 {
+		return false
+	}
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.counts[group] >= q.max
+}
+
+// Take attempts to take one unit of the quota for group.
+// The second return value reports whether the quota is exhausted after taking.
+func (q *groupQuota) Take(group string) (ok bool, exhausted bool) {
+	if q.max <= 0 {
+		return true, false
+	}
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.counts[group] >= q.max {
+		return false, true
+	}
+	q.counts[group]++
+	return true, q.counts[group] >= q.max
+}
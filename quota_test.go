@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestGroupQuota(t *testing.T) {
+	q := newGroupQuota(2)
+
+	if ok, exhausted := q.Take("org.example"); !ok || exhausted {
+		t.Fatalf("expected first take to succeed without exhausting the quota")
+	}
+	if ok, exhausted := q.Take("org.example"); !ok || !exhausted {
+		t.Fatalf("expected second take to succeed and exhaust the quota")
+	}
+	if ok, _ := q.Take("org.example"); ok {
+		t.Fatalf("expected third take to fail")
+	}
+	if !q.Exhausted("org.example") {
+		t.Fatalf("expected quota of org.example to be exhausted")
+	}
+	if q.Exhausted("com.example") {
+		t.Fatalf("expected quota of com.example not to be exhausted")
+	}
+}
+
+func TestGroupQuotaUnlimited(t *testing.T) {
+	q := newGroupQuota(0)
+	for i := 0; i < 100; i++ {
+		if ok, exhausted := q.Take("org.example"); !ok || exhausted {
+			t.Fatalf("expected unlimited quota to never be exhausted")
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// safely calls fn and recovers from any panic in it. A recovered panic
+// is logged with its stack trace, counted, and returned as an error,
+// so that a single malformed SBOM can't crash a long-running crawl.
+func safely(gav GAV, summary *Summary, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("recovered from panic while processing %s: %v\n%s", gav, r, debug.Stack())
+			summary.AddPanic()
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn()
+}
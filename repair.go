@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func runRepair(args []string) {
+	suffixes := []string{"-cyclonedx.json", ".cdx.json"}
+	var fallbackBaseURLs []string
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s repair:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "  %s repair [flags] INDEX DIR\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Var((*listFlag)(&suffixes), "sbom-suffixes", "Comma-separated list of file name suffixes to try, in order, when downloading SBOMs")
+	fs.Var((*listFlag)(&fallbackBaseURLs), "fallback-base-urls", "Comma-separated list of repository URLs to try, in order, when an SBOM is not found on Maven Central")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	index, err := ReadIndexFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("failed to read index: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo := mavenCentral(newHTTPClient(false, nil))
+	repo.FallbackBaseURLs = fallbackBaseURLs
+	stats := repairIndex(ctx, repo, index, fs.Arg(1), suffixes)
+	fmt.Printf("%d verified, %d repaired, %d failed, %d skipped\n", stats.verified, stats.repaired, stats.failed, stats.skipped)
+	if stats.failed > 0 {
+		os.Exit(1)
+	}
+}
+
+type repairStats struct {
+	verified int
+	repaired int
+	failed   int
+	skipped  int
+}
+
+var errRepairMismatch = errors.New("re-downloaded sbom doesn't match the index")
+
+// repairIndex verifies that the SBOM of each entry in index exists in dir and matches
+// its recorded hash, and re-downloads it if it doesn't.
+// SBOMs written to archives are skipped.
+func repairIndex(ctx context.Context, repo Repository, index *Index, dir string, suffixes []string) repairStats {
+	var stats repairStats
+	for _, entry := range index.SBOMs {
+		if ctx.Err() != nil {
+			break
+		}
+		gav := GAV{GroupID: entry.GroupID, ArtifactID: entry.ArtifactID, Version: entry.Version}
+		if entry.Archive != "" {
+			debugf("skipping %s because it was written to archive %s", gav, entry.Archive)
+			stats.skipped++
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(entry.File))
+		problem := verifyIndexEntry(path, entry)
+		if problem == "" {
+			stats.verified++
+			continue
+		}
+
+		log.Printf("repairing %s: %s", entry.File, problem)
+		if err := repairIndexEntry(ctx, repo, gav, path, entry, suffixes); err != nil {
+			log.Printf("failed to repair %s: %v", entry.File, err)
+			stats.failed++
+			continue
+		}
+		stats.repaired++
+	}
+	return stats
+}
+
+// verifyIndexEntry checks the SBOM file of entry at path, and describes
+// the problem with it, or returns an empty string if there is none.
+// Entries of older indexes don't have a hash, so only their existence is checked.
+func verifyIndexEntry(path string, entry IndexEntry) string {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "file is missing"
+	}
+	if err != nil {
+		return err.Error()
+	}
+	if entry.SHA256 != "" && sha256Hex(data) != entry.SHA256 {
+		return "file is corrupted"
+	}
+	return ""
+}
+
+// repairIndexEntry re-downloads the SBOM of gav, and writes it to path if it's the one recorded in entry.
+// Without a recorded hash, it must have the recorded size, and must not have been modified when written.
+func repairIndexEntry(ctx context.Context, repo Repository, gav GAV, path string, entry IndexEntry, suffixes []string) error {
+	data, _, _, err := fetchSBOMBytes(ctx, repo, gav, repairSuffixes(suffixes, entry.File), NewSummary())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case entry.SHA256 != "":
+		if sha256Hex(data) != entry.SHA256 {
+			return fmt.Errorf("%w: it has a different hash, either because it was modified when written, or because it changed since", errRepairMismatch)
+		}
+	case entry.CompactedSize > 0 || entry.CanonicalSHA256 != "":
+		return fmt.Errorf("%w: it was modified when written", errRepairMismatch)
+	case len(data) != entry.Size:
+		return fmt.Errorf("%w: it has %d bytes, but %d were recorded", errRepairMismatch, len(data), entry.Size)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, false, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// repairSuffixes returns the suffixes that yield SBOMs in the format of fileName.
+func repairSuffixes(suffixes []string, fileName string) []string {
+	format := suffixFormat(fileName)
+	matching := make([]string, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		if suffixFormat(suffix) == format {
+			matching = append(matching, suffix)
+		}
+	}
+	if len(matching) == 0 && strings.HasSuffix(fileName, ".xml") {
+		matching = append(matching, xmlFallbackSuffix)
+	}
+	return matching
+}
+
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
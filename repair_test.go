@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepairIndex(t *testing.T) {
+	const sbom = `{"bomFormat": "CycloneDX", "specVersion": "1.4"}`
+	repo := newFixtureRepository(t, map[string]fixture{
+		"org/example/missing/1.0.0/missing-1.0.0-cyclonedx.json":        {body: sbom},
+		"org/example/corrupted/1.0.0/corrupted-1.0.0-cyclonedx.json":    {body: sbom},
+		"org/example/legacy/1.0.0/legacy-1.0.0-cyclonedx.json":          {body: sbom},
+		"org/example/changed/1.0.0/changed-1.0.0-cyclonedx.json":        {body: sbom + "\n"},
+		"org/example/compacted/1.0.0/compacted-1.0.0-cyclonedx.json":    {body: sbom},
+		"org/example/unavailable/1.0.0/unavailable-1.0.0-cyclonedx.xml": {status: 500},
+	}, nil)
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("intact.cdx.json", sbom)
+	write("corrupted.cdx.json", sbom[:10])
+
+	index := NewIndex()
+	entry := func(artifact, file string) IndexEntry {
+		return IndexEntry{GroupID: "org.example", ArtifactID: artifact, Version: "1.0.0", File: file, Size: len(sbom), SHA256: sha256Hex([]byte(sbom))}
+	}
+	index.Add(entry("intact", "intact.cdx.json"))
+	index.Add(entry("missing", "nested/missing.cdx.json"))
+	index.Add(entry("corrupted", "corrupted.cdx.json"))
+	legacy := entry("legacy", "legacy.cdx.json")
+	legacy.SHA256 = ""
+	index.Add(legacy)
+	index.Add(entry("changed", "changed.cdx.json"))
+	compacted := entry("compacted", "compacted.cdx.json")
+	compacted.SHA256 = ""
+	compacted.CompactedSize = 10
+	index.Add(compacted)
+	index.Add(entry("unavailable", "unavailable.cdx.xml"))
+	archived := entry("archived", "archived.cdx.json")
+	archived.Archive = "sboms-0001.zip"
+	index.Add(archived)
+
+	stats := repairIndex(context.Background(), repo, index, dir, []string{"-cyclonedx.json"})
+
+	expected := repairStats{verified: 1, repaired: 3, failed: 3, skipped: 1}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+	for _, name := range []string{"nested/missing.cdx.json", "corrupted.cdx.json", "legacy.cdx.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("expected %s to be repaired: %v", name, err)
+		} else if string(data) != sbom {
+			t.Errorf("expected %s to be repaired, got %q", name, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "changed.cdx.json")); !os.IsNotExist(err) {
+		t.Errorf("expected changed.cdx.json not to be written, got %v", err)
+	}
+}
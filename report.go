@@ -0,0 +1,147 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// reportSchemaVersion is the version of the -summary-file format.
+// It's incremented whenever fields are removed or change their meaning;
+// adding fields doesn't change it. Fields added within a version must not
+// be required by report.schema.json, which TestSummaryWriteReport checks.
+const reportSchemaVersion = 1
+
+// reportSchema is the JSON schema of Report, as printed by the report-schema subcommand.
+//
+//go:embed report.schema.json
+var reportSchema []byte
+
+// Report is the summary of a crawl as written to -summary-file.
+type Report struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	Started        time.Time `json:"started"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+
+	Artifacts            int `json:"artifacts"`
+	Downloaded           int `json:"downloaded"`
+	Written              int `json:"written"`
+	Failed               int `json:"failed"`
+	MissingRootComponent int `json:"missingRootComponent"`
+	Sampled              int `json:"sampled"`
+	SkipListed           int `json:"skipListed"`
+	RateLimited          int `json:"rateLimited"`
+	Panics               int `json:"panics"`
+	EnvelopedSignatures  int `json:"envelopedSignatures"`
+	DetachedSignatures   int `json:"detachedSignatures"`
+
+	// StoppedEarlyBy is the flag whose limit stopped the crawl early, if any.
+	StoppedEarlyBy string `json:"stoppedEarlyBy,omitempty"`
+	// UniqueGroups is the number of distinct groups of the collected SBOMs, with -max-sboms.
+	UniqueGroups int `json:"uniqueGroups,omitempty"`
+
+	Discarded           map[string]int                `json:"discarded"`
+	FilteredArtifacts   map[string]int                `json:"filteredArtifacts"`
+	ExhaustedGroups     []string                      `json:"exhaustedGroups"`
+	DeadlineArtifacts   []string                      `json:"deadlineArtifacts"`
+	StuckWorkers        []string                      `json:"stuckWorkers"`
+	ComponentTypes      map[string]int                `json:"componentTypes"`
+	Licenses            map[string]int                `json:"licenses"`
+	SpecInconsistencies map[string]int                `json:"specInconsistencies"`
+	ToolVersions        map[string]int                `json:"toolVersions"`
+	UnresolvablePurls   map[string][]string           `json:"unresolvablePurls"`
+	TimestampAnomalies  map[string][]TimestampAnomaly `json:"timestampAnomalies"`
+	Concurrency         []ReportConcurrency           `json:"concurrency"`
+}
+
+// ReportConcurrency records that the concurrency was set to Value after ElapsedSeconds.
+type ReportConcurrency struct {
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	Value          int     `json:"value"`
+}
+
+// Report returns a snapshot of the summary.
+func (s *Summary) Report() Report {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	report := Report{
+		SchemaVersion:        reportSchemaVersion,
+		Started:              s.started.UTC(),
+		ElapsedSeconds:       time.Since(s.started).Seconds(),
+		Artifacts:            s.artifacts,
+		Downloaded:           s.downloaded,
+		Written:              s.written,
+		Failed:               s.failed,
+		MissingRootComponent: s.missingRootComponent,
+		Sampled:              s.sampled,
+		SkipListed:           s.skipListed,
+		RateLimited:          s.rateLimited,
+		Panics:               s.panics,
+		EnvelopedSignatures:  s.envelopedSignatures,
+		DetachedSignatures:   s.detachedSignatures,
+		StoppedEarlyBy:       s.stoppedEarlyBy,
+		UniqueGroups:         s.uniqueGroups,
+		Discarded:            copyCounts(s.discarded),
+		FilteredArtifacts:    copyCounts(s.filteredArtifacts),
+		ExhaustedGroups:      make([]string, 0, len(s.exhaustedGroups)),
+		DeadlineArtifacts:    make([]string, 0, len(s.deadlineArtifacts)),
+		StuckWorkers:         append([]string{}, s.stuckWorkers...),
+		ComponentTypes:       copyCounts(s.componentTypes),
+		Licenses:             copyCounts(s.licenses),
+		SpecInconsistencies:  copyCounts(s.specInconsistencies),
+		ToolVersions:         copyCounts(s.toolVersions),
+		UnresolvablePurls:    make(map[string][]string, len(s.unresolvablePurls)),
+		TimestampAnomalies:   make(map[string][]TimestampAnomaly, len(s.timestampAnomalies)),
+		Concurrency:          make([]ReportConcurrency, 0, len(s.concurrency)),
+	}
+	for group := range s.exhaustedGroups {
+		report.ExhaustedGroups = append(report.ExhaustedGroups, group)
+	}
+	sort.Strings(report.ExhaustedGroups)
+	for artifact := range s.deadlineArtifacts {
+		report.DeadlineArtifacts = append(report.DeadlineArtifacts, artifact)
+	}
+	sort.Strings(report.DeadlineArtifacts)
+	for gav, purls := range s.unresolvablePurls {
+		report.UnresolvablePurls[gav] = purls
+	}
+	for artifact, anomalies := range s.timestampAnomalies {
+		report.TimestampAnomalies[artifact] = anomalies
+	}
+	for _, change := range s.concurrency {
+		report.Concurrency = append(report.Concurrency, ReportConcurrency{ElapsedSeconds: change.Elapsed.Seconds(), Value: change.Value})
+	}
+
+	return report
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	copied := make(map[string]int, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
+
+// WriteReport writes the report of the summary to path.
+func (s *Summary) WriteReport(path string) error {
+	return writeFileAtomic(path, false, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s.Report())
+	})
+}
+
+func runReportSchema(args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "report-schema doesn't take any arguments\n")
+		os.Exit(2)
+	}
+	os.Stdout.Write(reportSchema)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSummaryWriteReport(t *testing.T) {
+	summary := NewSummary()
+	summary.AddWritten()
+	summary.AddDiscarded(discardNotSampled)
+	summary.AddGroupQuotaExhausted("org.b")
+	summary.AddGroupQuotaExhausted("org.a")
+	summary.AddConcurrency(2)
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := summary.WriteReport(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.SchemaVersion != reportSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", reportSchemaVersion, report.SchemaVersion)
+	}
+	if report.Written != 1 || report.Discarded[discardNotSampled] != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if len(report.ExhaustedGroups) != 2 || report.ExhaustedGroups[0] != "org.a" {
+		t.Errorf("expected sorted exhausted groups, got %v", report.ExhaustedGroups)
+	}
+	if len(report.Concurrency) != 1 || report.Concurrency[0].Value != 2 {
+		t.Errorf("unexpected concurrency: %v", report.Concurrency)
+	}
+
+	// Every field of the report must be described by the schema, and vice versa.
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Required   []string       `json:"required"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(reportSchema, &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	for field := range fields {
+		if _, ok := schema.Properties[field]; !ok {
+			t.Errorf("field %s is missing from the schema", field)
+		}
+	}
+	for _, field := range schema.Required {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("required field %s is missing from the report", field)
+		}
+	}
+	// Consumers validating against the schema of this version must accept reports with fields added since.
+	if !reflect.DeepEqual(schema.Required, reportRequiredFields) {
+		t.Errorf("expected the fields required by schema version %d to be %v, got %v", reportSchemaVersion, reportRequiredFields, schema.Required)
+	}
+	summary.SetStoppedEarly("-max-sboms")
+	summary.SetUniqueGroups(2)
+	report = summary.Report()
+	data, _ = json.Marshal(report)
+	fields = nil
+	_ = json.Unmarshal(data, &fields)
+	var properties []string
+	for property := range schema.Properties {
+		if _, ok := fields[property]; !ok {
+			properties = append(properties, property)
+		}
+	}
+	sort.Strings(properties)
+	if len(properties) > 0 {
+		t.Errorf("schema properties %v are missing from the report", properties)
+	}
+}
+
+// reportRequiredFields are the fields required by version 1 of report.schema.json.
+var reportRequiredFields = []string{
+	"schemaVersion", "started", "elapsedSeconds", "artifacts", "downloaded", "written", "failed",
+	"missingRootComponent", "sampled", "rateLimited", "panics", "discarded", "filteredArtifacts",
+	"exhaustedGroups", "componentTypes", "licenses", "concurrency",
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+const maxRateLimitAttempts = 5
+
+// Jitter modes for the backoff when rate limited (-retry-jitter).
+const (
+	retryJitterFull = "full"
+	retryJitterNone = "none"
+)
+
+// The backoff schedule when rate limited (-retry-base-delay, -retry-max-delay, -retry-jitter).
+var (
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 2 * time.Minute
+	retryJitter    = retryJitterFull
+)
+
+// maxReadAttempts limits how often a download is re-issued
+// when reading the response body fails.
+const maxReadAttempts = 3
+
+// readRetryDelay is the delay before re-issuing a download after reading its body failed.
+var readRetryDelay = 2 * time.Second
+
+var errRateLimited = errors.New("rate limited")
+
+// isRetryableReadError determines whether an error that occurred while reading
+// a response body was caused by a broken connection, rather than e.g. a timeout.
+func isRetryableReadError(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// isThrottlePage determines whether a successful response, for which an SBOM
+// in the given format was expected, is actually an HTML page. Maven Central
+// is known to serve those with status 200 when throttling clients.
+func isThrottlePage(header http.Header, body []byte, format cyclonedx.BOMFileFormat) bool {
+	if mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && mediaType == "text/html" {
+		return true
+	}
+
+	body = bytes.TrimSpace(body)
+	if format == cyclonedx.BOMFileFormatXML {
+		lower := bytes.ToLower(body)
+		return bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.HasPrefix(lower, []byte("<html"))
+	}
+
+	return bytes.HasPrefix(body, []byte("<"))
+}
+
+// rateLimitBackoff returns the delay before the next attempt. The delay
+// starts at retryBaseDelay and doubles with every attempt, up to retryMaxDelay.
+//
+// With full jitter, a random delay between zero and that value is used instead,
+// so that workers which were throttled at the same time don't retry in lockstep.
+func rateLimitBackoff(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	if retryJitter == retryJitterFull && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+	return delay
+}
+
+// sleepContext sleeps for d, or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestIsThrottlePage(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		format      cyclonedx.BOMFileFormat
+		expected    bool
+	}{
+		{"JSON", "application/json", `{"bomFormat":"CycloneDX"}`, cyclonedx.BOMFileFormatJSON, false},
+		{"JSONWithoutContentType", "", `{"bomFormat":"CycloneDX"}`, cyclonedx.BOMFileFormatJSON, false},
+		{"HTMLContentType", "text/html; charset=utf-8", `{"bomFormat":"CycloneDX"}`, cyclonedx.BOMFileFormatJSON, true},
+		{"HTMLBody", "application/octet-stream", "\n  <!DOCTYPE html><html></html>", cyclonedx.BOMFileFormatJSON, true},
+		{"XML", "application/xml", `<?xml version="1.0"?><bom></bom>`, cyclonedx.BOMFileFormatXML, false},
+		{"HTMLBodyXMLExpected", "", "<html><body>slow down</body></html>", cyclonedx.BOMFileFormatXML, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.contentType != "" {
+				header.Set("Content-Type", tc.contentType)
+			}
+			if actual := isThrottlePage(header, []byte(tc.body), tc.format); actual != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRateLimitBackoff(t *testing.T) {
+	defer func(base, max time.Duration, jitter string) {
+		retryBaseDelay, retryMaxDelay, retryJitter = base, max, jitter
+	}(retryBaseDelay, retryMaxDelay, retryJitter)
+	retryBaseDelay, retryMaxDelay = time.Second, 5*time.Second
+
+	schedule := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+
+	retryJitter = retryJitterNone
+	for i, expected := range schedule {
+		if delay := rateLimitBackoff(i + 1); delay != expected {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, expected, delay)
+		}
+	}
+
+	retryJitter = retryJitterFull
+	for i, upper := range schedule {
+		for j := 0; j < 100; j++ {
+			if delay := rateLimitBackoff(i + 1); delay < 0 || delay > upper {
+				t.Fatalf("attempt %d: expected delay between 0s and %s, got %s", i+1, upper, delay)
+			}
+		}
+	}
+}
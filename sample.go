@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// sampled decides whether gav is part of a sample with the given rate.
+//
+// The decision is derived from a hash of seed and gav rather than from a
+// shared random number generator, so it does not depend on the order in
+// which workers happen to process GAVs: the same seed always yields the same sample.
+// SHA-256 is used because the upper bits of non-cryptographic hashes like FNV
+// barely change between GAVs that only differ in their last characters.
+func sampled(gav GAV, rate float64, seed int64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	h := sha256.New()
+	_ = binary.Write(h, binary.LittleEndian, seed)
+	_, _ = h.Write([]byte(gav.String()))
+	sum := binary.BigEndian.Uint64(h.Sum(nil))
+
+	// Use the upper 53 bits to get a uniformly distributed float in [0, 1).
+	return float64(sum>>11)/float64(1<<53) < rate
+}
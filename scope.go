@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+var validScopes = []cyclonedx.Scope{cyclonedx.ScopeRequired, cyclonedx.ScopeOptional, cyclonedx.ScopeExcluded}
+
+// componentScope returns the scope of component.
+// Components without scope are required, as defined by the specification.
+func componentScope(component cyclonedx.Component) cyclonedx.Scope {
+	if component.Scope == "" {
+		return cyclonedx.ScopeRequired
+	}
+	return component.Scope
+}
+
+func parseScope(s string) (cyclonedx.Scope, error) {
+	for _, scope := range validScopes {
+		if strings.EqualFold(s, string(scope)) {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("invalid scope %q: expected one of required, optional or excluded", s)
+}
+
+// filterScopes removes all components from bom whose scope is not one of the given ones.
+// It returns the number of removed components.
+func filterScopes(bom *cyclonedx.BOM, scopes []string) int {
+	allowed := make(map[cyclonedx.Scope]bool, len(scopes))
+	for _, s := range scopes {
+		if scope, err := parseScope(s); err == nil {
+			allowed[scope] = true
+		}
+	}
+
+	return filterComponents(bom, func(component cyclonedx.Component) bool {
+		return allowed[componentScope(component)]
+	})
+}
+
+// countScopes counts the components of bom, including nested ones, by scope.
+func countScopes(bom *cyclonedx.BOM) map[cyclonedx.Scope]int {
+	counts := make(map[cyclonedx.Scope]int)
+
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			counts[componentScope(component)]++
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+
+	return counts
+}
+
+func formatScopeCounts(counts map[cyclonedx.Scope]int) string {
+	parts := make([]string, 0, len(counts))
+	for scope, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s=%d", scope, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestFilterScopes(t *testing.T) {
+	bom := cyclonedx.BOM{
+		Components: &[]cyclonedx.Component{
+			{
+				BOMRef: "a",
+				Components: &[]cyclonedx.Component{
+					{BOMRef: "a-nested", Scope: cyclonedx.ScopeOptional},
+				},
+			},
+			{BOMRef: "b", Scope: cyclonedx.ScopeRequired},
+			{BOMRef: "c", Scope: cyclonedx.ScopeExcluded},
+		},
+		Dependencies: &[]cyclonedx.Dependency{
+			{Ref: "a", Dependencies: &[]string{"a-nested", "b", "c"}},
+			{Ref: "c"},
+		},
+	}
+
+	expectedCounts := map[cyclonedx.Scope]int{
+		cyclonedx.ScopeRequired: 2,
+		cyclonedx.ScopeOptional: 1,
+		cyclonedx.ScopeExcluded: 1,
+	}
+	if counts := countScopes(&bom); !reflect.DeepEqual(counts, expectedCounts) {
+		t.Fatalf("expected counts %v, got %v", expectedCounts, counts)
+	}
+
+	if removed := filterScopes(&bom, []string{"Required"}); removed != 2 {
+		t.Fatalf("expected 2 removed components, got %d", removed)
+	}
+
+	expected := []cyclonedx.Dependency{
+		{Ref: "a", Dependencies: &[]string{"b"}},
+	}
+	if !reflect.DeepEqual(*bom.Dependencies, expected) {
+		t.Fatalf("expected dependencies %v, got %v", expected, *bom.Dependencies)
+	}
+	if len(*bom.Components) != 2 || len(*(*bom.Components)[0].Components) != 0 {
+		t.Fatalf("unexpected components %v", *bom.Components)
+	}
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ArtifactSearchResponse struct {
+	NextCursorMark string `json:"nextCursorMark"`
+	Response       struct {
+		Docs []struct {
+			GroupID       string `json:"g"`
+			ArtifactID    string `json:"a"`
+			LatestVersion string `json:"latestVersion"`
+		} `json:"docs"`
+	} `json:"response"`
+}
+
+type VersionSearchResponse struct {
+	NextCursorMark string `json:"nextCursorMark"`
+	Response       struct {
+		NumFound int `json:"numFound"`
+		Docs     []VersionDoc
+	} `json:"response"`
+}
+
+// VersionDoc is a single version in the version search results.
+type VersionDoc struct {
+	GroupID    string   `json:"g"`
+	ArtifactID string   `json:"a"`
+	Version    string   `json:"v"`
+	Packaging  string   `json:"p"`  // "jar", "pom", etc.
+	EC         []string `json:"ec"` // "-sources.jar", ".jar", "-cyclonedx.json", etc.
+}
+
+type Artifact struct {
+	GroupID       string
+	ArtifactID    string
+	LatestVersion string
+}
+
+func (a Artifact) String() string {
+	return fmt.Sprintf("%s:%s", a.GroupID, a.ArtifactID)
+}
+
+type GAV struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+}
+
+func (g GAV) String() string {
+	return fmt.Sprintf("%s:%s:%s", g.GroupID, g.ArtifactID, g.Version)
+}
+
+// ParseGAV parses coordinates in the form group:artifact:version.
+func ParseGAV(s string) (GAV, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return GAV{}, fmt.Errorf("invalid coordinates %q: expected group:artifact:version", s)
+	}
+
+	return GAV{
+		GroupID:    parts[0],
+		ArtifactID: parts[1],
+		Version:    parts[2],
+	}, nil
+}
+
+func (g GAV) Less(other GAV) bool {
+	if g.GroupID != other.GroupID {
+		return g.GroupID < other.GroupID
+	}
+	if g.ArtifactID != other.ArtifactID {
+		return g.ArtifactID < other.ArtifactID
+	}
+	return g.Version < other.Version
+}
+
+var groupPrefixRegex = regexp.MustCompile(`^[A-Za-z0-9_\-]+(\.[A-Za-z0-9_\-]+)*\.?$`)
+
+// artifactSearchQuery builds the Solr query used to discover artifacts with SBOMs.
+// If groupPrefixes are given, the search is limited to groups starting with any of them.
+func artifactSearchQuery(groupPrefixes []string) string {
+	if len(groupPrefixes) == 0 {
+		return "cyclonedx.json"
+	}
+
+	clauses := make([]string, len(groupPrefixes))
+	for i, prefix := range groupPrefixes {
+		clauses[i] = fmt.Sprintf("g:%s*", prefix)
+	}
+
+	return fmt.Sprintf("cyclonedx.json AND (%s)", strings.Join(clauses, " OR "))
+}
+
+// collectArtifacts pages through the artifact search results and sends
+// each artifact for which accept returns true to artifactsChan as soon as
+// its page has been fetched. It returns once the last page has been consumed;
+// closing the channel is left to the caller.
+func collectArtifacts(ctx context.Context, repo Repository, query string, accept func(Artifact) bool, artifactsChan chan<- Artifact) error {
+	return searchArtifactPages(ctx, repo, query, firstSearchPosition, func(page []Artifact, _ searchPosition) error {
+		for _, artifact := range page {
+			if !accept(artifact) {
+				continue
+			}
+			select {
+			case artifactsChan <- artifact:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
+
+// discoverArtifacts runs one artifact search per group prefix, up to concurrency at a time,
+// and sends the artifacts found by any of them to artifactsChan, each only once. Without
+// prefixes, or with a single one, it's equivalent to collectArtifacts. Searches that fail
+// don't stop the others; their errors are returned once all searches have completed.
+func discoverArtifacts(ctx context.Context, repo Repository, groupPrefixes []string, concurrency int, accept func(Artifact) bool, artifactsChan chan<- Artifact) error {
+	if len(groupPrefixes) < 2 {
+		return collectArtifacts(ctx, repo, artifactSearchQuery(groupPrefixes), accept, artifactsChan)
+	}
+
+	// Prefixes may overlap (e.g. org.apache and org.apache.commons).
+	var (
+		seenMux sync.Mutex
+		seen    = make(map[string]bool)
+	)
+	acceptOnce := func(artifact Artifact) bool {
+		seenMux.Lock()
+		key := artifact.String()
+		duplicate := seen[key]
+		seen[key] = true
+		seenMux.Unlock()
+		return !duplicate && accept(artifact)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	errs := make([]error, len(groupPrefixes))
+	var wg sync.WaitGroup
+	for i, prefix := range groupPrefixes {
+		wg.Add(1)
+		go func(i int, prefix string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+			errs[i] = collectArtifacts(ctx, repo, artifactSearchQuery([]string{prefix}), acceptOnce, artifactsChan)
+		}(i, prefix)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// searchPosition is where paging through search results continues.
+// Solr's cursors are stable under concurrent index updates, unlike offsets,
+// which may skip or repeat results. Start is tracked regardless, as a fallback
+// for servers that don't support cursors.
+type searchPosition struct {
+	Cursor string // empty if cursors are not supported
+	Start  int
+}
+
+var firstSearchPosition = searchPosition{Cursor: "*"}
+
+// errCursorRejected is returned for searches with a cursor that the server
+// rejected, e.g. because it doesn't support cursors or sorting.
+var errCursorRejected = errors.New("search rejected the cursor")
+
+// setSearchPosition adds the parameters for fetching the page at pos to params.
+func setSearchPosition(params url.Values, pos searchPosition) {
+	if pos.Cursor != "" {
+		// Cursors require a sort on the unique key.
+		params.Set("cursorMark", pos.Cursor)
+		params.Set("sort", "id asc")
+	} else {
+		params.Set("start", strconv.Itoa(pos.Start))
+	}
+}
+
+// checkSearchStatus returns an error if the search at pos failed with status.
+func checkSearchStatus(status int, pos searchPosition) error {
+	switch {
+	case status == http.StatusOK:
+		return nil
+	case status == http.StatusBadRequest && pos.Cursor != "":
+		return fmt.Errorf("%w: unexpected status code: %d", errCursorRejected, status)
+	default:
+		return fmt.Errorf("unexpected status code: %d", status)
+	}
+}
+
+// searchArtifactPages searches for artifacts matching query, beginning at pos,
+// and calls handle for every page of results, along with the position of the
+// next page. It stops at the first error returned by handle.
+func searchArtifactPages(ctx context.Context, repo Repository, query string, pos searchPosition, handle func(page []Artifact, next searchPosition) error) error {
+	log.Printf("searching for artifacts with cdx sbom (query: %s)", query)
+	for {
+		g, next, err := searchArtifacts(ctx, repo, query, 150, pos)
+		if errors.Is(err, errCursorRejected) {
+			log.Printf("search rejected cursors, falling back to offsets (query: %s)", query)
+			pos.Cursor = ""
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to search for artifacts: %w", err)
+		}
+		if len(g) == 0 {
+			break
+		}
+		err = handle(g, next)
+		if err != nil {
+			return err
+		}
+		if pos.Cursor != "" && next.Cursor == pos.Cursor {
+			// The cursor stopped advancing, so there are no more results.
+			break
+		}
+		pos = next
+	}
+	log.Printf("no more search results (query: %s)", query)
+	return nil
+}
+
+// searchArtifacts fetches the page of artifacts at pos,
+// and returns it along with the position of the next page.
+func searchArtifacts(ctx context.Context, repo Repository, query string, rows int, pos searchPosition) ([]Artifact, searchPosition, error) {
+	log.Printf("fetching artifact search results %d - %d", pos.Start, pos.Start+rows)
+	params := url.Values{
+		"q":    {query},
+		"rows": {strconv.Itoa(rows)},
+		"wt":   {"json"},
+	}
+	setSearchPosition(params, pos)
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repo.SearchURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, pos, err
+	}
+
+	res, err := repo.Client.Do(req)
+	if err != nil {
+		return nil, pos, err
+	}
+	defer res.Body.Close()
+
+	if err := checkSearchStatus(res.StatusCode, pos); err != nil {
+		return nil, pos, err
+	}
+
+	var resJSON ArtifactSearchResponse
+	err = json.NewDecoder(res.Body).Decode(&resJSON)
+	if err != nil {
+		return nil, pos, err
+	}
+
+	artifacts := make([]Artifact, len(resJSON.Response.Docs))
+	for i := 0; i < len(resJSON.Response.Docs); i++ {
+		artifacts[i] = Artifact{
+			GroupID:       resJSON.Response.Docs[i].GroupID,
+			ArtifactID:    resJSON.Response.Docs[i].ArtifactID,
+			LatestVersion: resJSON.Response.Docs[i].LatestVersion,
+		}
+	}
+
+	next := searchPosition{Cursor: resJSON.NextCursorMark, Start: pos.Start + len(artifacts)}
+	if pos.Cursor != "" && next.Cursor == "" && len(artifacts) > 0 {
+		log.Printf("search doesn't support cursors, falling back to offsets")
+	}
+
+	return artifacts, next, nil
+}
+
+// VersionMatch is a version of an artifact for which an SBOM has been published.
+type VersionMatch struct {
+	GAV GAV
+
+	// Classifiers lists all SBOM-like classifiers and extensions
+	// published for GAV, e.g. "-cyclonedx.json" and "-cyclonedx.xml".
+	Classifiers []string
+}
+
+// collectVersions searches for all versions of artifact with an SBOM matching any of the given suffixes.
+// If newerThan is not empty, only versions newer than it according to cmp are returned.
+func collectVersions(ctx context.Context, repo Repository, artifact Artifact, suffixes []string, newerThan string, cmp VersionComparator) ([]VersionMatch, error) {
+	log.Printf("searching for versions of %s with cdx sbom", artifact)
+	docs, err := searchVersionDocs(ctx, repo, artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for versions of %s: %w", artifact, err)
+	}
+	log.Printf("no more versions of %s", artifact)
+
+	matches := make([]VersionMatch, 0)
+	for _, doc := range uniqueVersionDocs(docs) {
+		if !containsAny(doc.EC, suffixes) {
+			continue
+		}
+		matches = append(matches, VersionMatch{
+			GAV: GAV{
+				GroupID:    doc.GroupID,
+				ArtifactID: doc.ArtifactID,
+				Version:    doc.Version,
+			},
+			Classifiers: sbomClassifiers(doc.EC, suffixes),
+		})
+	}
+
+	return newerVersions(matches, artifact, newerThan, cmp), nil
+}
+
+// uniqueVersionDocs returns the first doc of every version in docs.
+// Pages fetched by offset may repeat versions if the index changed in the meantime.
+func uniqueVersionDocs(docs []VersionDoc) []VersionDoc {
+	seen := make(map[string]bool, len(docs))
+	unique := make([]VersionDoc, 0, len(docs))
+	for _, doc := range docs {
+		if !seen[doc.Version] {
+			seen[doc.Version] = true
+			unique = append(unique, doc)
+		}
+	}
+	return unique
+}
+
+// newerVersions returns the matches with a version newer than newerThan according to cmp,
+// or all of them if newerThan is empty.
+func newerVersions(matches []VersionMatch, artifact Artifact, newerThan string, cmp VersionComparator) []VersionMatch {
+	if newerThan == "" {
+		return matches
+	}
+
+	newer := make([]VersionMatch, 0, len(matches))
+	for _, match := range matches {
+		if cmp.Compare(match.GAV.Version, newerThan) > 0 {
+			newer = append(newer, match)
+		}
+	}
+	log.Printf("found %d new versions of %s (newer than %s)", len(newer), artifact, newerThan)
+	return newer
+}
+
+// The stride of version paging is fixed rather than configurable: 150 rows is what
+// the artifact search uses as well, and is served reliably by Maven Central's search.
+// Every worker may page through versions at the same time, so the number of concurrent
+// search requests is already scaled by -concurrency; a separate knob would mostly
+// make it easier to get throttled.
+const (
+	versionPageSize = 150
+
+	// versionPageConcurrency bounds how many pages of versions of a single artifact
+	// are fetched concurrently when paging by offset.
+	versionPageConcurrency = 4
+)
+
+// versionPage is a page of version search results.
+type versionPage struct {
+	docs     []VersionDoc
+	numFound int            // total number of versions
+	next     searchPosition // position of the next page
+}
+
+// searchVersionDocs returns all versions of artifact. Like artifacts, versions are paged
+// with a cursor. If the search doesn't support cursors, it falls back to offsets.
+func searchVersionDocs(ctx context.Context, repo Repository, artifact Artifact) ([]VersionDoc, error) {
+	docs, err := searchVersionsByCursor(ctx, repo, artifact)
+	if errors.Is(err, errCursorRejected) {
+		debugf("version search rejected cursors, falling back to offsets for %s", artifact)
+		return searchVersionsByOffset(ctx, repo, artifact)
+	}
+	return docs, err
+}
+
+// searchVersionsByCursor pages through the versions of artifact with a cursor until it stops advancing.
+// It returns errCursorRejected if the search doesn't return cursors.
+func searchVersionsByCursor(ctx context.Context, repo Repository, artifact Artifact) ([]VersionDoc, error) {
+	var docs []VersionDoc
+	pos := firstSearchPosition
+	for {
+		page, err := fetchVersionPage(ctx, repo, artifact, versionPageSize, pos)
+		if err != nil {
+			return nil, err
+		}
+		if page.next.Cursor == "" {
+			return nil, errCursorRejected
+		}
+		docs = append(docs, page.docs...)
+		if len(page.docs) == 0 || page.next.Cursor == pos.Cursor {
+			return docs, nil
+		}
+		pos = page.next
+	}
+}
+
+// searchVersionsByOffset pages through the versions of artifact by offset.
+// Once the number of versions is known from the first page, the remaining pages
+// are fetched concurrently.
+func searchVersionsByOffset(ctx context.Context, repo Repository, artifact Artifact) ([]VersionDoc, error) {
+	page, err := fetchVersionPage(ctx, repo, artifact, versionPageSize, searchPosition{})
+	if err != nil {
+		return nil, err
+	}
+	docs := page.docs
+	start := len(page.docs)
+
+	if len(page.docs) > 0 && page.numFound > start {
+		pages, err := searchVersionPages(ctx, repo, artifact, start, page.numFound)
+		if err != nil {
+			return nil, err
+		}
+		for _, page := range pages {
+			docs = append(docs, page.docs...)
+		}
+		start = page.numFound
+	}
+
+	// Versions may have been published in the meantime, so keep paging until there are no more.
+	for len(page.docs) > 0 {
+		page, err = fetchVersionPage(ctx, repo, artifact, versionPageSize, searchPosition{Start: start})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, page.docs...)
+		start += len(page.docs)
+	}
+
+	return docs, nil
+}
+
+// searchVersionPages fetches the pages of versions of artifact from start up to end concurrently.
+// The pages are returned in order, regardless of the order in which they were fetched.
+func searchVersionPages(ctx context.Context, repo Repository, artifact Artifact, start, end int) ([]versionPage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make([]versionPage, (end-start+versionPageSize-1)/versionPageSize)
+	errs := make(chan error, len(pages))
+	sem := make(chan struct{}, versionPageConcurrency)
+	wg := sync.WaitGroup{}
+	for i := range pages {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			page, err := fetchVersionPage(ctx, repo, artifact, versionPageSize, searchPosition{Start: start + i*versionPageSize})
+			if err != nil {
+				errs <- err
+				cancel()
+				return
+			}
+			pages[i] = page
+		}(i)
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+		return pages, ctx.Err()
+	}
+}
+
+// fetchVersionPage fetches the page of versions of artifact at pos.
+func fetchVersionPage(ctx context.Context, repo Repository, artifact Artifact, rows int, pos searchPosition) (versionPage, error) {
+	log.Printf("fetching version search results for %s: %d - %d", artifact, pos.Start, pos.Start+rows)
+	params := url.Values{
+		"q":    {fmt.Sprintf("g:%s AND a:%s", artifact.GroupID, artifact.ArtifactID)},
+		"core": {"gav"},
+		"rows": {strconv.Itoa(rows)},
+		"wt":   {"json"},
+	}
+	setSearchPosition(params, pos)
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repo.SearchURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return versionPage{}, err
+	}
+
+	res, err := repo.Client.Do(req)
+	if err != nil {
+		return versionPage{}, err
+	}
+	defer res.Body.Close()
+
+	if err := checkSearchStatus(res.StatusCode, pos); err != nil {
+		return versionPage{}, err
+	}
+
+	var resJSON VersionSearchResponse
+	err = json.NewDecoder(res.Body).Decode(&resJSON)
+	if err != nil {
+		return versionPage{}, err
+	}
+
+	return versionPage{
+		docs:     resJSON.Response.Docs,
+		numFound: resJSON.Response.NumFound,
+		next:     searchPosition{Cursor: resJSON.NextCursorMark, Start: pos.Start + len(resJSON.Response.Docs)},
+	}, nil
+}
+
+// sbomClassifiers returns the entries of ec that refer to SBOMs.
+func sbomClassifiers(ec []string, suffixes []string) []string {
+	classifiers := make([]string, 0)
+	for _, candidate := range ec {
+		lower := strings.ToLower(candidate)
+		if strings.Contains(lower, "cyclonedx") || strings.Contains(lower, "cdx") || containsAny(suffixes, []string{candidate}) {
+			classifiers = append(classifiers, candidate)
+		}
+	}
+
+	return classifiers
+}
+
+func containsAny(haystack []string, needles []string) bool {
+	for _, candidate := range haystack {
+		for _, needle := range needles {
+			if candidate == needle {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func contains(haystack []string, needle string) bool {
+	return containsAny(haystack, []string{needle})
+}
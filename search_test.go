@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestArtifactSearchQuery(t *testing.T) {
+	if query := artifactSearchQuery(nil); query != "cyclonedx.json" {
+		t.Fatalf("unexpected query without prefixes: %s", query)
+	}
+
+	query := artifactSearchQuery([]string{"org.apache", "com.fasterxml"})
+	if query != "cyclonedx.json AND (g:org.apache* OR g:com.fasterxml*)" {
+		t.Fatalf("unexpected query with prefixes: %s", query)
+	}
+}
+
+func TestSBOMClassifiers(t *testing.T) {
+	ec := []string{".jar", "-sources.jar", "-cyclonedx.json", "-cyclonedx.xml", ".pom", ".cdx.json"}
+	classifiers := sbomClassifiers(ec, []string{"-cyclonedx.json"})
+
+	expected := []string{"-cyclonedx.json", "-cyclonedx.xml", ".cdx.json"}
+	if !reflect.DeepEqual(classifiers, expected) {
+		t.Fatalf("expected %v, got %v", expected, classifiers)
+	}
+}
+
+func TestCollectVersions(t *testing.T) {
+	repo := newFixtureRepository(t, nil, map[string]string{
+		"core=gav&cursorMark=%2A&q=g%3Aorg.example+AND+a%3Alib&rows=150&sort=id+asc&wt=json": `{"nextCursorMark": "AoE1", "response": {"docs": [
+			{"g": "org.example", "a": "lib", "v": "1.0.0", "ec": [".jar", "-cyclonedx.json"]}
+		]}}`,
+		"core=gav&cursorMark=AoE1&q=g%3Aorg.example+AND+a%3Alib&rows=150&sort=id+asc&wt=json": `{"nextCursorMark": "AoE2", "response": {"docs": [
+			{"g": "org.example", "a": "lib", "v": "0.9.0", "ec": [".jar"]}
+		]}}`,
+		"core=gav&cursorMark=AoE2&q=g%3Aorg.example+AND+a%3Alib&rows=150&sort=id+asc&wt=json": `{"nextCursorMark": "AoE2", "response": {"docs": []}}`,
+	})
+
+	versions, err := collectVersions(context.Background(), repo, Artifact{GroupID: "org.example", ArtifactID: "lib"}, []string{"-cyclonedx.json"}, "", MavenVersionComparator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []VersionMatch{
+		{
+			GAV:         GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"},
+			Classifiers: []string{"-cyclonedx.json"},
+		},
+	}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Fatalf("expected %v, got %v", expected, versions)
+	}
+}
+
+func TestCollectVersionsByOffset(t *testing.T) {
+	page := func(version string) string {
+		return `{"response": {"numFound": 400, "docs": [{"g": "org.example", "a": "lib", "v": "` + version + `", "ec": ["-cyclonedx.json"]}]}}`
+	}
+	repo := newFixtureRepository(t, nil, map[string]string{
+		// Without nextCursorMark, the search doesn't support cursors.
+		"core=gav&cursorMark=%2A&q=g%3Aorg.example+AND+a%3Alib&rows=150&sort=id+asc&wt=json": page("4.0.0"),
+		"core=gav&q=g%3Aorg.example+AND+a%3Alib&rows=150&start=0&wt=json":                    page("4.0.0"),
+		"core=gav&q=g%3Aorg.example+AND+a%3Alib&rows=150&start=1&wt=json":                    page("3.0.0"),
+		"core=gav&q=g%3Aorg.example+AND+a%3Alib&rows=150&start=151&wt=json":                  page("2.0.0"),
+		"core=gav&q=g%3Aorg.example+AND+a%3Alib&rows=150&start=301&wt=json":                  page("1.0.0"),
+		"core=gav&q=g%3Aorg.example+AND+a%3Alib&rows=150&start=400&wt=json":                  `{"response": {"numFound": 400, "docs": []}}`,
+	})
+
+	versions, err := collectVersions(context.Background(), repo, Artifact{GroupID: "org.example", ArtifactID: "lib"}, []string{"-cyclonedx.json"}, "", MavenVersionComparator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages must be merged in order, regardless of which was fetched first.
+	var actual []string
+	for _, version := range versions {
+		actual = append(actual, version.GAV.Version)
+	}
+	expected := []string{"4.0.0", "3.0.0", "2.0.0", "1.0.0"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCollectArtifactsCursor(t *testing.T) {
+	repo := newFixtureRepository(t, nil, map[string]string{
+		"cursorMark=%2A&q=cyclonedx.json&rows=150&sort=id+asc&wt=json": `{"nextCursorMark": "AoE1", "response": {"docs": [
+			{"g": "org.example", "a": "a"}
+		]}}`,
+		"cursorMark=AoE1&q=cyclonedx.json&rows=150&sort=id+asc&wt=json": `{"nextCursorMark": "AoE2", "response": {"docs": [
+			{"g": "org.example", "a": "b"}
+		]}}`,
+		"cursorMark=AoE2&q=cyclonedx.json&rows=150&sort=id+asc&wt=json": `{"nextCursorMark": "AoE2", "response": {"docs": []}}`,
+	})
+
+	artifactsChan := make(chan Artifact, 10)
+	err := collectArtifacts(context.Background(), repo, "cyclonedx.json", func(Artifact) bool { return true }, artifactsChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(artifactsChan)
+
+	var artifacts []string
+	for artifact := range artifactsChan {
+		artifacts = append(artifacts, artifact.String())
+	}
+	expected := []string{"org.example:a", "org.example:b"}
+	if !reflect.DeepEqual(artifacts, expected) {
+		t.Fatalf("expected %v, got %v", expected, artifacts)
+	}
+}
+
+func TestDiscoverArtifacts(t *testing.T) {
+	page := func(docs string) string {
+		return `{"nextCursorMark": "*", "response": {"docs": [` + docs + `]}}`
+	}
+	repo := newFixtureRepository(t, nil, map[string]string{
+		"cursorMark=%2A&q=cyclonedx.json+AND+%28g%3Aorg.example%2A%29&rows=150&sort=id+asc&wt=json": page(`
+			{"g": "org.example", "a": "a"},
+			{"g": "org.example.sub", "a": "b"}
+		`),
+		"cursorMark=%2A&q=cyclonedx.json+AND+%28g%3Aorg.example.sub%2A%29&rows=150&sort=id+asc&wt=json": page(`
+			{"g": "org.example.sub", "a": "b"},
+			{"g": "org.example.sub", "a": "c"}
+		`),
+		"cursorMark=%2A&q=cyclonedx.json+AND+%28g%3Acom.broken%2A%29&rows=150&sort=id+asc&wt=json": `{"response":`,
+	})
+
+	artifactsChan := make(chan Artifact, 10)
+	err := discoverArtifacts(context.Background(), repo, []string{"org.example", "org.example.sub", "com.broken"}, 2, func(artifact Artifact) bool {
+		return artifact.ArtifactID != "c"
+	}, artifactsChan)
+	if err == nil || !strings.Contains(err.Error(), "failed to search for artifacts") {
+		t.Fatalf("expected the error of the failed search, got %v", err)
+	}
+	close(artifactsChan)
+
+	var artifacts []string
+	for artifact := range artifactsChan {
+		artifacts = append(artifacts, artifact.String())
+	}
+	sort.Strings(artifacts)
+	expected := []string{"org.example.sub:b", "org.example:a"}
+	if !reflect.DeepEqual(artifacts, expected) {
+		t.Fatalf("expected %v, got %v", expected, artifacts)
+	}
+}
+
+func TestCollectArtifactsCursorRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Has("cursorMark"):
+			http.Error(w, "can not use cursorMark with sort", http.StatusBadRequest)
+		case query.Get("start") == "0":
+			_, _ = w.Write([]byte(`{"response": {"docs": [{"g": "org.example", "a": "a"}]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"response": {"docs": []}}`))
+		}
+	}))
+	defer server.Close()
+	repo := Repository{Client: server.Client(), SearchURL: server.URL}
+
+	artifactsChan := make(chan Artifact, 10)
+	err := collectArtifacts(context.Background(), repo, "cyclonedx.json", func(Artifact) bool { return true }, artifactsChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(artifactsChan)
+
+	var artifacts []string
+	for artifact := range artifactsChan {
+		artifacts = append(artifacts, artifact.String())
+	}
+	if !reflect.DeepEqual(artifacts, []string{"org.example:a"}) {
+		t.Fatalf("expected to fall back to offsets, got %v", artifacts)
+	}
+}
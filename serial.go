@@ -0,0 +1,30 @@
+package main
+
+import "sync"
+
+// serialSet keeps track of the serial numbers of collected SBOMs.
+// It is safe for concurrent use.
+type serialSet struct {
+	mux  sync.Mutex
+	seen map[string]bool
+}
+
+func newSerialSet() *serialSet {
+	return &serialSet{seen: make(map[string]bool)}
+}
+
+// Add records serial, and reports whether it was new.
+// Empty serials are never recorded, and thus always new.
+func (s *serialSet) Add(serial string) bool {
+	if serial == "" {
+		return true
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.seen[serial] {
+		return false
+	}
+	s.seen[serial] = true
+	return true
+}
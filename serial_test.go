@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestSerialSet(t *testing.T) {
+	serials := newSerialSet()
+
+	for _, step := range []struct {
+		serial string
+		isNew  bool
+	}{
+		{"urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", true},
+		{"urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", false},
+		{"urn:uuid:6f4e8c4a-1b2f-4e0b-9f3c-2d1e0a9b8c7d", true},
+		{"", true},
+		{"", true},
+	} {
+		if isNew := serials.Add(step.serial); isNew != step.isNew {
+			t.Fatalf("expected Add(%q) to return %t", step.serial, step.isNew)
+		}
+	}
+}
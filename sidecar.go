@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// sidecarMeta holds the provenance of an SBOM, and is written next to it with -write-sidecar-meta.
+type sidecarMeta struct {
+	GroupID      string           `json:"group"`
+	ArtifactID   string           `json:"artifact"`
+	Version      string           `json:"version"`
+	URL          string           `json:"url,omitempty"`
+	FetchedAt    string           `json:"fetchedAt,omitempty"`
+	SHA256       string           `json:"sha256"` // of the written file
+	SpecVersion  string           `json:"specVersion"`
+	Components   int              `json:"components"`
+	Dependencies int              `json:"dependencies"` // direct edges of the dependency graph
+	Headers      *ResponseHeaders `json:"headers,omitempty"`
+}
+
+// encodeSidecarMeta encodes the sidecar of the SBOM in result, whose encoded form is data.
+func encodeSidecarMeta(result Result, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	meta := sidecarMeta{
+		GroupID:     result.GAV.GroupID,
+		ArtifactID:  result.GAV.ArtifactID,
+		Version:     result.GAV.Version,
+		URL:         result.URL,
+		SHA256:      hex.EncodeToString(digest[:]),
+		SpecVersion: result.BOM.SpecVersion.String(),
+		Components:  countComponents(result.BOM.Components, true),
+	}
+	for _, deps := range directDependencies(result.BOM) {
+		meta.Dependencies += len(deps)
+	}
+	if !result.FetchedAt.IsZero() {
+		meta.FetchedAt = result.FetchedAt.UTC().Format(time.RFC3339)
+	}
+	if result.Headers != (ResponseHeaders{}) {
+		meta.Headers = &result.Headers
+	}
+
+	return json.MarshalIndent(meta, "", "  ")
+}
+
+// sidecarFileName returns the name of the sidecar of the SBOM stored under name,
+// e.g. org.example_lib_1.0.0.meta.json for org.example_lib_1.0.0.cdx.json.
+func sidecarFileName(name string) string {
+	for _, suffix := range []string{".cdx.json", ".cdx.xml"} {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			return base + ".meta.json"
+		}
+	}
+	return name + ".meta.json"
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestSidecarFileName(t *testing.T) {
+	testCases := map[string]string{
+		"org.example_lib_1.0.0.cdx.json":  "org.example_lib_1.0.0.meta.json",
+		"org.example_lib_1.0.0.cdx.xml":   "org.example_lib_1.0.0.meta.json",
+		"org.example/lib/1.0.0/sbom.json": "org.example/lib/1.0.0/sbom.json.meta.json",
+	}
+
+	for name, expected := range testCases {
+		if sidecar := sidecarFileName(name); sidecar != expected {
+			t.Errorf("sidecarFileName(%q): expected %q, got %q", name, expected, sidecar)
+		}
+	}
+}
+
+func TestEncodeSidecarMeta(t *testing.T) {
+	result := Result{
+		GAV: GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"},
+		BOM: &cyclonedx.BOM{
+			SpecVersion: cyclonedx.SpecVersion1_4,
+			Metadata:    &cyclonedx.Metadata{Component: &cyclonedx.Component{BOMRef: "root"}},
+			Components: &[]cyclonedx.Component{
+				{BOMRef: "a", Components: &[]cyclonedx.Component{{BOMRef: "b"}}},
+			},
+			Dependencies: &[]cyclonedx.Dependency{
+				{Ref: "root", Dependencies: &[]string{"a"}},
+				{Ref: "a", Dependencies: &[]string{"b"}},
+			},
+		},
+		URL:       "https://repo1.maven.org/maven2/org/example/lib/1.0.0/lib-1.0.0-cyclonedx.json",
+		FetchedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Headers:   newResponseHeaders(http.Header{"Etag": {`"abc"`}}),
+	}
+
+	data, err := encodeSidecarMeta(result, []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta sidecarMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := sidecarMeta{
+		GroupID:      "org.example",
+		ArtifactID:   "lib",
+		Version:      "1.0.0",
+		URL:          result.URL,
+		FetchedAt:    "2024-01-01T12:00:00Z",
+		SHA256:       "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		SpecVersion:  "1.4",
+		Components:   2,
+		Dependencies: 2,
+		Headers:      &ResponseHeaders{ETag: `"abc"`},
+	}
+	if meta.Headers == nil || *meta.Headers != *expected.Headers {
+		t.Fatalf("expected headers %+v, got %+v", expected.Headers, meta.Headers)
+	}
+	meta.Headers, expected.Headers = nil, nil
+	if meta != expected {
+		t.Fatalf("expected %+v, got %+v", expected, meta)
+	}
+}
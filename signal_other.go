@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "context"
+
+// logSummaryOnSignal is a no-op on platforms without SIGUSR1.
+func logSummaryOnSignal(_ context.Context, _ *Summary) {
+}
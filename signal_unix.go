@@ -0,0 +1,30 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// logSummaryOnSignal logs the current state of summary whenever
+// the process receives SIGUSR1, until ctx is cancelled.
+func logSummaryOnSignal(ctx context.Context, summary *Summary) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+
+	go func() {
+		defer signal.Stop(sigs)
+
+		for {
+			select {
+			case <-sigs:
+				summary.Log()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
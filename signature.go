@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// xmlSignatureNamespace is the namespace of enveloped XML signatures.
+const xmlSignatureNamespace = "http://www.w3.org/2000/09/xmldsig#"
+
+// detachedSignatureSuffix is appended to the URL and file name of an SBOM
+// for its detached signature.
+const detachedSignatureSuffix = ".jws"
+
+// hasEnvelopedSignature determines whether the SBOM of result carries an enveloped signature,
+// i.e. a top-level signature (JSON Signature Format) or an XML signature of the root element.
+// Signatures of components or services don't count.
+//
+// The signature of JSON SBOMs is recorded while decoding them. XML SBOMs are decoded
+// by cyclonedx-go, which doesn't support signatures, so their raw content is inspected.
+func hasEnvelopedSignature(result *Result) bool {
+	if result.Format != cyclonedx.BOMFileFormatXML {
+		return result.Signed
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(result.Raw))
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			// Only direct children of the root element sign the whole SBOM.
+			if depth == 2 && token.Name.Space == xmlSignatureNamespace && token.Name.Local == "Signature" {
+				return true
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+}
+
+// downloadDetachedSignature downloads the detached signature of the SBOM at sbomURL.
+// It returns nil if the SBOM has none.
+func downloadDetachedSignature(ctx context.Context, repo Repository, gav GAV, sbomURL string) ([]byte, error) {
+	debugf("downloading detached signature for %s", gav)
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sbomURL+detachedSignatureSuffix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := repo.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		debugf("no detached signature found for %s", gav)
+		return nil, nil
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	log.Printf("found detached signature for %s", gav)
+	return io.ReadAll(res.Body)
+}
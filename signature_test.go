@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestHasEnvelopedSignature(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		format   cyclonedx.BOMFileFormat
+		expected bool
+	}{
+		{"JSONSigned", `{"bomFormat": "CycloneDX", "signature": {"algorithm": "ES256", "value": "..."}}`, cyclonedx.BOMFileFormatJSON, true},
+		{"JSONUnsigned", `{"bomFormat": "CycloneDX", "components": [{"signature": {}}]}`, cyclonedx.BOMFileFormatJSON, false},
+		{"JSONNull", `{"bomFormat": "CycloneDX", "signature": null}`, cyclonedx.BOMFileFormatJSON, false},
+		{"XMLSigned", `<bom><Signature xmlns="http://www.w3.org/2000/09/xmldsig#"/></bom>`, cyclonedx.BOMFileFormatXML, true},
+		{"XMLUnsigned", `<bom></bom>`, cyclonedx.BOMFileFormatXML, false},
+		{"XMLComponentSigned", `<bom><components><component><Signature xmlns="http://www.w3.org/2000/09/xmldsig#"/></component></components></bom>`, cyclonedx.BOMFileFormatXML, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := &Result{Raw: []byte(tc.data), Format: tc.format}
+			if tc.format == cyclonedx.BOMFileFormatJSON {
+				var err error
+				if _, _, result.Signed, err = decodeSBOM(result.Raw, tc.format, false, false, &cyclonedx.BOM{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if actual := hasEnvelopedSignature(result); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDownloadDetachedSignature(t *testing.T) {
+	signed := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"}
+	unsigned := GAV{GroupID: "org.example", ArtifactID: "lib", Version: "2.0.0"}
+	repo := newFixtureRepository(t, map[string]fixture{
+		"org/example/lib/1.0.0/lib-1.0.0-cyclonedx.json.jws": {body: "eyJhbGciOiJFUzI1NiJ9..c2ln"},
+	}, nil)
+
+	signature, err := downloadDetachedSignature(context.Background(), repo, signed, artifactFileURL(repo.BaseURL, signed, "-cyclonedx.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signature) != "eyJhbGciOiJFUzI1NiJ9..c2ln" {
+		t.Errorf("unexpected signature %q", signature)
+	}
+
+	signature, err = downloadDetachedSignature(context.Background(), repo, unsigned, artifactFileURL(repo.BaseURL, unsigned, "-cyclonedx.json"))
+	if err != nil || signature != nil {
+		t.Errorf("expected no signature and no error, got %q and %v", signature, err)
+	}
+}
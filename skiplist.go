@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SkipList holds artifacts and versions that are never downloaded (-skip-list-file),
+// e.g. because they are known to serve broken or huge SBOMs.
+type SkipList struct {
+	artifacts map[string]bool
+	versions  map[GAV]bool
+}
+
+// readSkipList reads a file with one group:artifact or group:artifact:version entry per line.
+// In addition to the comment lines of readListFile, anything following a # is ignored,
+// so that entries can be annotated with the reason for skipping them.
+func readSkipList(path string) (*SkipList, error) {
+	lines, err := readListFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &SkipList{artifacts: make(map[string]bool), versions: make(map[GAV]bool)}
+	for _, line := range lines {
+		entry, _, _ := strings.Cut(line, "#")
+		entry = strings.TrimSpace(entry)
+
+		parts := strings.Split(entry, ":")
+		switch {
+		case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+			list.artifacts[entry] = true
+		case len(parts) == 3:
+			gav, err := ParseGAV(entry)
+			if err != nil {
+				return nil, err
+			}
+			list.versions[gav] = true
+		default:
+			return nil, fmt.Errorf("invalid entry %q: expected group:artifact or group:artifact:version", entry)
+		}
+	}
+	return list, nil
+}
+
+// SkipsArtifact reports whether all versions of artifact are to be skipped.
+func (l *SkipList) SkipsArtifact(artifact Artifact) bool {
+	return l != nil && l.artifacts[artifact.String()]
+}
+
+// SkipsVersion reports whether gav is to be skipped.
+func (l *SkipList) SkipsVersion(gav GAV) bool {
+	return l != nil && l.versions[gav]
+}
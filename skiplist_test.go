@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSkipList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skip.txt")
+	content := "# known-bad coordinates\norg.example:huge\n\n  org.example:broken:1.0.0  # truncated sbom\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := readSkipList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts := map[Artifact]bool{
+		{GroupID: "org.example", ArtifactID: "huge"}:   true,
+		{GroupID: "org.example", ArtifactID: "broken"}: false,
+		{GroupID: "org.example", ArtifactID: "other"}:  false,
+	}
+	for artifact, expected := range artifacts {
+		if actual := list.SkipsArtifact(artifact); actual != expected {
+			t.Errorf("expected SkipsArtifact(%s) to be %v, got %v", artifact, expected, actual)
+		}
+	}
+
+	versions := map[GAV]bool{
+		{GroupID: "org.example", ArtifactID: "broken", Version: "1.0.0"}: true,
+		{GroupID: "org.example", ArtifactID: "broken", Version: "1.0.1"}: false,
+	}
+	for gav, expected := range versions {
+		if actual := list.SkipsVersion(gav); actual != expected {
+			t.Errorf("expected SkipsVersion(%s) to be %v, got %v", gav, expected, actual)
+		}
+	}
+
+	var empty *SkipList
+	if empty.SkipsArtifact(Artifact{GroupID: "org.example", ArtifactID: "huge"}) {
+		t.Error("expected a nil list to skip nothing")
+	}
+}
+
+func TestReadSkipListInvalid(t *testing.T) {
+	for _, entry := range []string{"org.example", "org.example:", "org.example:lib:1.0.0:sources"} {
+		path := filepath.Join(t.TempDir(), "skip.txt")
+		if err := os.WriteFile(path, []byte(entry+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readSkipList(path); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("expected entry %q to be invalid, got %v", entry, err)
+		}
+	}
+}
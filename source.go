@@ -0,0 +1,338 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CycloneDX/cyclonedx-go"
+	"github.com/klauspost/compress/zstd"
+)
+
+const archiveSourcePrefix = "archive:"
+
+// Source yields the SBOMs to process.
+type Source interface {
+	Stream(ctx context.Context) <-chan Result
+}
+
+// ArchiveSource reads SBOMs from archives previously written with -archive,
+// instead of downloading them. The SBOMs go through the same filters as
+// downloaded ones.
+type ArchiveSource struct {
+	path       string
+	opts       Options
+	summary    *Summary
+	groupQuota *groupQuota
+	diskBudget *diskBudget
+}
+
+func NewArchiveSource(path string, opts Options, summary *Summary, diskBudget *diskBudget) *ArchiveSource {
+	opts.Filters.decodeSlots = newDecodeSlots(opts.Filters.MaxDecodes)
+	return &ArchiveSource{
+		path:       path,
+		opts:       opts,
+		summary:    summary,
+		groupQuota: newGroupQuota(opts.MaxPerGroup),
+		diskBudget: diskBudget,
+	}
+}
+
+type archiveEntry struct {
+	gav    GAV
+	format cyclonedx.BOMFileFormat
+	data   []byte
+}
+
+// Stream reads all SBOMs from the archive and yields a Result for every SBOM
+// that passed all filters, and for every error encountered along the way.
+// Other files, like POMs and dependency edges, are skipped.
+func (s *ArchiveSource) Stream(ctx context.Context) <-chan Result {
+	results := make(chan Result, s.opts.Concurrency)
+	entries := make(chan archiveEntry, 1)
+
+	ctx, stop := context.WithCancel(ctx)
+
+	send := func(result Result) bool {
+		select {
+		case results <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < s.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for entry := range entries {
+				result, err := s.process(ctx, entry)
+				if err != nil {
+					result = &Result{GAV: entry.gav, Err: err}
+				}
+				if result.Discard != nil && !s.opts.yieldDiscarded() {
+					continue
+				}
+				if !send(*result) {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		err := s.readEntries(ctx, entries)
+		close(entries)
+		if err != nil {
+			send(Result{Err: err})
+		}
+
+		wg.Wait()
+		close(results)
+		stop()
+	}()
+
+	return results
+}
+
+func (s *ArchiveSource) process(ctx context.Context, entry archiveEntry) (result *Result, err error) {
+	err = safely(entry.gav, s.summary, func() error {
+		result, err = processSBOM(ctx, entry.gav, entry.data, entry.format, s.opts.Filters, s.summary)
+		return err
+	})
+	if err != nil || result.Discard != nil {
+		return result, err
+	}
+
+	ok, exhausted := s.groupQuota.Take(entry.gav.GroupID)
+	if exhausted {
+		s.summary.AddGroupQuotaExhausted(entry.gav.GroupID)
+	}
+	if !ok {
+		log.Printf("discarding sbom for %s because its group reached -max-per-group", entry.gav)
+		s.summary.AddDiscarded(discardGroupQuota)
+		return discardedResult(entry.gav, discardGroupQuota, map[string]any{"maxPerGroup": s.opts.MaxPerGroup}), nil
+	}
+
+	return result, nil
+}
+
+// readEntries reads the SBOMs from all archives and sends them to entries.
+func (s *ArchiveSource) readEntries(ctx context.Context, entries chan<- archiveEntry) error {
+	paths, err := archivePaths(s.path)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		log.Printf("reading sboms from %s", path)
+		err = walkArchive(path, func(name string, r io.Reader) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if s.diskBudget.Exhausted() {
+				log.Printf("stopping because -max-disk-bytes was reached")
+				s.summary.SetStoppedEarly("-max-disk-bytes")
+				return errBudgetExhausted
+			}
+			if s.diskBudget.SBOMLimitReached() {
+				log.Printf("stopping because -max-sboms was reached")
+				s.summary.SetStoppedEarly("-max-sboms")
+				return errBudgetExhausted
+			}
+
+			gav, format, ok := parseSBOMFileName(filepath.Base(name))
+			if !ok {
+				debugf("skipping %s in %s because it's not an sbom", name, path)
+				return nil
+			}
+			if !s.diskBudget.AcceptsGroup(gav.GroupID) {
+				debugf("skipping %s in %s because -max-sboms was reached and its group is already represented", name, path)
+				return nil
+			}
+
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+
+			select {
+			case entries <- archiveEntry{gav: gav, format: format, data: data}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if errors.Is(err, errBudgetExhausted) || errors.Is(err, context.Canceled) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+var errBudgetExhausted = errors.New("disk budget exhausted")
+
+// archivePaths resolves path to the archives to read. When path doesn't exist,
+// the shards written with -archive-max-entries or -archive-max-bytes are used.
+func archivePaths(path string) ([]string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return []string{path}, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ext := filepath.Ext(path)
+	shards, err := filepath.Glob(strings.TrimSuffix(path, ext) + "-[0-9][0-9][0-9][0-9]" + ext)
+	if err != nil {
+		return nil, err
+	}
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("%s does not exist", path)
+	}
+	sort.Strings(shards)
+
+	return shards, nil
+}
+
+// Formats of archives read by walkArchive, as determined by archiveFormat.
+const (
+	archiveZip     = "zip"
+	archiveTar     = "tar"
+	archiveTarGzip = "tar.gz"
+	archiveTarZstd = "tar.zst"
+)
+
+// archiveFormat determines the format of the archive at path by its extension.
+// Archives without a known extension are read as tar archives.
+func archiveFormat(path string) (string, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip, nil
+	case strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGzip, nil
+	case strings.HasSuffix(lower, ".zst") || strings.HasSuffix(lower, ".tzst"):
+		return archiveTarZstd, nil
+	case strings.HasSuffix(lower, ".bz2") || strings.HasSuffix(lower, ".tbz2") ||
+		strings.HasSuffix(lower, ".xz") || strings.HasSuffix(lower, ".txz"):
+		return "", fmt.Errorf("unsupported compression of %s, decompress it first (supported are zip, tar, tar.gz and tar.zst)", filepath.Base(path))
+	default:
+		return archiveTar, nil
+	}
+}
+
+// walkArchive calls fn for every regular file in the zip or tar archive at path.
+// Tar archives may be compressed with gzip or zstd, and are decompressed while being read.
+func walkArchive(path string, fn func(name string, r io.Reader) error) error {
+	format, err := archiveFormat(path)
+	if err != nil {
+		return err
+	}
+
+	if format == archiveZip {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		for _, file := range zr.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(file.Name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case archiveTarGzip:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("not a gzip-compressed archive: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case archiveTarZstd:
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("not a zstd-compressed archive: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = fn(header.Name, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseSBOMFileName is the inverse of sbomFileName. As the parts of the name are
+// separated by underscores, underscores are attributed to the artifact ID.
+func parseSBOMFileName(name string) (GAV, cyclonedx.BOMFileFormat, bool) {
+	var format cyclonedx.BOMFileFormat
+	if base, ok := strings.CutSuffix(name, ".cdx.json"); ok {
+		name, format = base, cyclonedx.BOMFileFormatJSON
+	} else if base, ok := strings.CutSuffix(name, ".cdx.xml"); ok {
+		name, format = base, cyclonedx.BOMFileFormatXML
+	} else {
+		return GAV{}, format, false
+	}
+
+	first, last := strings.Index(name, "_"), strings.LastIndex(name, "_")
+	if first <= 0 || last == first || last == len(name)-1 {
+		return GAV{}, format, false
+	}
+
+	return GAV{
+		GroupID:    name[:first],
+		ArtifactID: name[first+1 : last],
+		Version:    name[last+1:],
+	}, format, true
+}
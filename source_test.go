@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestArchiveSource(t *testing.T) {
+	dir := t.TempDir()
+	output := &archiveOutput{
+		path:       filepath.Join(dir, "corpus.zip"),
+		maxEntries: 2,
+	}
+	files := map[string]string{
+		"org.example_lib_1.0.0.cdx.json":   fixtureSBOM,
+		"org.example_lib_1.0.0.edges.json": `[]`,
+		"org.example_empty_1.0.0.cdx.json": `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1}`,
+		"org.example_lib_2.0.0.cdx.xml":    fixtureSBOMXML,
+	}
+	for name, content := range files {
+		if _, err := output.Write(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := output.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		Concurrency:    2,
+		IndexDiscarded: true,
+		Filters: Filters{
+			MinComponents: 1,
+			SampleRate:    1,
+		},
+	}
+	summary := NewSummary()
+	source := NewArchiveSource(filepath.Join(dir, "corpus.zip"), opts, summary, newDiskBudget(0))
+
+	results := make(map[string]Result)
+	for result := range source.Stream(context.Background()) {
+		if result.Err != nil {
+			t.Fatalf("unexpected error: %v", result.Err)
+		}
+		results[result.GAV.String()] = result
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d: %v", len(results), results)
+	}
+	if result := results["org.example:lib:1.0.0"]; result.BOM == nil || result.Format != cyclonedx.BOMFileFormatJSON {
+		t.Errorf("expected json sbom for org.example:lib:1.0.0, got %+v", result)
+	}
+	if result := results["org.example:lib:2.0.0"]; result.BOM == nil || result.Format != cyclonedx.BOMFileFormatXML {
+		t.Errorf("expected xml sbom for org.example:lib:2.0.0, got %+v", result)
+	}
+	if result := results["org.example:empty:1.0.0"]; result.Discard == nil || result.Discard.Reason != discardTooFewComponents {
+		t.Errorf("expected org.example:empty:1.0.0 to be discarded, got %+v", result)
+	}
+}
+
+func TestParseSBOMFileName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		gav    GAV
+		format cyclonedx.BOMFileFormat
+		ok     bool
+	}{
+		{
+			name:   "org.example_lib_1.0.0.cdx.json",
+			gav:    GAV{GroupID: "org.example", ArtifactID: "lib", Version: "1.0.0"},
+			format: cyclonedx.BOMFileFormatJSON,
+			ok:     true,
+		},
+		{
+			name:   "org.example_lib_2.13_1.0.0.cdx.xml",
+			gav:    GAV{GroupID: "org.example", ArtifactID: "lib_2.13", Version: "1.0.0"},
+			format: cyclonedx.BOMFileFormatXML,
+			ok:     true,
+		},
+		{name: "org.example_lib_1.0.0.edges.json"},
+		{name: "org.example_lib_1.0.0.pom"},
+		{name: "lib.cdx.json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gav, format, ok := parseSBOMFileName(tc.name)
+			if ok != tc.ok {
+				t.Fatalf("expected ok to be %t, got %t", tc.ok, ok)
+			}
+			if ok && (gav != tc.gav || format != tc.format) {
+				t.Fatalf("expected %s (%s), got %s (%s)", tc.gav, formatName(tc.format), gav, formatName(format))
+			}
+		})
+	}
+}
+
+func TestWalkArchive(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	_ = tw.WriteHeader(&tar.Header{Name: "org.example_lib_1.0.0.cdx.json", Mode: 0o644, Size: 2, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("{}"))
+	_ = tw.Close()
+
+	var tarGz bytes.Buffer
+	gw := gzip.NewWriter(&tarGz)
+	_, _ = gw.Write(tarball.Bytes())
+	_ = gw.Close()
+
+	var tarZst bytes.Buffer
+	zw, _ := zstd.NewWriter(&tarZst)
+	_, _ = zw.Write(tarball.Bytes())
+	_ = zw.Close()
+
+	testCases := []struct {
+		name   string
+		path   string
+		errMsg string
+	}{
+		{"TarGzip", write("corpus.tar.gz", tarGz.Bytes()), ""},
+		{"TarZstd", write("corpus.tar.zst", tarZst.Bytes()), ""},
+		{"CorruptGzip", write("corrupt.tgz", []byte("not gzip")), "not a gzip-compressed archive"},
+		{"Bzip2", write("corpus.tar.bz2", []byte("BZh9")), "unsupported compression of corpus.tar.bz2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			err := walkArchive(tc.path, func(name string, r io.Reader) error {
+				names = append(names, name)
+				_, err := io.ReadAll(r)
+				return err
+			})
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(names) != 1 || names[0] != "org.example_lib_1.0.0.cdx.json" {
+				t.Fatalf("unexpected files: %v", names)
+			}
+		})
+	}
+}
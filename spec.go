@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fields of CycloneDX JSON documents, along with the minor
+// spec version that introduced them (-check-spec-consistency).
+var (
+	specBOMFields = map[string]int{
+		"compositions":    3,
+		"properties":      3,
+		"vulnerabilities": 4,
+		"annotations":     5,
+		"formulation":     5,
+		"definitions":     6,
+		"declarations":    6,
+	}
+	specMetadataFields = map[string]int{
+		"properties": 3,
+		"lifecycles": 5,
+	}
+	specComponentFields = map[string]int{
+		"properties":       3,
+		"evidence":         3,
+		"releaseNotes":     4,
+		"signature":        4,
+		"modelCard":        5,
+		"data":             5,
+		"cryptoProperties": 6,
+		"omniborId":        6,
+		"swhid":            6,
+	}
+)
+
+// specMinor returns the minor version of a 1.x spec version.
+func specMinor(version string) (int, bool) {
+	minor, ok := strings.CutPrefix(version, "1.")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(minor)
+	return n, err == nil
+}
+
+// checkSpecConsistency checks whether the fields present in a JSON SBOM are
+// consistent with its declared specVersion. It returns the fields that require
+// a newer spec version than the declared one, e.g. "annotations (1.5)".
+//
+// The legacy tools array is deprecated since 1.5, but still valid,
+// so only the opposite case (tools as an object in older versions) is flagged.
+func checkSpecConsistency(data []byte) ([]string, error) {
+	var doc struct {
+		SpecVersion string                     `json:"specVersion"`
+		Metadata    map[string]json.RawMessage `json:"metadata"`
+		Components  []json.RawMessage          `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	declared, ok := specMinor(doc.SpecVersion)
+	if !ok {
+		return nil, fmt.Errorf("unsupported spec version %q", doc.SpecVersion)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	issues := make(map[string]bool)
+	check := func(path string, present map[string]json.RawMessage, known map[string]int) {
+		for field, since := range known {
+			if _, ok := present[field]; ok && since > declared {
+				issues[fmt.Sprintf("%s%s (1.%d)", path, field, since)] = true
+			}
+		}
+	}
+	check("", fields, specBOMFields)
+	check("metadata.", doc.Metadata, specMetadataFields)
+	if tools := strings.TrimSpace(string(doc.Metadata["tools"])); strings.HasPrefix(tools, "{") && declared < 5 {
+		issues["metadata.tools (1.5)"] = true
+	}
+	if component, ok := doc.Metadata["component"]; ok {
+		if err := checkComponentSpecConsistency("metadata.component.", component, check); err != nil {
+			return nil, err
+		}
+	}
+	for _, component := range doc.Components {
+		if err := checkComponentSpecConsistency("components[].", component, check); err != nil {
+			return nil, err
+		}
+	}
+
+	sorted := make([]string, 0, len(issues))
+	for issue := range issues {
+		sorted = append(sorted, issue)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+func checkComponentSpecConsistency(path string, data json.RawMessage, check func(string, map[string]json.RawMessage, map[string]int)) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	check(path, fields, specComponentFields)
+
+	var nested []json.RawMessage
+	if raw, ok := fields["components"]; ok {
+		if err := json.Unmarshal(raw, &nested); err != nil {
+			return err
+		}
+	}
+	for _, component := range nested {
+		// Nested components are reported like top-level ones.
+		if err := checkComponentSpecConsistency("components[].", component, check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckSpecConsistency(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []string
+		errMsg   string
+	}{
+		{
+			name:     "Consistent",
+			data:     `{"specVersion": "1.4", "metadata": {"tools": [{"name": "a"}]}, "vulnerabilities": [], "components": [{"name": "a", "releaseNotes": {}}]}`,
+			expected: []string{},
+		},
+		{
+			name:     "LegacyToolsInNewerVersion",
+			data:     `{"specVersion": "1.5", "metadata": {"tools": [{"name": "a"}]}}`,
+			expected: []string{},
+		},
+		{
+			name:     "ToolsObject",
+			data:     `{"specVersion": "1.4", "metadata": {"tools": {"components": []}}}`,
+			expected: []string{"metadata.tools (1.5)"},
+		},
+		{
+			name: "NewerFields",
+			data: `{
+  "specVersion": "1.2",
+  "metadata": {"lifecycles": [], "component": {"name": "lib", "evidence": {}}},
+  "annotations": [],
+  "components": [
+    {"name": "a", "properties": [], "components": [{"name": "b", "modelCard": {}}]},
+    {"name": "c", "properties": []}
+  ]
+}`,
+			expected: []string{
+				"annotations (1.5)",
+				"components[].modelCard (1.5)",
+				"components[].properties (1.3)",
+				"metadata.component.evidence (1.3)",
+				"metadata.lifecycles (1.5)",
+			},
+		},
+		{
+			name:   "UnsupportedSpecVersion",
+			data:   `{"specVersion": "2.0"}`,
+			errMsg: `unsupported spec version "2.0"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issues, err := checkSpecConsistency([]byte(tc.data))
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error %q, got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(issues, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, issues)
+			}
+		})
+	}
+}
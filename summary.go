@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+const (
+	filterIncludeGroups = "include-groups-file"
+	filterExcludeGroups = "exclude-groups-file"
+	filterSkipList      = "skip-list-file"
+)
+
+const (
+	discardTooFewComponents     = "too-few-components"
+	discardMissingRootComponent = "missing-root-component"
+	discardNotSampled           = "not-sampled"
+	discardGroupQuota           = "max-per-group"
+	discardEmptyAfterPurlFilter = "empty-after-purl-filter"
+	discardNoMatchingProperty   = "no-matching-property"
+	discardLowTransitiveRatio   = "low-transitive-ratio"
+	discardAllExcluded          = "all-components-excluded"
+	discardDuplicateSerial      = "duplicate-serial"
+	discardDecodeWarnings       = "decode-warnings"
+	discardDuplicateComponents  = "duplicate-component-set"
+	discardLowQualityScore      = "low-quality-score"
+	discardSBOMLimit            = "max-sboms"
+	discardTooSmall             = "too-small"
+	discardTooLarge             = "too-large"
+	discardNoMatchingGroup      = "no-matching-group"
+	discardLowVersionedRatio    = "low-versioned-ratio"
+	discardNoCPE                = "no-cpe"
+	discardLowCPERatio          = "low-cpe-ratio"
+	discardOldSpecVersion       = "old-spec-version"
+)
+
+// Summary keeps track of what happened during a crawl.
+// It is safe for concurrent use.
+type Summary struct {
+	mux                  sync.Mutex
+	started              time.Time
+	artifacts            int
+	downloaded           int
+	written              int
+	failed               int
+	missingRootComponent int
+	sampled              int
+	skipListed           int
+	rateLimited          int
+	panics               int
+	discarded            map[string]int
+	filteredArtifacts    map[string]int
+	exhaustedGroups      map[string]bool
+	deadlineArtifacts    map[string]bool
+	stuckWorkers         []string
+	componentTypes       map[string]int
+	licenses             map[string]int
+	specInconsistencies  map[string]int
+	toolVersions         map[string]int
+	unresolvablePurls    map[string][]string
+	timestampAnomalies   map[string][]TimestampAnomaly
+	envelopedSignatures  int
+	detachedSignatures   int
+	stoppedEarlyBy       string
+	uniqueGroups         int
+	concurrency          []concurrencyChange
+}
+
+// concurrencyChange records that the concurrency was set to Value, after Elapsed.
+type concurrencyChange struct {
+	Elapsed time.Duration
+	Value   int
+}
+
+func NewSummary() *Summary {
+	return &Summary{
+		started:             time.Now(),
+		discarded:           make(map[string]int),
+		filteredArtifacts:   make(map[string]int),
+		exhaustedGroups:     make(map[string]bool),
+		deadlineArtifacts:   make(map[string]bool),
+		componentTypes:      make(map[string]int),
+		licenses:            make(map[string]int),
+		specInconsistencies: make(map[string]int),
+		toolVersions:        make(map[string]int),
+		unresolvablePurls:   make(map[string][]string),
+		timestampAnomalies:  make(map[string][]TimestampAnomaly),
+	}
+}
+
+// AddArtifact records an artifact for which all versions have been processed.
+func (s *Summary) AddArtifact() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.artifacts++
+}
+
+func (s *Summary) AddDownloaded() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.downloaded++
+}
+
+func (s *Summary) AddWritten() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.written++
+}
+
+func (s *Summary) AddFailed() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.failed++
+}
+
+func (s *Summary) AddMissingRootComponent() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.missingRootComponent++
+}
+
+func (s *Summary) AddRateLimited() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.rateLimited++
+}
+
+func (s *Summary) AddPanic() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.panics++
+}
+
+// AddSkipListed records a version that was skipped because of -skip-list-file.
+// Artifacts skipped entirely are recorded with AddFilteredArtifact.
+func (s *Summary) AddSkipListed() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.skipListed++
+}
+
+func (s *Summary) AddSampled() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.sampled++
+}
+
+// SetStoppedEarly records that the crawl was stopped because the limit of flag was reached.
+func (s *Summary) SetStoppedEarly(flag string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.stoppedEarlyBy = flag
+}
+
+// SetUniqueGroups records the number of distinct groups of the collected SBOMs, with -max-sboms.
+func (s *Summary) SetUniqueGroups(groups int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.uniqueGroups = groups
+}
+
+// AddConcurrency records a change of the concurrency with -auto-concurrency.
+func (s *Summary) AddConcurrency(value int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.concurrency = append(s.concurrency, concurrencyChange{Elapsed: time.Since(s.started), Value: value})
+}
+
+// requestCounts returns the number of downloaded SBOMs, failures and throttle pages so far.
+func (s *Summary) requestCounts() (downloaded, failed, rateLimited int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.downloaded, s.failed, s.rateLimited
+}
+
+// AddBOM records the component types and licenses of an SBOM that passed all filters.
+func (s *Summary) AddBOM(bom *cyclonedx.BOM) {
+	if bom.Components == nil {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, component := range *bom.Components {
+		s.componentTypes[string(component.Type)]++
+
+		if component.Licenses == nil || len(*component.Licenses) == 0 {
+			s.licenses["none"]++
+			continue
+		}
+		for _, choice := range *component.Licenses {
+			if name := licenseName(choice); name != "" {
+				s.licenses[name]++
+			}
+		}
+	}
+}
+
+// AddCollected records the statistics of an SBOM that was kept after all filters and checks.
+// It's called only once an SBOM can't be discarded anymore, e.g. because of -max-per-group,
+// so that the statistics don't include discarded SBOMs.
+func (s *Summary) AddCollected(result Result, toolVersions bool) {
+	s.AddSampled()
+	s.AddBOM(result.BOM)
+	if toolVersions {
+		s.AddToolVersions(result.Tools)
+	}
+	if len(result.UnresolvablePurls) > 0 {
+		s.AddUnresolvablePurls(result.GAV, result.UnresolvablePurls)
+	}
+}
+
+// AddFilteredArtifact records an artifact that was skipped before any of its versions were searched for.
+func (s *Summary) AddFilteredArtifact(filter string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.filteredArtifacts[filter]++
+}
+
+func (s *Summary) AddGroupQuotaExhausted(group string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.exhaustedGroups[group] = true
+}
+
+// AddArtifactDeadlineExceeded records an artifact that was given up on because of -artifact-deadline.
+func (s *Summary) AddArtifactDeadlineExceeded(artifact string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.deadlineArtifacts[artifact] = true
+}
+
+// AddStuckWorker records the step of a worker that tripped -stuck-timeout.
+func (s *Summary) AddStuckWorker(step string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.stuckWorkers = append(s.stuckWorkers, step)
+}
+
+// AddSignatures records whether a written SBOM has an enveloped or a detached signature (-fetch-signatures).
+func (s *Summary) AddSignatures(enveloped, detached bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if enveloped {
+		s.envelopedSignatures++
+	}
+	if detached {
+		s.detachedSignatures++
+	}
+}
+
+// AddSpecInconsistencies records the fields of an SBOM that require a newer spec version than the declared one.
+func (s *Summary) AddSpecInconsistencies(fields []string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, field := range fields {
+		s.specInconsistencies[field]++
+	}
+}
+
+// AddToolVersions records the tools that generated an SBOM that passed all filters (-tool-version).
+func (s *Summary) AddToolVersions(tools []SBOMTool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if len(tools) == 0 {
+		s.toolVersions["none"]++
+	}
+	for _, tool := range tools {
+		s.toolVersions[tool.String()]++
+	}
+}
+
+// AddUnresolvablePurls records the package URLs of components of the SBOM of gav
+// that reference artifacts that don't exist (-verify-purl-resolvable).
+func (s *Summary) AddUnresolvablePurls(gav GAV, purls []string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.unresolvablePurls[gav.String()] = purls
+}
+
+// AddTimestampAnomalies records the versions of artifact whose SBOMs are older
+// than those of preceding versions (-validate-timestamps-monotonic).
+func (s *Summary) AddTimestampAnomalies(artifact string, anomalies []TimestampAnomaly) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.timestampAnomalies[artifact] = anomalies
+}
+
+func (s *Summary) AddDiscarded(reason string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.discarded[reason]++
+}
+
+func (s *Summary) Log() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	elapsed := time.Since(s.started)
+	log.Printf("summary: artifacts=%d downloaded=%d written=%d failed=%d missing_root_component=%d", s.artifacts, s.downloaded, s.written, s.failed, s.missingRootComponent)
+	log.Printf("summary: elapsed=%s rate=%.1f sboms/min", elapsed.Round(time.Second), float64(s.written)/elapsed.Minutes())
+
+	if s.stoppedEarlyBy != "" {
+		log.Printf("summary: stopped early because %s was reached", s.stoppedEarlyBy)
+	}
+	if s.uniqueGroups > 0 {
+		log.Printf("summary: collected sboms of %d unique groups", s.uniqueGroups)
+	}
+	if s.panics > 0 {
+		log.Printf("summary: recovered from %d panics", s.panics)
+	}
+	if s.rateLimited > 0 {
+		log.Printf("summary: received %d throttle pages", s.rateLimited)
+	}
+	if s.envelopedSignatures > 0 || s.detachedSignatures > 0 {
+		log.Printf("summary: signatures: enveloped=%d detached=%d", s.envelopedSignatures, s.detachedSignatures)
+	}
+	if len(s.concurrency) > 0 {
+		timeline := make([]string, 0, len(s.concurrency))
+		for _, change := range s.concurrency {
+			timeline = append(timeline, fmt.Sprintf("%d (%s)", change.Value, change.Elapsed.Round(time.Second)))
+		}
+		log.Printf("summary: concurrency: %s", strings.Join(timeline, " -> "))
+	}
+	if notSampled := s.discarded[discardNotSampled]; notSampled > 0 {
+		log.Printf("summary: sampled %d of %d eligible sboms", s.sampled, s.sampled+notSampled)
+	}
+
+	filters := make([]string, 0, len(s.filteredArtifacts))
+	for filter := range s.filteredArtifacts {
+		filters = append(filters, filter)
+	}
+	sort.Strings(filters)
+	for _, filter := range filters {
+		log.Printf("summary: filtered %d artifacts (%s)", s.filteredArtifacts[filter], filter)
+	}
+	if s.skipListed > 0 {
+		log.Printf("summary: skipped %d versions (%s)", s.skipListed, filterSkipList)
+	}
+
+	reasons := make([]string, 0, len(s.discarded))
+	for reason := range s.discarded {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		log.Printf("summary: discarded %d sboms (%s)", s.discarded[reason], reason)
+	}
+
+	if len(s.exhaustedGroups) > 0 {
+		groups := make([]string, 0, len(s.exhaustedGroups))
+		for group := range s.exhaustedGroups {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		log.Printf("summary: %d groups reached -max-per-group: %s", len(groups), strings.Join(groups, ", "))
+	}
+	if len(s.deadlineArtifacts) > 0 {
+		artifacts := make([]string, 0, len(s.deadlineArtifacts))
+		for artifact := range s.deadlineArtifacts {
+			artifacts = append(artifacts, artifact)
+		}
+		sort.Strings(artifacts)
+		log.Printf("summary: %d artifacts exceeded -artifact-deadline: %s", len(artifacts), strings.Join(artifacts, ", "))
+	}
+	if len(s.stuckWorkers) > 0 {
+		log.Printf("summary: %d workers tripped -stuck-timeout: %s", len(s.stuckWorkers), strings.Join(s.stuckWorkers, "; "))
+	}
+	if len(s.unresolvablePurls) > 0 {
+		gavs := make([]string, 0, len(s.unresolvablePurls))
+		for gav := range s.unresolvablePurls {
+			gavs = append(gavs, gav)
+		}
+		sort.Strings(gavs)
+		log.Printf("summary: %d sboms reference artifacts that don't exist: %s", len(gavs), strings.Join(gavs, ", "))
+	}
+	if len(s.timestampAnomalies) > 0 {
+		artifacts := make([]string, 0, len(s.timestampAnomalies))
+		for artifact := range s.timestampAnomalies {
+			artifacts = append(artifacts, artifact)
+		}
+		sort.Strings(artifacts)
+		log.Printf("summary: %d artifacts have sboms with non-monotonic timestamps: %s", len(artifacts), strings.Join(artifacts, ", "))
+	}
+
+	logHistogram("spec inconsistencies", s.specInconsistencies, 0)
+	logHistogram("tool versions", s.toolVersions, 0)
+	logHistogram("component types", s.componentTypes, 0)
+	logHistogram("licenses", s.licenses, 25)
+}
+
+// logHistogram logs the entries of histogram in descending order of their count.
+// If limit is greater than zero, only the top limit entries are logged.
+func logHistogram(name string, histogram map[string]int, limit int) {
+	if len(histogram) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(histogram))
+	for key := range histogram {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if histogram[keys[i]] != histogram[keys[j]] {
+			return histogram[keys[i]] > histogram[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	log.Printf("summary: %s:", name)
+	for _, key := range keys {
+		log.Printf("summary:   %-40s %d", key, histogram[key])
+	}
+}
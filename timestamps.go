@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// versionTimestamp is the metadata.timestamp of the SBOM of a version.
+type versionTimestamp struct {
+	version   string
+	timestamp string
+}
+
+// TimestampAnomaly records that the SBOM of Version is older than that of
+// PreviousVersion, although it's a newer version (-validate-timestamps-monotonic).
+type TimestampAnomaly struct {
+	Version           string `json:"version"`
+	Timestamp         string `json:"timestamp"`
+	PreviousVersion   string `json:"previousVersion"`
+	PreviousTimestamp string `json:"previousTimestamp"`
+}
+
+// timestampAnomalies orders timestamps by version according to cmp, and returns an anomaly
+// for each version whose timestamp is older than that of the preceding version.
+// Timestamps that aren't valid RFC 3339 date-times are ignored.
+func timestampAnomalies(timestamps []versionTimestamp, cmp VersionComparator) []TimestampAnomaly {
+	type parsed struct {
+		versionTimestamp
+		time time.Time
+	}
+	valid := make([]parsed, 0, len(timestamps))
+	for _, ts := range timestamps {
+		t, err := time.Parse(time.RFC3339, ts.timestamp)
+		if err != nil {
+			debugf("ignoring timestamp %q of version %s: %v", ts.timestamp, ts.version, err)
+			continue
+		}
+		valid = append(valid, parsed{versionTimestamp: ts, time: t})
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return cmp.Compare(valid[i].version, valid[j].version) < 0
+	})
+
+	var anomalies []TimestampAnomaly
+	for i := 1; i < len(valid); i++ {
+		previous, current := valid[i-1], valid[i]
+		if current.time.Before(previous.time) {
+			anomalies = append(anomalies, TimestampAnomaly{
+				Version:           current.version,
+				Timestamp:         current.timestamp,
+				PreviousVersion:   previous.version,
+				PreviousTimestamp: previous.timestamp,
+			})
+		}
+	}
+	return anomalies
+}
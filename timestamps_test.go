@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTimestampAnomalies(t *testing.T) {
+	timestamps := []versionTimestamp{
+		{version: "1.10.0", timestamp: "2023-03-01T00:00:00Z"},
+		{version: "1.2.0", timestamp: "2023-02-01T00:00:00+01:00"},
+		{version: "1.0.0", timestamp: "2023-01-01T00:00:00Z"},
+		{version: "1.11.0", timestamp: "2022-12-01T00:00:00Z"},
+		{version: "1.11.1", timestamp: "not a timestamp"},
+		{version: "1.12.0", timestamp: "2022-12-01T00:00:00Z"},
+	}
+
+	expected := []TimestampAnomaly{
+		{Version: "1.11.0", Timestamp: "2022-12-01T00:00:00Z", PreviousVersion: "1.10.0", PreviousTimestamp: "2023-03-01T00:00:00Z"},
+	}
+	if anomalies := timestampAnomalies(timestamps, MavenVersionComparator{}); !reflect.DeepEqual(anomalies, expected) {
+		t.Errorf("expected %+v, got %+v", expected, anomalies)
+	}
+
+	if anomalies := timestampAnomalies(timestamps[:3], MavenVersionComparator{}); anomalies != nil {
+		t.Errorf("expected no anomalies, got %+v", anomalies)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "github.com/CycloneDX/cyclonedx-go"
+
+// SBOMTool identifies a tool that generated an SBOM, as listed in metadata.tools.
+type SBOMTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+func (t SBOMTool) String() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + " " + t.Version
+}
+
+// sbomTools returns the tools listed in the metadata of bom. Tools of the object form of
+// CycloneDX 1.5 are included, as they are decoded as tools (see decodeJSONMetadata).
+// Tools without name are omitted.
+func sbomTools(bom *cyclonedx.BOM) []SBOMTool {
+	if bom.Metadata == nil || bom.Metadata.Tools == nil {
+		return nil
+	}
+
+	var tools []SBOMTool
+	for _, tool := range *bom.Metadata.Tools {
+		if tool.Name != "" {
+			tools = append(tools, SBOMTool{Name: tool.Name, Version: tool.Version})
+		}
+	}
+	return tools
+}
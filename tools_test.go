@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestSBOMTools(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		xml      bool
+		expected []SBOMTool
+	}{
+		{
+			name:     "Legacy",
+			data:     `{"bomFormat": "CycloneDX", "specVersion": "1.4", "metadata": {"tools": [{"vendor": "OWASP Foundation", "name": "CycloneDX Maven plugin", "version": "2.7.4"}, {"name": ""}]}}`,
+			expected: []SBOMTool{{Name: "CycloneDX Maven plugin", Version: "2.7.4"}},
+		},
+		{
+			name: "Object",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "metadata": {"tools": {
+  "components": [{"type": "library", "group": "org.cyclonedx", "name": "cyclonedx-maven-plugin", "version": "2.7.10"}],
+  "services": [{"name": "build-service"}]
+}}}`,
+			expected: []SBOMTool{{Name: "cyclonedx-maven-plugin", Version: "2.7.10"}, {Name: "build-service"}},
+		},
+		{
+			name: "XML",
+			data: `<?xml version="1.0"?>
+<bom xmlns="http://cyclonedx.org/schema/bom/1.4"><metadata><tools><tool><name>CycloneDX Maven plugin</name><version>2.7.4</version></tool></tools></metadata></bom>`,
+			xml:      true,
+			expected: []SBOMTool{{Name: "CycloneDX Maven plugin", Version: "2.7.4"}},
+		},
+		{
+			name: "NoMetadata",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.4"}`,
+		},
+		{
+			name: "NoTools",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "metadata": {}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			format := cyclonedx.BOMFileFormatJSON
+			if tc.xml {
+				format = cyclonedx.BOMFileFormatXML
+			}
+			var bom cyclonedx.BOM
+			// The object form of tools must decode without -lenient.
+			if _, _, _, err := decodeSBOM([]byte(tc.data), format, false, false, &bom); err != nil {
+				t.Fatal(err)
+			}
+			if tools := sbomTools(&bom); !reflect.DeepEqual(tools, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, tools)
+			}
+			if tc.xml {
+				return
+			}
+			var metadataOnly cyclonedx.BOM
+			if _, _, err := decodeSBOMMetadata([]byte(tc.data), &metadataOnly); err != nil {
+				t.Fatal(err)
+			}
+			if tools := sbomTools(&metadataOnly); !reflect.DeepEqual(tools, tc.expected) {
+				t.Errorf("expected %v when decoding only the metadata, got %v", tc.expected, tools)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// encodeBOM encodes bom in the spec version it was originally declared in.
+func encodeBOM(bom *cyclonedx.BOM, format cyclonedx.BOMFileFormat, pretty bool) ([]byte, error) {
+	buf := bytes.Buffer{}
+	err := cyclonedx.NewBOMEncoder(&buf, format).
+		SetPretty(pretty).
+		SetEscapeHTML(false).
+		EncodeVersion(bom, bom.SpecVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func runValidate(args []string) {
+	var strict bool
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s validate:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flags.Output(), "  %s validate [flags] DIR\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&strict, "strict", false, "Exit with a non-zero status if any SBOM fails validation")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	passed, failed := 0, 0
+	err := filepath.WalkDir(flags.Arg(0), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isSBOMFile(path) {
+			return nil
+		}
+
+		problems := validateFile(path)
+		if len(problems) == 0 {
+			passed++
+			return nil
+		}
+		failed++
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("failed to walk %s: %v", flags.Arg(0), err)
+	}
+
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	if strict && failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// validateFile decodes the SBOM at path like the crawler does (see decodeSBOM), and returns
+// the warnings of the decoder along with the problems found by validateBOM. SBOMs that can't
+// be read or decoded have a single problem.
+func validateFile(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to read: %v", err)}
+	}
+
+	format := suffixFormat(path)
+	var bom cyclonedx.BOM
+	warnings, _, _, err := decodeSBOM(data, format, false, false, &bom)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to decode: %v", err)}
+	}
+
+	return append(warnings, validateBOM(&bom, format)...)
+}
+
+func isSBOMFile(path string) bool {
+	return strings.HasSuffix(path, ".cdx.json") || strings.HasSuffix(path, ".cdx.xml")
+}
+
+// validateBOM checks bom for problems that would trip up consumers:
+// missing required fields, duplicate bom-refs, invalid package URLs
+// and dependencies on unknown bom-refs. It returns a description of each problem.
+func validateBOM(bom *cyclonedx.BOM, format cyclonedx.BOMFileFormat) []string {
+	var problems []string
+	if format == cyclonedx.BOMFileFormatXML {
+		if !strings.HasPrefix(bom.XMLNS, "http://cyclonedx.org/schema/bom/") {
+			problems = append(problems, fmt.Sprintf("unexpected namespace %q", bom.XMLNS))
+		}
+	} else {
+		if bom.BOMFormat != cyclonedx.BOMFormat {
+			problems = append(problems, fmt.Sprintf("bomFormat must be %q, but is %q", cyclonedx.BOMFormat, bom.BOMFormat))
+		}
+		if bom.SpecVersion == 0 {
+			problems = append(problems, "specVersion is missing")
+		}
+	}
+
+	refs := make(map[string]bool)
+	var visit func(component cyclonedx.Component, path string)
+	visit = func(component cyclonedx.Component, path string) {
+		if component.Name == "" {
+			problems = append(problems, fmt.Sprintf("%s has no name", path))
+		}
+		if component.Type == "" {
+			problems = append(problems, fmt.Sprintf("%s has no type", path))
+		}
+		if component.BOMRef != "" {
+			if refs[component.BOMRef] {
+				problems = append(problems, fmt.Sprintf("bom-ref %q is not unique", component.BOMRef))
+			}
+			refs[component.BOMRef] = true
+		}
+		if component.PackageURL != "" && !validPurl(component.PackageURL) {
+			problems = append(problems, fmt.Sprintf("%s has an invalid purl %q", path, component.PackageURL))
+		}
+		if component.Components != nil {
+			for i, child := range *component.Components {
+				visit(child, fmt.Sprintf("%s.components[%d]", path, i))
+			}
+		}
+	}
+
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		visit(*bom.Metadata.Component, "metadata.component")
+	}
+	if bom.Components != nil {
+		for i, component := range *bom.Components {
+			visit(component, fmt.Sprintf("components[%d]", i))
+		}
+	}
+
+	if bom.Services != nil {
+		for _, service := range *bom.Services {
+			refs[service.BOMRef] = true
+		}
+	}
+
+	if bom.Dependencies != nil {
+		for _, dep := range *bom.Dependencies {
+			if !refs[dep.Ref] {
+				problems = append(problems, fmt.Sprintf("dependency graph references unknown bom-ref %q", dep.Ref))
+			}
+			if dep.Dependencies == nil {
+				continue
+			}
+			for _, ref := range *dep.Dependencies {
+				if !refs[ref] {
+					problems = append(problems, fmt.Sprintf("dependency graph references unknown bom-ref %q", ref))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+var purlTypeRegex = regexp.MustCompile(`^[a-zA-Z.+-][a-zA-Z0-9.+-]*$`)
+
+// validPurl performs a structural check of a package URL:
+// it must have the pkg scheme, a valid type and a name.
+func validPurl(purl string) bool {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+
+	typ, rest, ok := strings.Cut(strings.TrimLeft(rest, "/"), "/")
+	if !ok || !purlTypeRegex.MatchString(typ) {
+		return false
+	}
+
+	rest, _, _ = strings.Cut(rest, "@")
+	rest = strings.Trim(rest, "/")
+	name := rest[strings.LastIndex(rest, "/")+1:]
+	return name != ""
+}
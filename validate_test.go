@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestValidateBOM(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(bom *cyclonedx.BOM)
+		problems []string
+	}{
+		{
+			name:   "Valid",
+			modify: func(bom *cyclonedx.BOM) {},
+		},
+		{
+			name:     "MissingBOMFormat",
+			modify:   func(bom *cyclonedx.BOM) { bom.BOMFormat = "" },
+			problems: []string{`bomFormat must be "CycloneDX", but is ""`},
+		},
+		{
+			name:     "DuplicateBOMRef",
+			modify:   func(bom *cyclonedx.BOM) { (*bom.Components)[1].BOMRef = "a" },
+			problems: []string{`bom-ref "a" is not unique`, `dependency graph references unknown bom-ref "b"`, `dependency graph references unknown bom-ref "b"`},
+		},
+		{
+			name: "InvalidPurl",
+			modify: func(bom *cyclonedx.BOM) {
+				(*bom.Components)[0].PackageURL = "maven/org.example/a@1.0.0"
+				(*bom.Components)[1].PackageURL = "pkg:maven/org.example/b@1.0.0?type=jar"
+			},
+			problems: []string{`components[0] has an invalid purl "maven/org.example/a@1.0.0"`},
+		},
+		{
+			name:     "MissingName",
+			modify:   func(bom *cyclonedx.BOM) { bom.Metadata.Component.Name = "" },
+			problems: []string{"metadata.component has no name"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bom := newDependencyGraphBOM()
+			for i := range *bom.Components {
+				(*bom.Components)[i].Type = cyclonedx.ComponentTypeLibrary
+			}
+			bom.Metadata.Component.Type = cyclonedx.ComponentTypeApplication
+			tc.modify(bom)
+
+			problems := validateBOM(bom, cyclonedx.BOMFileFormatJSON)
+			if !reflect.DeepEqual(problems, tc.problems) {
+				t.Fatalf("expected problems %q, got %q", tc.problems, problems)
+			}
+		})
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name: "NewerSpecVersion",
+			data: `{"bomFormat": "CycloneDX", "specVersion": "1.5", "metadata": {"tools": {"components": [{"name": "a"}]}}, "components": [{"type": "library", "name": "a"}]}`,
+		},
+		{
+			name:     "UnknownComponentType",
+			data:     `{"bomFormat": "CycloneDX", "specVersion": "1.4", "components": [{"type": "librar", "name": "a"}]}`,
+			problems: []string{`component "a" has unknown type "librar"`},
+		},
+		{
+			name:     "Undecodable",
+			data:     `{"bomFormat": "CycloneDX", "specVersion": "2.0"}`,
+			problems: []string{"failed to decode: invalid specification version"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a.cdx.json")
+			if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if problems := validateFile(path); !reflect.DeepEqual(problems, tc.problems) {
+				t.Fatalf("expected problems %q, got %q", tc.problems, problems)
+			}
+		})
+	}
+}
+
+func TestValidPurl(t *testing.T) {
+	for purl, valid := range map[string]bool{
+		"pkg:maven/org.example/lib@1.0.0":          true,
+		"pkg:npm/%40angular/core@16.0.0":           true,
+		"pkg:generic/lib?download_url=https://x/y": true,
+		"pkg:/maven/org.example/lib":               true,
+		"pkg:maven":                                false,
+		"pkg:maven/":                               false,
+		"pkg:1maven/org.example/lib":               false,
+		"https://example.com/lib":                  false,
+	} {
+		if validPurl(purl) != valid {
+			t.Errorf("expected validity of %s to be %t", purl, valid)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// unresolvablePurls checks whether the artifacts referenced by a sample of up to n Maven
+// package URLs of the components of bom exist in repo (-verify-purl-resolvable), and returns
+// the package URLs of those that don't, sorted. The root component is not considered.
+func unresolvablePurls(ctx context.Context, repo Repository, bom *cyclonedx.BOM, n int) ([]string, error) {
+	var unresolvable []string
+	for _, purl := range sampleMavenPurls(bom, n) {
+		gav, _ := mavenPurlGAV(purl)
+		exists, err := artifactExists(ctx, repo, gav)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", purl, err)
+		}
+		if !exists {
+			unresolvable = append(unresolvable, purl)
+		}
+	}
+	sort.Strings(unresolvable)
+	return unresolvable, nil
+}
+
+// sampleMavenPurls returns up to n Maven package URLs of the components of bom, including
+// nested ones, that reference distinct artifacts.
+//
+// The sample is chosen by a hash of the coordinates rather than by their position,
+// so the same SBOM always yields the same sample.
+func sampleMavenPurls(bom *cyclonedx.BOM, n int) []string {
+	purls := make(map[GAV]string)
+	var visit func(components *[]cyclonedx.Component)
+	visit = func(components *[]cyclonedx.Component) {
+		if components == nil {
+			return
+		}
+		for _, component := range *components {
+			if gav, ok := mavenPurlGAV(component.PackageURL); ok {
+				if _, seen := purls[gav]; !seen {
+					purls[gav] = component.PackageURL
+				}
+			}
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+
+	type candidate struct {
+		hash uint64
+		gav  GAV
+	}
+	candidates := make([]candidate, 0, len(purls))
+	for gav := range purls {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(gav.String()))
+		candidates = append(candidates, candidate{hash: h.Sum64(), gav: gav})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].hash != candidates[j].hash {
+			return candidates[i].hash < candidates[j].hash
+		}
+		return candidates[i].gav.Less(candidates[j].gav)
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	sample := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		sample = append(sample, purls[c.gav])
+	}
+	return sample
+}
+
+// artifactExists checks whether the POM of gav exists in repo with a HEAD request.
+// Every artifact published to a Maven repository has one, regardless of its packaging.
+func artifactExists(ctx context.Context, repo Repository, gav GAV) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	res, _, err := fetchArtifactFile(ctx, repo, http.MethodHead, gav, ".pom")
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+func TestUnresolvablePurls(t *testing.T) {
+	repo := newFixtureRepository(t, map[string]fixture{
+		"org/example/lib/1.0.0/lib-1.0.0.pom":                      {},
+		"fallback/org/example/elsewhere/1.0.0/elsewhere-1.0.0.pom": {},
+		"org/example/broken/1.0.0/broken-1.0.0.pom":                {status: 500},
+	}, nil)
+	repo.FallbackBaseURLs = []string{repo.BaseURL + "/fallback"}
+
+	bom := &cyclonedx.BOM{
+		Metadata: &cyclonedx.Metadata{
+			Component: &cyclonedx.Component{PackageURL: "pkg:maven/org.example/root@1.0.0"},
+		},
+		Components: &[]cyclonedx.Component{
+			{PackageURL: "pkg:maven/org.example/lib@1.0.0?type=jar"},
+			{PackageURL: "pkg:maven/org.example/lib@1.0.0?classifier=sources"},
+			{
+				PackageURL: "pkg:maven/org.example/elsewhere@1.0.0",
+				Components: &[]cyclonedx.Component{
+					{PackageURL: "pkg:maven/org.example/typo@1.0.0"},
+				},
+			},
+			{PackageURL: "pkg:maven/org.example/fabricated@9.9.9"},
+			{PackageURL: "pkg:npm/example@1.0.0"},
+			{Name: "without-purl"},
+		},
+	}
+
+	unresolvable, err := unresolvablePurls(context.Background(), repo, bom, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"pkg:maven/org.example/fabricated@9.9.9", "pkg:maven/org.example/typo@1.0.0"}
+	if !reflect.DeepEqual(unresolvable, expected) {
+		t.Errorf("expected %v, got %v", expected, unresolvable)
+	}
+
+	*bom.Components = append(*bom.Components, cyclonedx.Component{PackageURL: "pkg:maven/org.example/broken@1.0.0"})
+	if _, err = unresolvablePurls(context.Background(), repo, bom, 10); err == nil || !strings.Contains(err.Error(), "unexpected status code: 500") {
+		t.Errorf("expected error for unexpected status code, got %v", err)
+	}
+}
+
+func TestSampleMavenPurls(t *testing.T) {
+	var components []cyclonedx.Component
+	for _, artifact := range []string{"a", "b", "c", "d", "e", "f"} {
+		components = append(components, cyclonedx.Component{PackageURL: "pkg:maven/org.example/" + artifact + "@1.0.0"})
+	}
+	bom := &cyclonedx.BOM{Components: &components}
+
+	sample := sampleMavenPurls(bom, 3)
+	if len(sample) != 3 {
+		t.Fatalf("expected 3 purls, got %v", sample)
+	}
+
+	// The sample must not depend on the order of components.
+	for i, j := 0, len(components)-1; i < j; i, j = i+1, j-1 {
+		components[i], components[j] = components[j], components[i]
+	}
+	if reversed := sampleMavenPurls(bom, 3); !reflect.DeepEqual(reversed, sample) {
+		t.Errorf("expected the same sample %v for reversed components, got %v", sample, reversed)
+	}
+
+	if all := sampleMavenPurls(bom, 10); len(all) != len(components) {
+		t.Errorf("expected all %d purls, got %v", len(components), all)
+	}
+}
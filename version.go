@@ -0,0 +1,253 @@
+package main
+
+import (
+	"strings"
+)
+
+// VersionComparator orders the versions of an artifact, e.g. for -newer-than-index.
+// Compare returns a negative number if a < b, zero if a == b, and a positive number if a > b.
+type VersionComparator interface {
+	Compare(a, b string) int
+}
+
+// MavenVersionComparator is the default VersionComparator, see compareVersions.
+type MavenVersionComparator struct{}
+
+func (MavenVersionComparator) Compare(a, b string) int {
+	return compareVersions(a, b)
+}
+
+// compareVersions compares two Maven versions the way Maven's ComparableVersion does.
+// It returns a negative number if a < b, zero if a == b, and a positive number if a > b.
+//
+// Versions are split into numeric and string items at '.', '-' and transitions
+// between digits and letters. Numeric items are compared numerically, and known
+// qualifiers are ordered alpha < beta < milestone < rc < snapshot < release < sp,
+// followed by unknown qualifiers in lexical order. Trailing zeros and release
+// qualifiers are insignificant, so 1 == 1.0 == 1.0.0-ga.
+func compareVersions(a, b string) int {
+	return parseVersion(a).compare(parseVersion(b))
+}
+
+// versionItem is an item of a parsed version.
+// compare must handle a nil other, which stands for a missing item.
+type versionItem interface {
+	compare(other versionItem) int
+	isNull() bool
+}
+
+type intItem string // decimal digits without leading zeros
+
+type stringItem string // comparable qualifier, see comparableQualifier
+
+type listItem []versionItem
+
+var versionQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+// releaseQualifier is the comparable qualifier of a release.
+const releaseQualifier = "5"
+
+var qualifierAliases = map[string]string{
+	"ga":      "",
+	"final":   "",
+	"release": "",
+	"cr":      "rc",
+}
+
+func newStringItem(value string, followedByDigit bool) stringItem {
+	if followedByDigit && len(value) == 1 {
+		switch value {
+		case "a":
+			value = "alpha"
+		case "b":
+			value = "beta"
+		case "m":
+			value = "milestone"
+		}
+	}
+	if alias, ok := qualifierAliases[value]; ok {
+		value = alias
+	}
+	return stringItem(comparableQualifier(value))
+}
+
+// comparableQualifier maps known qualifiers to their position,
+// and prefixes unknown ones so they sort after all known qualifiers.
+func comparableQualifier(qualifier string) string {
+	for i, q := range versionQualifiers {
+		if q == qualifier {
+			return string(rune('0' + i))
+		}
+	}
+	return string(rune('0'+len(versionQualifiers))) + "-" + qualifier
+}
+
+func (i intItem) isNull() bool {
+	return i == "0"
+}
+
+func (i intItem) compare(other versionItem) int {
+	switch o := other.(type) {
+	case nil:
+		if i.isNull() {
+			return 0
+		}
+		return 1
+	case intItem:
+		if len(i) != len(o) {
+			return len(i) - len(o)
+		}
+		return strings.Compare(string(i), string(o))
+	default:
+		return 1
+	}
+}
+
+func (s stringItem) isNull() bool {
+	return s == releaseQualifier
+}
+
+func (s stringItem) compare(other versionItem) int {
+	switch o := other.(type) {
+	case nil:
+		return strings.Compare(string(s), releaseQualifier)
+	case stringItem:
+		return strings.Compare(string(s), string(o))
+	default:
+		return -1
+	}
+}
+
+func (l listItem) isNull() bool {
+	return len(l) == 0
+}
+
+func (l listItem) compare(other versionItem) int {
+	switch o := other.(type) {
+	case nil:
+		if len(l) == 0 {
+			return 0
+		}
+		return l[0].compare(nil)
+	case intItem:
+		return -1
+	case stringItem:
+		return 1
+	case listItem:
+		for i := 0; i < len(l) || i < len(o); i++ {
+			var result int
+			switch {
+			case i >= len(l):
+				result = -o[i].compare(nil)
+			case i >= len(o):
+				result = l[i].compare(nil)
+			default:
+				result = l[i].compare(o[i])
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+// normalize removes trailing null items.
+func (l listItem) normalize() listItem {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].isNull() {
+			l = append(l[:i], l[i+1:]...)
+		} else if _, ok := l[i].(listItem); !ok {
+			break
+		}
+	}
+	return l
+}
+
+func parseVersion(version string) listItem {
+	version = strings.ToLower(version)
+
+	// Lists are still appended to after being added to their parent,
+	// so while parsing, a child list is referenced by its index in nodes.
+	type node struct {
+		items []any
+	}
+	nodes := []*node{{}}
+	current := 0
+	newList := func() {
+		nodes = append(nodes, &node{})
+		nodes[current].items = append(nodes[current].items, len(nodes)-1)
+		current = len(nodes) - 1
+	}
+	add := func(item versionItem) {
+		nodes[current].items = append(nodes[current].items, item)
+	}
+	parseItem := func(isDigit bool, s string) versionItem {
+		if isDigit {
+			s = strings.TrimLeft(s, "0")
+			if s == "" {
+				s = "0"
+			}
+			return intItem(s)
+		}
+		return newStringItem(s, false)
+	}
+
+	isDigit := false
+	start := 0
+	for i := 0; i < len(version); i++ {
+		c := version[i]
+		switch {
+		case c == '.':
+			if i == start {
+				add(intItem("0"))
+			} else {
+				add(parseItem(isDigit, version[start:i]))
+			}
+			start = i + 1
+		case c == '-':
+			if i == start {
+				add(intItem("0"))
+			} else {
+				add(parseItem(isDigit, version[start:i]))
+			}
+			start = i + 1
+			newList()
+		case c >= '0' && c <= '9':
+			if !isDigit && i > start {
+				// 1.0.0.X1 < 1.0.0-X2: treat X1 as -X1
+				add(newStringItem(version[start:i], true))
+				start = i
+				newList()
+			}
+			isDigit = true
+		default:
+			if isDigit && i > start {
+				add(parseItem(true, version[start:i]))
+				start = i
+				newList()
+			}
+			isDigit = false
+		}
+	}
+	if len(version) > start {
+		add(parseItem(isDigit, version[start:]))
+	}
+
+	var build func(n int) listItem
+	build = func(n int) listItem {
+		list := make(listItem, 0, len(nodes[n].items))
+		for _, item := range nodes[n].items {
+			if child, ok := item.(int); ok {
+				list = append(list, build(child))
+			} else {
+				list = append(list, item.(versionItem))
+			}
+		}
+		return list.normalize()
+	}
+
+	return build(0)
+}
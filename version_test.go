@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	// Each version is less than the next one.
+	ordered := []string{
+		"1-alpha",
+		"1-alpha2",
+		"1-alpha10",
+		"1-beta",
+		"1-milestone1",
+		"1-rc1",
+		"1-SNAPSHOT",
+		"1",
+		"1-sp",
+		"1-abc",
+		"1-1",
+		"1.0.1",
+		"1.1",
+		"1.9",
+		"1.10",
+		"1.10.0.1",
+		"2.0-M1",
+		"2.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, b := ordered[i], ordered[i+1]
+		if compareVersions(a, b) >= 0 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+		if compareVersions(b, a) <= 0 {
+			t.Errorf("expected %s > %s", b, a)
+		}
+	}
+
+	equal := [][2]string{
+		{"1", "1.0"},
+		{"1", "1.0.0"},
+		{"1.0", "1-ga"},
+		{"1.0", "1.0-final"},
+		{"1.0", "1.0.RELEASE"},
+		{"1-a1", "1-alpha-1"},
+		{"1-cr1", "1-rc1"},
+		{"1.0-01", "1.0-1"},
+	}
+	for _, pair := range equal {
+		if compareVersions(pair[0], pair[1]) != 0 {
+			t.Errorf("expected %s == %s", pair[0], pair[1])
+		}
+	}
+}
+
+func TestMavenVersionComparator(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0.RELEASE", "1.0.0", 0},
+		{"2.5.RELEASE", "2.5.1.RELEASE", -1},
+		{"1.0.0.M1", "1.0.0.RC1", -1},
+		{"1.0.0.RC1", "1.0.0.RELEASE", -1},
+		{"1.0-SNAPSHOT", "1.0", -1},
+		{"1.0.0.Final", "1.0.0.SP1", -1},
+		{"5.3.0-beta1", "5.3.0-alpha2", 1},
+		{"1.0.0-jre", "1.0.0-android", 1},
+		{"2.0", "10.0", -1},
+		{"1.2.3", "1.2.3.0.0", 0},
+	}
+	for _, tc := range testCases {
+		got := MavenVersionComparator{}.Compare(tc.a, tc.b)
+		if (got < 0 && tc.want >= 0) || (got == 0 && tc.want != 0) || (got > 0 && tc.want <= 0) {
+			t.Errorf("expected Compare(%s, %s) to have the sign of %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+// lexicalComparator is a VersionComparator as a user could inject it.
+type lexicalComparator struct{}
+
+func (lexicalComparator) Compare(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+func TestNewerVersionsComparator(t *testing.T) {
+	artifact := Artifact{GroupID: "org.example", ArtifactID: "lib"}
+	var matches []VersionMatch
+	for _, version := range []string{"1.9", "1.10", "2.0"} {
+		matches = append(matches, VersionMatch{GAV: GAV{GroupID: artifact.GroupID, ArtifactID: artifact.ArtifactID, Version: version}})
+	}
+
+	testCases := []struct {
+		name string
+		cmp  VersionComparator
+		want []string
+	}{
+		{"Maven", MavenVersionComparator{}, []string{"1.10", "2.0"}},
+		{"Custom", lexicalComparator{}, []string{"2.0"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var versions []string
+			for _, match := range newerVersions(matches, artifact, "1.9", tc.cmp) {
+				versions = append(versions, match.GAV.Version)
+			}
+			if !reflect.DeepEqual(versions, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, versions)
+			}
+		})
+	}
+}
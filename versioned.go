@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+)
+
+// isConcreteVersion determines whether version pins a single version, as opposed
+// to being empty, a range (e.g. [1.0,2.0), ^1.2.3 or >=1.0) or a placeholder
+// (e.g. *, 1.x or latest).
+func isConcreteVersion(version string) bool {
+	version = strings.TrimSpace(version)
+	if version == "" || strings.EqualFold(version, "latest") || strings.ContainsAny(version, "[](),<>=^~*| ") {
+		return false
+	}
+	for _, segment := range strings.Split(version, ".") {
+		if segment == "x" || segment == "X" {
+			return false
+		}
+	}
+	return true
+}
+
+// versionedRatio returns the fraction of components of bom that have a concrete version (see componentRatio).
+func versionedRatio(bom *cyclonedx.BOM) float64 {
+	return componentRatio(bom, func(component cyclonedx.Component) bool {
+		return isConcreteVersion(component.Version)
+	})
+}
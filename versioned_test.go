@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestIsConcreteVersion(t *testing.T) {
+	testCases := map[string]bool{
+		"1.0.0":          true,
+		"2.15.0-rc1":     true,
+		"v1.2.3+build.4": true,
+		"":               false,
+		"  ":             false,
+		"[1.0,2.0)":      false,
+		"^1.2.3":         false,
+		"~1.2":           false,
+		">=1.0":          false,
+		"1.x":            false,
+		"*":              false,
+		"latest":         false,
+		"1.0 || 2.0":     false,
+	}
+
+	for version, expected := range testCases {
+		if actual := isConcreteVersion(version); actual != expected {
+			t.Errorf("isConcreteVersion(%q): expected %v, got %v", version, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var errStuck = errors.New("made no progress within -stuck-timeout")
+
+// watchdog detects workers that made no progress for -stuck-timeout, e.g. because
+// reading a response body hangs despite timeouts. Workers report progress by starting
+// and finishing steps, like searching for versions or downloading an SBOM, and by
+// making progress within a step, like issuing a request or reading a response body.
+// A nil watchdog doesn't watch anything. It is safe for concurrent use.
+type watchdog struct {
+	mux         sync.Mutex
+	timeout     time.Duration
+	cancelStuck bool
+	summary     *Summary
+	nextID      int
+	steps       map[int]*watchedStep
+}
+
+// watchedStep is a step a worker is currently busy with.
+type watchedStep struct {
+	name       string
+	progressed time.Time
+	cancel     context.CancelCauseFunc
+	tripped    bool
+}
+
+// watchedStepKey is the context key of the function that records progress of the current step.
+type watchedStepKey struct{}
+
+func newWatchdog(timeout time.Duration, cancelStuck bool, summary *Summary) *watchdog {
+	if timeout <= 0 {
+		return nil
+	}
+	return &watchdog{
+		timeout:     timeout,
+		cancelStuck: cancelStuck,
+		summary:     summary,
+		steps:       make(map[int]*watchedStep),
+	}
+}
+
+// Step records that a worker starts the step called name. The returned context is
+// cancelled with errStuck if the step trips the watchdog and stuck steps are to be
+// cancelled. The returned function must be called once the step is done.
+//
+// Requests issued with the returned context through a progressTransport record
+// progress, so that steps which are slow but still moving don't trip the watchdog.
+func (w *watchdog) Step(ctx context.Context, name string) (context.Context, func()) {
+	if w == nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	w.mux.Lock()
+	id := w.nextID
+	w.nextID++
+	step := &watchedStep{name: name, progressed: time.Now(), cancel: cancel}
+	w.steps[id] = step
+	w.mux.Unlock()
+
+	ctx = context.WithValue(ctx, watchedStepKey{}, func() {
+		w.mux.Lock()
+		step.progressed = time.Now()
+		w.mux.Unlock()
+	})
+	return ctx, func() {
+		w.mux.Lock()
+		delete(w.steps, id)
+		w.mux.Unlock()
+		cancel(nil)
+	}
+}
+
+// Run checks for stuck steps until ctx is done.
+func (w *watchdog) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+
+	interval := w.timeout / 4
+	if interval <= 0 {
+		interval = w.timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check trips the watchdog for every step that made no progress within timeout before now.
+// Each step trips it at most once.
+func (w *watchdog) check(now time.Time) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	for _, step := range w.steps {
+		if step.tripped || now.Sub(step.progressed) < w.timeout {
+			continue
+		}
+		step.tripped = true
+		w.summary.AddStuckWorker(step.name)
+		if w.cancelStuck {
+			log.Printf("worker made no progress for %s while %s, cancelling it", now.Sub(step.progressed).Round(time.Second), step.name)
+			step.cancel(errStuck)
+		} else {
+			log.Printf("worker made no progress for %s while %s", now.Sub(step.progressed).Round(time.Second), step.name)
+		}
+	}
+}
+
+// reportProgress records that the watched step of ctx, if any, made progress.
+func reportProgress(ctx context.Context) {
+	if progress, ok := ctx.Value(watchedStepKey{}).(func()); ok {
+		progress()
+	}
+}
+
+// progressTransport reports progress to the watchdog for every request,
+// which includes every retry, and for every read from a response body.
+type progressTransport struct {
+	base http.RoundTripper
+}
+
+func (t *progressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reportProgress(req.Context())
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	reportProgress(req.Context())
+	res.Body = &progressReader{ReadCloser: res.Body, ctx: req.Context()}
+	return res, nil
+}
+
+// progressReader reports progress whenever it reads any bytes.
+type progressReader struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		reportProgress(r.ctx)
+	}
+	return n, err
+}
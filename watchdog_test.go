@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	for _, cancelStuck := range []bool{false, true} {
+		summary := NewSummary()
+		w := newWatchdog(time.Minute, cancelStuck, summary)
+
+		stuckCtx, stuckDone := w.Step(context.Background(), "downloading sbom for org.example:stuck:1.0.0")
+		_, doneDone := w.Step(context.Background(), "downloading sbom for org.example:done:1.0.0")
+		doneDone()
+		start := time.Now()
+
+		w.check(start.Add(30 * time.Second))
+		if len(summary.stuckWorkers) != 0 {
+			t.Fatalf("expected no stuck workers before the timeout, got %v", summary.stuckWorkers)
+		}
+
+		w.check(start.Add(2 * time.Minute))
+		w.check(start.Add(3 * time.Minute))
+		if expected := []string{"downloading sbom for org.example:stuck:1.0.0"}; !reflect.DeepEqual(summary.stuckWorkers, expected) {
+			t.Errorf("expected stuck workers %v, got %v", expected, summary.stuckWorkers)
+		}
+		if cancelled := errors.Is(context.Cause(stuckCtx), errStuck); cancelled != cancelStuck {
+			t.Errorf("expected the stuck step to be cancelled: %v, got cause %v", cancelStuck, context.Cause(stuckCtx))
+		}
+		stuckDone()
+	}
+
+	var disabled *watchdog
+	ctx, done := disabled.Step(context.Background(), "searching")
+	done()
+	if ctx.Err() != nil {
+		t.Errorf("expected a disabled watchdog not to cancel steps, got %v", ctx.Err())
+	}
+}
+
+func TestWatchdogProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	summary := NewSummary()
+	w := newWatchdog(time.Minute, false, summary)
+	ctx, done := w.Step(context.Background(), "downloading sbom for org.example:slow:1.0.0")
+	defer done()
+
+	// Pretend the step last made progress long ago, like a download that is
+	// being retried with a long backoff.
+	start := time.Now()
+	for _, step := range w.steps {
+		step.progressed = start.Add(-2 * time.Minute)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := (&http.Client{Transport: &progressTransport{base: http.DefaultTransport}}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	w.check(start.Add(30 * time.Second))
+	if len(summary.stuckWorkers) != 0 {
+		t.Fatalf("expected a step that made progress not to trip the watchdog, got %v", summary.stuckWorkers)
+	}
+
+	w.check(start.Add(2 * time.Minute))
+	if len(summary.stuckWorkers) != 1 {
+		t.Errorf("expected the step to trip the watchdog once it made no further progress, got %v", summary.stuckWorkers)
+	}
+}